@@ -1,8 +1,10 @@
 package app
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
 	"github.com/Dimitriy14/staff-manager/web/services/vacation"
 
@@ -10,23 +12,63 @@ import (
 	"github.com/Dimitriy14/staff-manager/config"
 	"github.com/Dimitriy14/staff-manager/db"
 	"github.com/Dimitriy14/staff-manager/elasticsearch"
+	"github.com/Dimitriy14/staff-manager/events"
 	"github.com/Dimitriy14/staff-manager/logger"
+	"github.com/Dimitriy14/staff-manager/mail"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbound"
+	"github.com/Dimitriy14/staff-manager/outbox"
+	"github.com/Dimitriy14/staff-manager/repository/approval"
+	auditRepo "github.com/Dimitriy14/staff-manager/repository/audit"
+	changeRequestRepo "github.com/Dimitriy14/staff-manager/repository/change-request"
+	"github.com/Dimitriy14/staff-manager/repository/department"
+	feedtoken "github.com/Dimitriy14/staff-manager/repository/feed-token"
+	moodRepo "github.com/Dimitriy14/staff-manager/repository/mood"
+	notificationRepo "github.com/Dimitriy14/staff-manager/repository/notification"
+	outboxRepo "github.com/Dimitriy14/staff-manager/repository/outbox"
+	privacyRepo "github.com/Dimitriy14/staff-manager/repository/privacy"
 	"github.com/Dimitriy14/staff-manager/repository/recent-action"
 	tasksRepo "github.com/Dimitriy14/staff-manager/repository/tasks"
 	"github.com/Dimitriy14/staff-manager/repository/user"
 	vacationRepo "github.com/Dimitriy14/staff-manager/repository/vacation"
+	vacationBalance "github.com/Dimitriy14/staff-manager/repository/vacation-balance"
+	vacationComment "github.com/Dimitriy14/staff-manager/repository/vacation-comment"
+	webhookRepo "github.com/Dimitriy14/staff-manager/repository/webhook"
+	"github.com/Dimitriy14/staff-manager/scheduler"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
 	authUsecase "github.com/Dimitriy14/staff-manager/usecases/auth"
+	"github.com/Dimitriy14/staff-manager/usecases/celebrations"
+	changerequests "github.com/Dimitriy14/staff-manager/usecases/change-requests"
+	"github.com/Dimitriy14/staff-manager/usecases/directory"
+	"github.com/Dimitriy14/staff-manager/usecases/lifecycle"
+	"github.com/Dimitriy14/staff-manager/usecases/mood"
+	"github.com/Dimitriy14/staff-manager/usecases/notifications"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
 	"github.com/Dimitriy14/staff-manager/usecases/photos"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
 	tasksuc "github.com/Dimitriy14/staff-manager/usecases/tasks"
 	vacationuc "github.com/Dimitriy14/staff-manager/usecases/vacation"
+	"github.com/Dimitriy14/staff-manager/usecases/webhooks"
 	"github.com/Dimitriy14/staff-manager/web"
 	"github.com/Dimitriy14/staff-manager/web/middlewares"
+	auditServ "github.com/Dimitriy14/staff-manager/web/services/audit"
 	"github.com/Dimitriy14/staff-manager/web/services/auth"
+	celebrationsServ "github.com/Dimitriy14/staff-manager/web/services/celebrations"
+	changeRequestsServ "github.com/Dimitriy14/staff-manager/web/services/change-requests"
+	directoryServ "github.com/Dimitriy14/staff-manager/web/services/directory"
+	eventsServ "github.com/Dimitriy14/staff-manager/web/services/events"
 	"github.com/Dimitriy14/staff-manager/web/services/health"
+	lifecycleServ "github.com/Dimitriy14/staff-manager/web/services/lifecycle"
+	moodServ "github.com/Dimitriy14/staff-manager/web/services/mood"
+	notificationServ "github.com/Dimitriy14/staff-manager/web/services/notifications"
+	orgServ "github.com/Dimitriy14/staff-manager/web/services/org"
+	privacyServ "github.com/Dimitriy14/staff-manager/web/services/privacy"
 	recent_changes "github.com/Dimitriy14/staff-manager/web/services/recent-changes"
 	"github.com/Dimitriy14/staff-manager/web/services/rest"
 	"github.com/Dimitriy14/staff-manager/web/services/tasks"
 	userServ "github.com/Dimitriy14/staff-manager/web/services/user"
+	webhookServ "github.com/Dimitriy14/staff-manager/web/services/webhooks"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 )
@@ -41,24 +83,16 @@ type Components struct {
 }
 
 func LoadApplication(cfgFile string, signal chan os.Signal) (c Components, err error) {
-	sess, err := session.NewSession()
-	if err != nil {
-		return c, err
-	}
-
-	cfg, err := config.Load(cfgFile, sess)
+	c, sess, err := loadBase(cfgFile)
 	if err != nil {
 		return Components{}, err
 	}
-	c.Configuration = cfg
-	c.Cognito = awservices.GetCognitoProvider(sess, cfg.AWSRegion, cfg.UserPoolID, cfg.ClientID)
+	cfg, l := c.Configuration, c.Log
 
-	l, err := logger.Load(cfg.Logger)
+	trustedProxies, err := middlewares.ParseProxies(cfg.TrustedProxies)
 	if err != nil {
 		return Components{}, err
 	}
-	c.Log = l
-	c.shutdowns = append(c.shutdowns, l.Close)
 
 	pg, err := db.Load(cfg.DB, l)
 	if err != nil {
@@ -75,34 +109,137 @@ func LoadApplication(cfgFile string, signal chan os.Signal) (c Components, err e
 	c.shutdowns = append(c.shutdowns, es.Close)
 
 	userRepo := user.NewRepository(c.ElasticSearch)
+	err = userRepo.CheckIndex(context.Background())
+	if err != nil {
+		return Components{}, err
+	}
+
 	authuc := authUsecase.NewAuthUsecase(c.Cognito, l)
 	restService := rest.NewRestService(l)
 	a := auth.NewAuthService(authUsecase.NewAuthUsecase(c.Cognito, l), restService, userRepo, l)
 	photo := photos.NewPhotosUploader(awservices.GetS3Manager(sess, cfg.AWSRegion), cfg.StorageURL, cfg.BucketName)
-	uServ := userServ.NewUserService(restService, l, userRepo, authuc, photo)
+	orgUsecase := org.NewOrgUsecase(userRepo, department.NewDepartmentRepo(pg))
+	authorizer := access.NewAuthorizer(orgUsecase)
+	privacyUsecase := privacy.NewPrivacyUsecase(userRepo, privacyRepo.NewPrivacyPolicyRepo(pg), orgUsecase, l)
+	uServ := userServ.NewUserService(restService, l, userRepo, authuc, photo, orgUsecase, privacyUsecase)
 
 	vacRepo := vacationRepo.NewVacationRepo(pg)
-	recentActionRepo := recent.NewRecentActionRepo(pg)
+	webhookClient := outbound.NewClient(time.Duration(cfg.Notifications.WebhookTimeoutInSec) * time.Second)
+	notificationRepository := notificationRepo.NewNotificationRepo(pg)
+	dispatcher := notifications.NewDispatcher(cfg.Notifications, notificationRepository, userRepo, l)
+	mailSender := mail.NewSender(cfg.SMTP)
+	dispatcher.Register(models.EmailChannel, notifications.NewEmailChannel(mailSender))
+	dispatcher.Register(models.WebhookChannel, notifications.NewWebhookChannel(webhookClient))
+	dispatcher.Register(models.SlackChannel, notifications.NewSlackChannel(webhookClient))
 
-	vacationUseCase := vacationuc.NewVacationUseCase(vacRepo, userRepo, recentActionRepo, l)
+	recentActionRepo := recent.NewRecentActionRepo(pg)
+	broker := events.NewBroker(cfg.DB, pg, recentActionRepo, l)
+	err = broker.Start()
+	if err != nil {
+		return Components{}, err
+	}
+	c.shutdowns = append(c.shutdowns, broker.Stop)
 
+	outboxRepository := outboxRepo.NewOutboxRepo(pg)
 	taskRepository := tasksRepo.NewRepository(es)
-	taskuc := tasksuc.NewTaskUsecase(taskRepository, userRepo, recentActionRepo)
+	webhooksClient := outbound.NewClient(time.Duration(cfg.Webhooks.TimeoutInSec) * time.Second)
+	webhooksUsecase := webhooks.NewWebhooksUsecase(cfg.Webhooks, webhookRepo.NewWebhookRepo(pg), webhooksClient, l)
+	auditUsecase := audit.NewAuditUsecase(auditRepo.NewAuditRepo(pg), outboxRepository, pg)
+
+	relay := outbox.NewRelay(cfg.Outbox, cfg.DB, pg, outboxRepository, l)
+	relay.Subscribe(models.RecentChangeTopic, notifications.NewRecentChangesHandler(recentActionRepo, broker).Handle)
+	relay.Subscribe(models.RecentChangeTopic, dispatcher.Handle)
+	relay.Subscribe(models.RecentChangeTopic, webhooksUsecase.Handle)
+	relay.Subscribe(models.AuditTopic, auditUsecase.Handle)
+	err = relay.Start()
+	if err != nil {
+		return Components{}, err
+	}
+	c.shutdowns = append(c.shutdowns, relay.Stop)
+
+	approvalRepo := approval.NewApprovalRepo(pg)
+	commentRepo := vacationComment.NewVacationCommentRepo(pg)
+	feedTokenRepo := feedtoken.NewFeedTokenRepo(pg)
+	balanceRepo := vacationBalance.NewVacationBalanceRepo(pg)
+
+	vacationUseCase := vacationuc.NewVacationUseCase(vacRepo, approvalRepo, commentRepo, feedTokenRepo, balanceRepo, userRepo, pg, outboxRepository,
+		authorizer, cfg.VacationApprovalChain, cfg.VacationDaysPerYear, cfg.Holidays, l)
+
+	digester := notifications.NewDigester(cfg.Digest, notificationRepository, recentActionRepo, taskRepository, vacRepo, userRepo, mailSender, l)
+
+	moodUsecase := mood.NewMoodUsecase(moodRepo.NewMoodRepo(pg), userRepo, orgUsecase, authorizer, cfg.Mood)
+	changeRequestsUsecase := changerequests.NewChangeRequestsUsecase(changeRequestRepo.NewChangeRequestRepo(pg), userRepo, outboxRepository, pg)
+	celebrationsUsecase := celebrations.NewCelebrationsUsecase(userRepo, outboxRepository, privacyUsecase, l)
+
+	jobs := scheduler.New(pg, l)
+	if cfg.VacationExpiryCron != "" {
+		err = jobs.AddJob(vacationuc.ExpiryJob, cfg.VacationExpiryCron, vacationUseCase.SetExpired)
+		if err != nil {
+			return Components{}, err
+		}
+	}
+	if cfg.Digest.DailyCron != "" {
+		err = jobs.AddJob("daily digest", cfg.Digest.DailyCron, digester.SendDaily)
+		if err != nil {
+			return Components{}, err
+		}
+	}
+	if cfg.Digest.WeeklyCron != "" {
+		err = jobs.AddJob("weekly digest", cfg.Digest.WeeklyCron, digester.SendWeekly)
+		if err != nil {
+			return Components{}, err
+		}
+	}
+	if cfg.Celebrations.AnnouncementCron != "" {
+		err = jobs.AddJob("celebrations announcement", cfg.Celebrations.AnnouncementCron, celebrationsUsecase.Announce)
+		if err != nil {
+			return Components{}, err
+		}
+	}
+	if cfg.Notifications.DeliveryCron != "" {
+		err = jobs.AddJob("notifications delivery", cfg.Notifications.DeliveryCron, dispatcher.Deliver)
+		if err != nil {
+			return Components{}, err
+		}
+	}
+	if cfg.Webhooks.DeliveryCron != "" {
+		err = jobs.AddJob("webhooks delivery", cfg.Webhooks.DeliveryCron, webhooksUsecase.Deliver)
+		if err != nil {
+			return Components{}, err
+		}
+	}
+	jobs.Start()
+	c.shutdowns = append(c.shutdowns, jobs.Stop)
+
+	taskuc := tasksuc.NewTaskUsecase(taskRepository, userRepo, outboxRepository, vacRepo, authorizer)
+	lifecycleUsecase := lifecycle.NewLifecycleUsecase(userRepo, taskRepository, feedTokenRepo, taskuc, vacationUseCase, authuc, cfg.Onboarding)
 	router := web.NewRouter(
 		c.Configuration.URLPrefix,
 		c.Configuration.OriginHosts,
 		web.Services{
-			Health:         health.GetHealth(cfg.ListenURL, restService, pg, es),
-			Rest:           restService,
-			Auth:           a,
-			User:           uServ,
-			LogMiddleware:  middlewares.LogMiddleware(l),
-			TxIDMiddleware: middlewares.AddIDRequestMiddleware,
-			AuthMiddleware: middlewares.AuthMiddleware(l, authuc, restService),
-			AdminOnly:      middlewares.AdminRestriction(l, restService),
-			Task:           tasks.NewTaskService(taskuc, restService, l),
-			RecentChanges:  recent_changes.NewService(recentActionRepo, restService, l),
-			Vacation:       vacation.NewService(restService, vacationUseCase, l),
+			Health:          health.GetHealth(cfg.ListenURL, restService, pg, es),
+			Rest:            restService,
+			Auth:            a,
+			User:            uServ,
+			LogMiddleware:   middlewares.LogMiddleware(l),
+			TxIDMiddleware:  middlewares.AddIDRequestMiddleware,
+			AuthMiddleware:  middlewares.AuthMiddleware(l, authuc, restService),
+			Audit:           auditServ.NewService(restService, auditUsecase, l),
+			Org:             orgServ.NewService(restService, orgUsecase, privacyUsecase, l),
+			Privacy:         privacyServ.NewService(restService, privacyUsecase, l),
+			Lifecycle:       lifecycleServ.NewService(restService, lifecycleUsecase, userRepo, l),
+			ChangeRequests:  changeRequestsServ.NewService(restService, changeRequestsUsecase, l),
+			Mood:            moodServ.NewService(restService, moodUsecase, l),
+			Celebrations:    celebrationsServ.NewService(restService, celebrationsUsecase, l),
+			Directory:       directoryServ.NewService(restService, directory.NewDirectoryUsecase(userRepo, authuc, privacyUsecase, l), l),
+			AuditMiddleware: middlewares.AuditMiddleware(l, auditUsecase, trustedProxies),
+			Permit:          middlewares.Permit(l, authorizer, restService),
+			Task:            tasks.NewTaskService(taskuc, privacyUsecase, restService, l),
+			RecentChanges:   recent_changes.NewService(recentActionRepo, authorizer, restService, l),
+			Vacation:        vacation.NewService(restService, vacationUseCase, privacyUsecase, jobs, l),
+			Notifications:   notificationServ.NewService(restService, dispatcher, digester, l),
+			Events:          eventsServ.NewService(restService, broker, l),
+			Webhooks:        webhookServ.NewService(restService, webhooksUsecase, l),
 		})
 	server := web.NewServer(cfg.ListenURL, router, l, signal)
 	server.Start()
@@ -113,6 +250,29 @@ func LoadApplication(cfgFile string, signal chan os.Signal) (c Components, err e
 	return
 }
 
+// loadBase loads configuration, logger and cognito provider which are shared by application and command line tools
+func loadBase(cfgFile string) (c Components, sess *session.Session, err error) {
+	sess, err = session.NewSession()
+	if err != nil {
+		return c, nil, err
+	}
+
+	cfg, err := config.Load(cfgFile, sess)
+	if err != nil {
+		return Components{}, nil, err
+	}
+	c.Configuration = cfg
+	c.Cognito = awservices.GetCognitoProvider(sess, cfg.AWSRegion, cfg.UserPoolID, cfg.ClientID)
+
+	l, err := logger.Load(cfg.Logger)
+	if err != nil {
+		return Components{}, nil, err
+	}
+	c.Log = l
+	c.shutdowns = append(c.shutdowns, l.Close)
+	return c, sess, nil
+}
+
 func (c *Components) Stop() {
 	if c == nil {
 		return
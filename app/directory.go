@@ -0,0 +1,99 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Dimitriy14/staff-manager/elasticsearch"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository/user"
+	authUsecase "github.com/Dimitriy14/staff-manager/usecases/auth"
+	"github.com/Dimitriy14/staff-manager/usecases/directory"
+)
+
+const xlsxExtension = ".xlsx"
+
+// RunImport imports users from CSV or XLSX file chosen by extension and writes import report to stdout
+func RunImport(cfgFile, file string, dryRun bool) error {
+	c, d, err := loadDirectory(cfgFile)
+	if err != nil {
+		return err
+	}
+	defer c.Stop()
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var rows []models.ImportRow
+	if strings.EqualFold(filepath.Ext(file), xlsxExtension) {
+		rows, err = directory.ReadXLSX(content)
+	} else {
+		rows, err = directory.ReadCSV(bytes.NewReader(content))
+	}
+	if err != nil {
+		return err
+	}
+
+	report, err := d.Import(context.Background(), rows, dryRun)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// RunExport writes CSV with the columns of all users to file, fields are not hidden by privacy settings
+func RunExport(cfgFile, file string, columns []string) error {
+	c, d, err := loadDirectory(cfgFile)
+	if err != nil {
+		return err
+	}
+	defer c.Stop()
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	err = d.Export(context.Background(), f, columns)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadDirectory loads only components required by directory usecase
+func loadDirectory(cfgFile string) (Components, directory.DirectoryUsecase, error) {
+	c, _, err := loadBase(cfgFile)
+	if err != nil {
+		return Components{}, nil, err
+	}
+
+	es, err := elasticsearch.Load(c.Configuration.ElasticSearch, c.Log)
+	if err != nil {
+		c.Stop()
+		return Components{}, nil, err
+	}
+	c.ElasticSearch = es
+	c.shutdowns = append(c.shutdowns, es.Close)
+
+	userRepo := user.NewRepository(es)
+	err = userRepo.CheckIndex(context.Background())
+	if err != nil {
+		c.Stop()
+		return Components{}, nil, err
+	}
+
+	authuc := authUsecase.NewAuthUsecase(c.Cognito, c.Log)
+	return c, directory.NewDirectoryUsecase(userRepo, authuc, nil, c.Log), nil
+}
@@ -0,0 +1,26 @@
+package app
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/elasticsearch"
+	"github.com/Dimitriy14/staff-manager/repository/user"
+)
+
+// RunMigration migrates users index to the current mapping, it should be run once before new version is started
+func RunMigration(cfgFile string) error {
+	c, _, err := loadBase(cfgFile)
+	if err != nil {
+		return err
+	}
+	defer c.Stop()
+
+	es, err := elasticsearch.Load(c.Configuration.ElasticSearch, c.Log)
+	if err != nil {
+		return err
+	}
+	c.ElasticSearch = es
+	c.shutdowns = append(c.shutdowns, es.Close)
+
+	return user.NewRepository(es).Migrate(context.Background())
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/Dimitriy14/staff-manager/db"
 	"github.com/Dimitriy14/staff-manager/elasticsearch"
 	"github.com/Dimitriy14/staff-manager/logger"
+	"github.com/Dimitriy14/staff-manager/mail"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbox"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -28,11 +31,78 @@ type Configuration struct {
 	StorageURL    string
 	DB            db.Config
 	CognitoConfig
+
+	// TrustedProxies are IPs or CIDRs of proxies which X-Forwarded-For header is trusted, the header is ignored if it is empty
+	TrustedProxies []string `json:"TrustedProxies"`
+
+	// VacationApprovalChain contains ordered approval steps, single admin approval is used if it is empty,
+	// manager step is decided by direct manager of the requester
+	VacationApprovalChain []models.ApprovalRule `json:"VacationApprovalChain"`
+	// VacationDaysPerYear is yearly vacation allowance of a user
+	VacationDaysPerYear int `json:"VacationDaysPerYear"`
+	// VacationExpiryCron is a cron expression for vacations expiry job, job is disabled if it is empty
+	VacationExpiryCron string `json:"VacationExpiryCron"`
+	// Holidays are marked in absence calendar
+	Holidays []models.Holiday `json:"Holidays"`
+	// Onboarding contains templates of tasks which are created when new user is onboarded
+	Onboarding []models.OnboardingTask `json:"Onboarding"`
+
+	// Notifications configures delivery of recent changes through notification channels, delivery job is disabled if its cron is empty
+	Notifications NotificationsConfig `json:"Notifications"`
+	// SMTP is used by email notifications, credentials are taken from secret config
+	SMTP mail.Config `json:"SMTP"`
+	// Digest configures scheduled digest emails
+	Digest DigestConfig `json:"Digest"`
+	// Outbox configures relay of domain events to subscribers
+	Outbox outbox.Config `json:"Outbox"`
+	// Webhooks configures delivery of recent changes to integrators, delivery job is disabled if its cron is empty
+	Webhooks WebhooksConfig `json:"Webhooks"`
+	// Celebrations configures announcement of birthdays and work anniversaries
+	Celebrations CelebrationsConfig `json:"Celebrations"`
+	// Mood configures anonymization of team pulse
+	Mood MoodConfig `json:"Mood"`
+}
+
+type NotificationsConfig struct {
+	// DeliveryCron is a schedule of delivery job, e.g. "@every 10s"
+	DeliveryCron        string `json:"DeliveryCron"`
+	BatchSize           int    `json:"BatchSize"`
+	MaxAttempts         int    `json:"MaxAttempts"`
+	InitialBackoffInSec int    `json:"InitialBackoffInSec"`
+	WebhookTimeoutInSec int    `json:"WebhookTimeoutInSec"`
+}
+
+type DigestConfig struct {
+	// DailyCron and WeeklyCron are schedules of digest jobs, job is disabled if its cron is empty
+	DailyCron  string `json:"DailyCron"`
+	WeeklyCron string `json:"WeeklyCron"`
+	// AbsenceHorizonInDays defines how far ahead upcoming absences are included
+	AbsenceHorizonInDays int `json:"AbsenceHorizonInDays"`
+}
+
+type WebhooksConfig struct {
+	// DeliveryCron is a schedule of delivery job, e.g. "@every 10s"
+	DeliveryCron        string `json:"DeliveryCron"`
+	BatchSize           int    `json:"BatchSize"`
+	MaxAttempts         int    `json:"MaxAttempts"`
+	InitialBackoffInSec int    `json:"InitialBackoffInSec"`
+	TimeoutInSec        int    `json:"TimeoutInSec"`
+}
+
+type CelebrationsConfig struct {
+	// AnnouncementCron is a schedule of job announcing today celebrations to teammates, job is disabled if it is empty
+	AnnouncementCron string `json:"AnnouncementCron"`
+}
+
+type MoodConfig struct {
+	// MinGroupSize is a number of respondents required to show average mood of the team, 5 is used if it is not set
+	MinGroupSize int `json:"MinGroupSize"`
 }
 
 type SecretConfig struct {
 	Postgres db.Config     `json:"Postgres"`
 	Cognito  CognitoConfig `json:"Cognito"`
+	SMTP     mail.Config   `json:"SMTP"`
 }
 
 type CognitoConfig struct {
@@ -52,6 +122,12 @@ func Load(configFile string, sess *session.Session) (Configuration, error) {
 		return Configuration{}, errors.Wrap(err, "unmarshalling config")
 	}
 
+	for i, rule := range cfg.VacationApprovalChain {
+		if err = rule.Validate(); err != nil {
+			return Configuration{}, errors.Wrapf(err, "invalid step %d of VacationApprovalChain", i+1)
+		}
+	}
+
 	scfg, err := getSecretConfig(cfg.AWSSecretName, awservices.GetSecretsManager(sess, cfg.AWSRegion))
 	if err != nil {
 		return Configuration{}, errors.Wrap(err, "getting secret config")
@@ -59,6 +135,8 @@ func Load(configFile string, sess *session.Session) (Configuration, error) {
 
 	cfg.DB = scfg.Postgres
 	cfg.CognitoConfig = scfg.Cognito
+	cfg.SMTP.Username = scfg.SMTP.Username
+	cfg.SMTP.Password = scfg.SMTP.Password
 	cfg.StorageURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", cfg.BucketName, cfg.AWSRegion)
 
 	return cfg, nil
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/pkg/errors"
+)
+
+// TryLock runs fn while holding session advisory lock (class, key), lock is held on dedicated connection,
+// so fn can use transactions of its own. It returns false without running fn if lock is held by another session.
+func (c *Client) TryLock(ctx context.Context, class, key int32, fn func(ctx context.Context)) (bool, error) {
+	conn, err := c.Session.DB().Conn(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "getting connection for advisory lock")
+	}
+	defer conn.Close()
+
+	var locked bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, $2)", class, key).Scan(&locked)
+	if err != nil {
+		return false, errors.Wrap(err, "acquiring advisory lock")
+	}
+	if !locked {
+		return false, nil
+	}
+
+	fn(ctx)
+
+	_, err = conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1, $2)", class, key)
+	if err != nil {
+		// lock is released with the session, so connection is closed instead of returning it to the pool
+		_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		return true, errors.Wrap(err, "releasing advisory lock")
+	}
+	return true, nil
+}
@@ -27,15 +27,16 @@ type Config struct {
 	DataBaseName string `json:"DataBaseName"`
 }
 
-func Load(cfg Config, log logger.Logger) (*Client, error) {
-	var (
-		postgres = "postgres"
-		dbInfo   = "host=%s port=%s user=%s password=%s dbname=%s sslmode=disable"
-	)
+// DataSourceName returns connection string for postgres driver
+func (cfg Config) DataSourceName() string {
+	const dbInfo = "host=%s port=%s user=%s password=%s dbname=%s sslmode=disable"
+	return fmt.Sprintf(dbInfo, cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DataBaseName)
+}
 
-	url := fmt.Sprintf(dbInfo, cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DataBaseName)
+func Load(cfg Config, log logger.Logger) (*Client, error) {
+	var postgres = "postgres"
 
-	db, err := gorm.Open(postgres, url)
+	db, err := gorm.Open(postgres, cfg.DataSourceName())
 	if err != nil {
 		return nil, errors.Wrap(err, "connecting to postgres:")
 	}
@@ -43,7 +44,14 @@ func Load(cfg Config, log logger.Logger) (*Client, error) {
 	db.SetLogger(logger.NewGORMLogger(log))
 	db.LogMode(true)
 
-	db.AutoMigrate(&models.RecentChanges{}, &models.VacationDB{})
+	db.AutoMigrate(&models.RecentChanges{}, &models.VacationDB{}, &models.VacationApproval{}, &models.VacationComment{}, &models.FeedToken{},
+		&models.NotificationPreference{}, &models.NotificationDelivery{}, &models.RecentChangeMark{}, &models.OutboxEvent{},
+		&models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.AuditEntry{}, &models.DigestSubscription{}, &models.Department{},
+		&models.PrivacyPolicy{}, &models.MoodCheckIn{}, &models.ProfileChangeRequest{}, &models.VacationBalance{})
+
+	// audit log is append-only, updates and deletes are silently ignored
+	db.Exec("CREATE OR REPLACE RULE audit_entries_no_update AS ON UPDATE TO audit_entries DO INSTEAD NOTHING")
+	db.Exec("CREATE OR REPLACE RULE audit_entries_no_delete AS ON DELETE TO audit_entries DO INSTEAD NOTHING")
 	return &Client{Session: db, addr: fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)}, nil
 }
 
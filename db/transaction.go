@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+type txKey struct{}
+
+// InTransaction runs fn in a transaction which is passed to repositories through context,
+// fn joins the outer transaction if it is already started
+func (c *Client) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	return c.Session.Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// InSavepoint runs fn in a savepoint of transaction from context, changes of failed fn are rolled back
+// while transaction stays usable. fn is run in a new transaction if there is no transaction in context.
+func (c *Client) InSavepoint(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	if !ok {
+		return c.InTransaction(ctx, fn)
+	}
+
+	if err := tx.Exec(fmt.Sprintf("SAVEPOINT %s", name)).Error; err != nil {
+		return errors.Wrapf(err, "creating savepoint %s", name)
+	}
+
+	if err := fn(ctx); err != nil {
+		if rbErr := tx.Exec(fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", name)).Error; rbErr != nil {
+			return errors.Wrapf(rbErr, "rolling back to savepoint %s after error: %s", name, err)
+		}
+		return err
+	}
+
+	return errors.Wrapf(tx.Exec(fmt.Sprintf("RELEASE SAVEPOINT %s", name)).Error, "releasing savepoint %s", name)
+}
+
+// Conn returns transaction started by InTransaction or session if there is no transaction in context
+func (c *Client) Conn(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx
+	}
+	return c.Session
+}
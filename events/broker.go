@@ -0,0 +1,152 @@
+// Package events streams recent changes to connected users.
+// IDs of changes are published through postgres NOTIFY so every service instance receives them,
+// loads the changes and forwards them to its own subscribers. Changes themselves are not sent
+// as NOTIFY payload is limited to 8000 bytes.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	channelName = "recent_changes"
+
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+
+	// subscriberBuffer is a number of changes kept for slow subscriber, changes are dropped when it is full
+	subscriberBuffer = 16
+)
+
+func NewBroker(cfg db.Config, client *db.Client, changes repository.RecentActionRepository, log logger.Logger) *Broker {
+	b := &Broker{
+		client:      client,
+		changes:     changes,
+		log:         log,
+		subscribers: make(map[string]map[chan models.RecentChanges]struct{}),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	b.listener = pq.NewListener(cfg.DataSourceName(), minReconnectInterval, maxReconnectInterval, b.logListenerEvent)
+	return b
+}
+
+type Broker struct {
+	client   *db.Client
+	changes  repository.RecentActionRepository
+	listener *pq.Listener
+	log      logger.Logger
+
+	mu          sync.RWMutex
+	subscribers map[string]map[chan models.RecentChanges]struct{}
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (b *Broker) Start() error {
+	err := b.listener.Listen(channelName)
+	if err != nil {
+		return errors.Wrap(err, "listening to recent changes channel")
+	}
+
+	go b.run()
+	return nil
+}
+
+func (b *Broker) Stop() error {
+	close(b.stop)
+	<-b.done
+	return b.listener.Close()
+}
+
+// Broadcast publishes saved change to all service instances, notification is sent by postgres
+// when transaction from context is committed and dropped when it is rolled back
+func (b *Broker) Broadcast(ctx context.Context, change models.RecentChanges) {
+	err := b.client.Conn(ctx).Exec("SELECT pg_notify(?, ?)", channelName, change.ID.String()).Error
+	if err != nil {
+		b.log.Errorf(transactionID.FromContext(ctx), "cannot publish recent change %s: err=%s", change.ID, err)
+	}
+}
+
+// Subscribe returns channel with changes related to the user, returned function must be called to unsubscribe
+func (b *Broker) Subscribe(userID string) (<-chan models.RecentChanges, func()) {
+	ch := make(chan models.RecentChanges, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan models.RecentChanges]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers[userID], ch)
+		if len(b.subscribers[userID]) == 0 {
+			delete(b.subscribers, userID)
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (b *Broker) run() {
+	defer close(b.done)
+
+	for {
+		select {
+		case n := <-b.listener.Notify:
+			// nil notification is sent after reconnect, changes published meanwhile are lost
+			if n == nil {
+				continue
+			}
+
+			change, err := b.changes.GetByID(n.Extra)
+			if err != nil {
+				b.log.Errorf("", "cannot load recent change %s: err=%s", n.Extra, err)
+				continue
+			}
+			b.fanOut(change)
+		case <-b.stop:
+			return
+		}
+	}
+}
+
+func (b *Broker) fanOut(change models.RecentChanges) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	users := []string{change.UserID}
+	if change.OwnerID != change.UserID {
+		users = append(users, change.OwnerID)
+	}
+
+	for _, userID := range users {
+		for ch := range b.subscribers[userID] {
+			select {
+			case ch <- change:
+			default:
+				b.log.Warnf("", "subscriber of user %s is too slow, recent change %s is dropped", userID, change.ID)
+			}
+		}
+	}
+}
+
+func (b *Broker) logListenerEvent(event pq.ListenerEventType, err error) {
+	if err != nil {
+		b.log.Warnf("", "recent changes listener event %d: err=%s", event, err)
+	}
+}
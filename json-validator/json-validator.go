@@ -12,15 +12,28 @@ import (
 
 var (
 	schemaNames = map[string]string{
-		schemas.UserRegistration:     schemas.UserRegistrationSchema,
-		schemas.SignIn:               schemas.SignInSchema,
-		schemas.UserUpdate:           schemas.UserUpdateSchema,
-		schemas.AdminUserUpdate:      schemas.AdminUserUpdateSchema,
-		schemas.TaskCreation:         schemas.TaskCreationSchema,
-		schemas.TaskUpdate:           schemas.TaskUpdateSchema,
-		schemas.TaskSearch:           schemas.TaskSearchSchema,
-		schemas.VacationCreate:       schemas.VacationCreateSchema,
-		schemas.VacationStatusUpdate: schemas.VacationStatusUpdateSchema,
+		schemas.UserRegistration:        schemas.UserRegistrationSchema,
+		schemas.SignIn:                  schemas.SignInSchema,
+		schemas.UserUpdate:              schemas.UserUpdateSchema,
+		schemas.AdminUserUpdate:         schemas.AdminUserUpdateSchema,
+		schemas.TaskCreation:            schemas.TaskCreationSchema,
+		schemas.TaskUpdate:              schemas.TaskUpdateSchema,
+		schemas.TaskSearch:              schemas.TaskSearchSchema,
+		schemas.VacationCreate:          schemas.VacationCreateSchema,
+		schemas.VacationStatusUpdate:    schemas.VacationStatusUpdateSchema,
+		schemas.VacationComment:         schemas.VacationCommentSchema,
+		schemas.VacationEndDateUpdate:   schemas.VacationEndDateUpdateSchema,
+		schemas.NotificationPreferences: schemas.NotificationPreferencesSchema,
+		schemas.DigestSubscription:      schemas.DigestSubscriptionSchema,
+		schemas.WebhookSubscription:     schemas.WebhookSubscriptionSchema,
+		schemas.Department:              schemas.DepartmentSchema,
+		schemas.PrivacySettings:         schemas.PrivacySettingsSchema,
+		schemas.PrivacyPolicy:           schemas.PrivacyPolicySchema,
+		schemas.EmploymentStatusUpdate:  schemas.EmploymentStatusUpdateSchema,
+		schemas.Termination:             schemas.TerminationSchema,
+		schemas.MoodCheckIn:             schemas.MoodCheckInSchema,
+		schemas.ProfileChangeRequest:    schemas.ProfileChangeRequestSchema,
+		schemas.ChangeRequestReview:     schemas.ChangeRequestReviewSchema,
 	}
 
 	schemasMap = map[string]*gojsonschema.Schema{}
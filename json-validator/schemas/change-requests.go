@@ -0,0 +1,60 @@
+package schemas
+
+var ProfileChangeRequest = "ProfileChangeRequest"
+var ProfileChangeRequestSchema = `
+{
+    "type": "object",
+    "properties": {
+        "firstName": {
+            "type": "string",
+            "minLength": 1
+        },
+        "lastName": {
+            "type": "string",
+            "minLength": 1
+        },
+        "position": {
+            "type": "string",
+            "minLength": 1
+        },
+        "reason": {
+            "type": "string",
+            "maxLength": 2000
+        }
+    },
+    "anyOf": [
+        {"required": ["firstName"]},
+        {"required": ["lastName"]},
+        {"required": ["position"]}
+    ],
+    "additionalProperties": false
+}
+`
+
+var ChangeRequestReview = "ChangeRequestReview"
+var ChangeRequestReviewSchema = `
+{
+    "type": "object",
+    "properties": {
+        "status": {
+            "type": "string",
+            "enum": ["Approved", "Rejected"]
+        },
+        "comment": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 2000
+        }
+    },
+    "required": ["status"],
+    "if": {
+        "properties": {
+            "status": {"const": "Rejected"}
+        }
+    },
+    "then": {
+        "required": ["comment"]
+    },
+    "additionalProperties": false
+}
+`
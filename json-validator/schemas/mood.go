@@ -0,0 +1,21 @@
+package schemas
+
+var MoodCheckIn = "MoodCheckIn"
+var MoodCheckInSchema = `
+{
+    "type": "object",
+    "properties": {
+        "score": {
+            "type": "integer",
+            "minimum": 1,
+            "maximum": 5
+        },
+        "note": {
+            "type": "string",
+            "maxLength": 1000
+        }
+    },
+    "required": ["score"],
+    "additionalProperties": false
+}
+`
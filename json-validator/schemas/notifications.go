@@ -0,0 +1,89 @@
+package schemas
+
+var NotificationPreferences = "NotificationPreferences"
+var NotificationPreferencesSchema = `
+{
+    "type": "array",
+    "items": {
+        "type": "object",
+        "properties": {
+            "channel": {
+                "type": "string",
+                "enum": ["email", "webhook", "slack"]
+            },
+            "target": {
+                "type": "string",
+                "maxLength": 2000
+            },
+            "eventTypes": {
+                "type": "array",
+                "minItems": 1,
+                "uniqueItems": true,
+                "items": {
+                    "type": "string",
+                    "enum": ["Assignment", "TaskStatusChange", "TaskDeletion", "VacationStatusChange", "VacationRequest",
+                        "VacationApprovalStep", "VacationCommentAdded", "VacationShortened", "Delegation", "Mention", "Birthday", "WorkAnniversary", "ProfileChangeReview"]
+                }
+            }
+        },
+        "required": ["channel", "eventTypes"],
+        "if": {
+            "properties": {"channel": {"enum": ["webhook", "slack"]}}
+        },
+        "then": {
+            "properties": {"target": {"pattern": "^https://"}},
+            "required": ["target"]
+        },
+        "additionalProperties": false
+    }
+}
+`
+
+var DigestSubscription = "DigestSubscription"
+var DigestSubscriptionSchema = `
+{
+    "type": "object",
+    "properties": {
+        "frequency": {
+            "type": "string",
+            "enum": ["none", "daily", "weekly"]
+        }
+    },
+    "required": ["frequency"],
+    "additionalProperties": false
+}
+`
+
+var WebhookSubscription = "WebhookSubscription"
+var WebhookSubscriptionSchema = `
+{
+    "type": "object",
+    "properties": {
+        "url": {
+            "type": "string",
+            "pattern": "^https?://",
+            "maxLength": 2000
+        },
+        "secret": {
+            "type": "string",
+            "minLength": 16,
+            "maxLength": 256
+        },
+        "eventTypes": {
+            "type": "array",
+            "minItems": 1,
+            "uniqueItems": true,
+            "items": {
+                "type": "string",
+                "enum": ["Assignment", "TaskStatusChange", "TaskDeletion", "VacationStatusChange", "VacationRequest",
+                    "VacationApprovalStep", "VacationCommentAdded", "VacationShortened", "Delegation", "Mention", "Birthday", "WorkAnniversary", "ProfileChangeReview"]
+            }
+        },
+        "active": {
+            "type": "boolean"
+        }
+    },
+    "required": ["url", "eventTypes"],
+    "additionalProperties": false
+}
+`
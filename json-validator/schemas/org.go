@@ -0,0 +1,21 @@
+package schemas
+
+var Department = "Department"
+var DepartmentSchema = `
+{
+    "type": "object",
+    "properties": {
+        "name": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 200
+        },
+        "parentID": {
+            "type": "string",
+            "pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+        }
+    },
+    "required": ["name"],
+    "additionalProperties": false
+}
+`
@@ -0,0 +1,38 @@
+package schemas
+
+var PrivacySettings = "PrivacySettings"
+var PrivacySettingsSchema = `
+{
+    "type": "object",
+    "properties": {
+        "mobilePhone": {
+            "type": "string",
+            "enum": ["public", "team", "manager", "hr", "self"]
+        },
+        "dateOfBirth": {
+            "type": "string",
+            "enum": ["public", "team", "manager", "hr", "self"]
+        },
+        "hireDate": {
+            "type": "string",
+            "enum": ["public", "team", "manager", "hr", "self"]
+        }
+    },
+    "additionalProperties": false
+}
+`
+
+var PrivacyPolicy = "PrivacyPolicy"
+var PrivacyPolicySchema = `
+{
+    "type": "object",
+    "properties": {
+        "minimum": {
+            "type": "string",
+            "enum": ["public", "team", "manager", "hr", "self"]
+        }
+    },
+    "required": ["minimum"],
+    "additionalProperties": false
+}
+`
@@ -16,7 +16,11 @@ var TaskCreationSchema = `
 		"assignedID": {
 			"type": "string",
             "pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
-        }
+        },
+		"dueDate": {
+			"type": "string",
+			"format": "date-time"
+		}
 	},
 	"required": ["title", "description"],
     "additionalProperties": false
@@ -43,7 +47,11 @@ var TaskUpdateSchema = `
 		"assignedID": {
 			"type": "string",
             "pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
-        }
+        },
+		"dueDate": {
+			"type": "string",
+			"format": "date-time"
+		}
 	},
 	"required": ["title", "description"],
     "additionalProperties": false
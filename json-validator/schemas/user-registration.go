@@ -19,7 +19,7 @@ var UserRegistrationSchema = `
 		},
         "role": {
 			"type": "string",
-            "enum": ["admin", "user"]
+            "enum": ["admin", "hr", "manager", "user"]
 		},
 		"email": {
 			"type": "string",
@@ -68,8 +68,80 @@ var UserUpdateSchema = `
 			"minLength": 8
 		},
         "mood": {
+            "description": "deprecated and ignored, mood is tracked by check-ins",
+            "type": "string"
+        },
+        "skills": {
+            "type": "array",
+            "maxItems": 50,
+            "items": {
+                "type": "object",
+                "properties": {
+                    "name": {
+                        "type": "string",
+                        "minLength": 1,
+                        "maxLength": 50
+                    },
+                    "proficiency": {
+                        "type": "string",
+                        "enum": ["beginner", "intermediate", "advanced", "expert"]
+                    }
+                },
+                "required": ["name", "proficiency"],
+                "additionalProperties": false
+            }
+        },
+        "languages": {
+            "type": "array",
+            "maxItems": 20,
+            "items": {
+                "type": "object",
+                "properties": {
+                    "name": {
+                        "type": "string",
+                        "minLength": 1,
+                        "maxLength": 50
+                    },
+                    "level": {
+                        "type": "string",
+                        "enum": ["basic", "conversational", "fluent", "native"]
+                    }
+                },
+                "required": ["name", "level"],
+                "additionalProperties": false
+            }
+        },
+        "location": {
+            "type": "string",
+            "maxLength": 100
+        },
+        "timeZone": {
+            "type": "string",
+            "maxLength": 64
+        },
+        "bio": {
             "type": "string",
-            "minLength": 1
+            "maxLength": 1000
+        },
+        "links": {
+            "type": "array",
+            "maxItems": 10,
+            "items": {
+                "type": "object",
+                "properties": {
+                    "title": {
+                        "type": "string",
+                        "minLength": 1,
+                        "maxLength": 50
+                    },
+                    "url": {
+                        "type": "string",
+                        "pattern": "^https?://"
+                    }
+                },
+                "required": ["title", "url"],
+                "additionalProperties": false
+            }
         }
 	},
     "additionalProperties": false
@@ -83,11 +155,15 @@ var AdminUserUpdateSchema = `
 	"properties": {
         "mobilePhone": {
 			"type": "string",
-			"minLength": 8
+			"anyOf": [{"maxLength": 0}, {"minLength": 8}]
 		},
         "dateOfBirth": {
 			"type": "string",
-			"minLength": 8
+			"anyOf": [{"maxLength": 0}, {"minLength": 8}]
+		},
+        "hireDate": {
+			"type": "string",
+			"pattern": "^([0-9]{4}-[0-9]{2}-[0-9]{2})?$"
 		},
         "firstName": {
 			"type": "string",
@@ -103,10 +179,47 @@ var AdminUserUpdateSchema = `
 		},
         "role": {
 			"type": "string",
-            "enum": ["admin", "user"]
+            "enum": ["admin", "hr", "manager", "user"]
+		},
+		"managerID": {
+			"type": "string",
+			"pattern": "^([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})?$"
+		},
+		"departmentID": {
+			"type": "string",
+			"pattern": "^([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})?$"
 		}
 	},
-    "required": ["firstName", "lastName", "position", "role"],
+    "minProperties": 1,
+    "additionalProperties": false
+}
+`
+
+var EmploymentStatusUpdate = "EmploymentStatusUpdate"
+var EmploymentStatusUpdateSchema = `
+{
+    "type": "object",
+    "properties": {
+        "status": {
+            "type": "string",
+            "enum": ["active", "on-leave"]
+        }
+    },
+    "required": ["status"],
+    "additionalProperties": false
+}
+`
+
+var Termination = "Termination"
+var TerminationSchema = `
+{
+    "type": "object",
+    "properties": {
+        "reassignTo": {
+            "type": "string",
+            "pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
+        }
+    },
     "additionalProperties": false
 }
 `
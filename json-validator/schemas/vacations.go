@@ -12,6 +12,10 @@ var VacationCreateSchema = `
         "endDate": {
             "type": "string",
 			"format": "date"
+        },
+		"delegateID": {
+			"type": "string",
+            "pattern": "^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$"
         }
 	},
 	"required": ["startDate", "endDate"],
@@ -27,9 +31,53 @@ var VacationStatusUpdateSchema = `
         "status": {
 			"type": "string",
             "enum": ["Approved", "Rejected"]
-		}
+		},
+        "comment": {
+            "type": "string",
+            "minLength": 1,
+            "maxLength": 2000
+        }
 	},
 	"required": ["status"],
+    "if": {
+        "properties": {
+            "status": {"const": "Rejected"}
+        }
+    },
+    "then": {
+        "required": ["comment"]
+    },
+    "additionalProperties": false
+}
+`
+
+var VacationComment = "VacationComment"
+var VacationCommentSchema = `
+{
+    "type": "object",
+	"properties": {
+        "text": {
+			"type": "string",
+            "minLength": 1,
+            "maxLength": 2000
+		}
+	},
+	"required": ["text"],
+    "additionalProperties": false
+}
+`
+
+var VacationEndDateUpdate = "VacationEndDateUpdate"
+var VacationEndDateUpdateSchema = `
+{
+    "type": "object",
+	"properties": {
+        "endDate": {
+            "type": "string",
+			"format": "date"
+        }
+	},
+	"required": ["endDate"],
     "additionalProperties": false
 }
 `
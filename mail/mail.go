@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Config contains SMTP server settings, Username and Password can be omitted for servers without auth (e.g. local mail catcher)
+type Config struct {
+	Host     string `json:"Host"`
+	Port     string `json:"Port"`
+	From     string `json:"From"`
+	Username string `json:"Username"`
+	Password string `json:"Password"`
+}
+
+// Message is an email with plain text and optional HTML body
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Sender interface {
+	Send(msg Message) error
+}
+
+func NewSender(cfg Config) *smtpSender {
+	return &smtpSender{cfg: cfg}
+}
+
+type smtpSender struct {
+	cfg Config
+}
+
+func (s *smtpSender) Send(msg Message) error {
+	if s.cfg.Host == "" {
+		return errors.New("SMTP server is not configured")
+	}
+
+	body, err := buildMessage(s.cfg.From, msg)
+	if err != nil {
+		return errors.Wrap(err, "building email")
+	}
+
+	var auth smtp.Auth
+	if s.cfg.Username != "" {
+		auth = smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+	}
+
+	err = smtp.SendMail(fmt.Sprintf("%s:%s", s.cfg.Host, s.cfg.Port), auth, s.cfg.From, msg.To, body)
+	return errors.Wrapf(err, "sending email to %v", msg.To)
+}
+
+func buildMessage(from string, msg Message) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buf.WriteString(msg.Text)
+		return buf.Bytes(), nil
+	}
+
+	w := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=utf-8", content: msg.Text},
+		{contentType: "text/html; charset=utf-8", content: msg.HTML},
+	}
+
+	for _, p := range parts {
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {p.contentType}})
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err = pw.Write([]byte(p.content)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
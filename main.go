@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/Dimitriy14/staff-manager/app"
@@ -12,8 +13,35 @@ import (
 
 func main() {
 	config := flag.String("config", "config.json", "-config path/to/config/file.json")
+	importFile := flag.String("import", "", "-import path/to/users.csv imports users from CSV or XLSX file and exits")
+	dryRun := flag.Bool("dry-run", false, "-dry-run reports what import would do without creating users")
+	exportFile := flag.String("export", "", "-export path/to/users.csv exports users to CSV file and exits")
+	columns := flag.String("columns", "", "-columns id,email,firstName selects exported columns")
+	migrate := flag.Bool("migrate", false, "-migrate migrates users index to the current mapping and exits")
 	flag.Parse()
 
+	switch {
+	case *migrate:
+		if err := app.RunMigration(*config); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	case *importFile != "":
+		if err := app.RunImport(*config, *importFile, *dryRun); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	case *exportFile != "":
+		var exported []string
+		if *columns != "" {
+			exported = strings.Split(*columns, ",")
+		}
+		if err := app.RunExport(*config, *exportFile, exported); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
 	s := make(chan os.Signal, 1)
 	signal.Notify(s,
 		syscall.SIGHUP,
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry is an immutable record of mutation. Entries are chained by hashes:
+// Hash covers all entry fields and PrevHash, so modified or removed entry breaks the chain.
+type AuditEntry struct {
+	ID            uuid.UUID `json:"id" gorm:"primary_key"`
+	Seq           int64     `json:"seq" gorm:"AUTO_INCREMENT;unique_index"`
+	Time          time.Time `json:"time"`
+	ActorID       string    `json:"actorID,omitempty" gorm:"index"`
+	Action        string    `json:"action" gorm:"index"`
+	TargetType    string    `json:"targetType,omitempty"`
+	TargetID      string    `json:"targetID,omitempty" gorm:"index"`
+	Before        string    `json:"before,omitempty" gorm:"type:text"`
+	After         string    `json:"after,omitempty" gorm:"type:text"`
+	Diff          string    `json:"diff,omitempty" gorm:"type:text"`
+	StatusCode    int       `json:"statusCode"`
+	TransactionID string    `json:"transactionID"`
+	SourceIP      string    `json:"sourceIP"`
+	PrevHash      string    `json:"prevHash"`
+	Hash          string    `json:"hash"`
+}
+
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	From       *time.Time
+	To         *time.Time
+	// BeforeSeq is a cursor of pages, only entries with lower sequence number are returned if it is set
+	BeforeSeq int64
+	Limit     int
+	Offset    int
+}
+
+// AuditVerification is a result of hash chain check, BrokenAt is a sequence number of the first entry which does not match
+type AuditVerification struct {
+	Valid    bool  `json:"valid"`
+	Checked  int   `json:"checked"`
+	BrokenAt int64 `json:"brokenAt,omitempty"`
+}
+
+// FieldChange is a value of field before and after mutation
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type LeaveType string
+
+const (
+	VacationLeave LeaveType = "Vacation"
+)
+
+// Holiday is a public holiday configured for the whole team, Date has "2006-01-02" format
+type Holiday struct {
+	Date string `json:"Date"`
+	Name string `json:"Name"`
+}
+
+type CalendarDay struct {
+	Date      string    `json:"date"`
+	IsWeekend bool      `json:"isWeekend"`
+	Holiday   string    `json:"holiday,omitempty"`
+	Absences  []Absence `json:"absences"`
+}
+
+type Absence struct {
+	UserID       string    `json:"userID"`
+	UserFullName string    `json:"userFullName"`
+	Position     string    `json:"position"`
+	VacationID   uuid.UUID `json:"vacationID"`
+	LeaveType    LeaveType `json:"leaveType"`
+}
+
+// FeedToken is a secret which gives access to iCalendar feed of absences without authentication
+type FeedToken struct {
+	// Token is stored as SHA-256 hash, plain token is returned only when it is issued
+	Token     string    `json:"token" gorm:"primary_key"`
+	UserID    string    `json:"userID"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// FeedEvent is an all-day event of iCalendar feed, End date is inclusive
+type FeedEvent struct {
+	UID     string
+	Summary string
+	Start   time.Time
+	End     time.Time
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// DateLayout is a layout of dates of birth and hire dates
+const DateLayout = "2006-01-02"
+
+// legacyDateLayouts are accepted in addition to DateLayout, dates of birth were stored as free-form strings before
+var legacyDateLayouts = []string{"02.01.2006", "2006/01/02", "02/01/2006", time.RFC3339}
+
+type CelebrationType string
+
+const (
+	BirthdayCelebration    CelebrationType = "birthday"
+	AnniversaryCelebration CelebrationType = "anniversary"
+)
+
+// Celebration is a birthday or work anniversary of the user
+type Celebration struct {
+	Type CelebrationType `json:"type"`
+	// Date is a day of the celebration
+	Date     string `json:"date"`
+	UserID   string `json:"userID"`
+	UserName string `json:"userName"`
+	ImageURL string `json:"imageURL,omitempty"`
+	// Years worked at the company, it is set only for anniversaries
+	Years int `json:"years,omitempty"`
+}
+
+// ParseDate parses date in DateLayout or one of legacy layouts
+func ParseDate(value string) (time.Time, error) {
+	date, err := time.Parse(DateLayout, value)
+	if err == nil {
+		return date, nil
+	}
+
+	for _, layout := range legacyDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, NewErrInvalidData("date %s should be in %s format", value, DateLayout)
+}
+
+// NormalizeDate converts date to DateLayout, empty date is kept empty
+func NormalizeDate(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	date, err := ParseDate(value)
+	if err != nil {
+		return "", err
+	}
+	return date.Format(DateLayout), nil
+}
+
+// NextOccurrence returns the first day not before from when the yearly date occurs,
+// February 29 occurs on February 28 in common years
+func NextOccurrence(date, from time.Time) time.Time {
+	for year := from.Year(); ; year++ {
+		day := date.Day()
+		if date.Month() == time.February && day == 29 && !isLeap(year) {
+			day = 28
+		}
+
+		occurrence := time.Date(year, date.Month(), day, 0, 0, 0, 0, time.UTC)
+		if !occurrence.Before(from) {
+			return occurrence
+		}
+	}
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
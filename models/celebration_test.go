@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     string
+		from     string
+		expected string
+	}{
+		{name: "later this year", date: "1990-06-15", from: "2021-03-01", expected: "2021-06-15"},
+		{name: "today", date: "1990-06-15", from: "2021-06-15", expected: "2021-06-15"},
+		{name: "passed this year", date: "1990-06-15", from: "2021-06-16", expected: "2022-06-15"},
+		{name: "February 29 in common year", date: "1992-02-29", from: "2021-01-10", expected: "2021-02-28"},
+		{name: "February 29 in leap year", date: "1992-02-29", from: "2024-02-28", expected: "2024-02-29"},
+		{name: "February 29 passed before leap year", date: "1992-02-29", from: "2023-03-01", expected: "2024-02-29"},
+		{name: "February 29 passed in leap year", date: "1992-02-29", from: "2024-03-01", expected: "2025-02-28"},
+		{name: "February 29 in century year", date: "1996-02-29", from: "2100-01-01", expected: "2100-02-28"},
+		{name: "February 29 in year divisible by 400", date: "1996-02-29", from: "2000-01-01", expected: "2000-02-29"},
+		{name: "new year", date: "1990-01-01", from: "2021-12-31", expected: "2022-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, err := ParseDate(tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from, err := time.Parse(DateLayout, tt.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := NextOccurrence(date, from).Format(DateLayout); got != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	for _, value := range []string{"1992-02-29", "29.02.1992", "1992/02/29", "29/02/1992", "1992-02-29T10:00:00+03:00"} {
+		date, err := ParseDate(value)
+		if err != nil {
+			t.Fatalf("%s: %s", value, err)
+		}
+		if got := date.Format(DateLayout); got != "1992-02-29" {
+			t.Fatalf("%s: expected 1992-02-29, got %s", value, got)
+		}
+	}
+
+	if _, err := ParseDate("1991-02-29"); !IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data for February 29 of common year, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ChangeRequestStatus string
+
+const (
+	ChangeRequestPending  ChangeRequestStatus = Pending
+	ChangeRequestApproved ChangeRequestStatus = Approved
+	ChangeRequestRejected ChangeRequestStatus = Rejected
+	ChangeRequestCanceled ChangeRequestStatus = Canceled
+)
+
+// ProfileChanges are requested values of restricted fields which employees cannot change themselves,
+// fields which are not requested are nil
+type ProfileChanges struct {
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Position  *string `json:"position,omitempty"`
+}
+
+// ProfileChangeRequest is a request of the user to change restricted fields which is approved or rejected by admin
+type ProfileChangeRequest struct {
+	ID     uuid.UUID `json:"id" gorm:"primary_key"`
+	UserID string    `json:"userID" gorm:"index"`
+	ProfileChanges
+	// Previous are values of requested fields before the change, they are current values while request is pending
+	Previous      ProfileChanges      `json:"previous" gorm:"embedded;embedded_prefix:previous_"`
+	Reason        string              `json:"reason,omitempty" gorm:"type:text"`
+	Status        ChangeRequestStatus `json:"status" gorm:"index"`
+	ReviewerID    string              `json:"reviewerID,omitempty"`
+	ReviewComment string              `json:"reviewComment,omitempty" gorm:"type:text"`
+	CreatedAt     time.Time           `json:"createdAt"`
+	ReviewedAt    *time.Time          `json:"reviewedAt,omitempty"`
+	// Diff contains only fields which values are changed by the request
+	Diff map[string]FieldChange `json:"diff" gorm:"-"`
+}
+
+type ProfileChangeRequestReq struct {
+	ProfileChanges
+	Reason string `json:"reason"`
+}
+
+type ChangeRequestReview struct {
+	Status  ChangeRequestStatus `json:"status"`
+	Comment string              `json:"comment"`
+}
+
+type ChangeRequestFilter struct {
+	UserID string
+	Status ChangeRequestStatus
+}
+
+// IsEmpty returns true if no field is requested
+func (c ProfileChanges) IsEmpty() bool {
+	return c.FirstName == nil && c.LastName == nil && c.Position == nil
+}
+
+// Of returns values of the user's fields which are requested by c
+func (c ProfileChanges) Of(u User) ProfileChanges {
+	var values ProfileChanges
+	if c.FirstName != nil {
+		values.FirstName = &u.FirstName
+	}
+	if c.LastName != nil {
+		values.LastName = &u.LastName
+	}
+	if c.Position != nil {
+		values.Position = &u.Position
+	}
+	return values
+}
+
+// Patch returns patch which applies requested values to the user
+func (c ProfileChanges) Patch() UserPatch {
+	return UserPatch{
+		FirstName: c.FirstName,
+		LastName:  c.LastName,
+		Position:  c.Position,
+	}
+}
+
+// FillDiff sets Diff from previous and requested values
+func (r *ProfileChangeRequest) FillDiff() {
+	r.Diff = make(map[string]FieldChange)
+	addDiff(r.Diff, "firstName", r.Previous.FirstName, r.FirstName)
+	addDiff(r.Diff, "lastName", r.Previous.LastName, r.LastName)
+	addDiff(r.Diff, "position", r.Previous.Position, r.Position)
+}
+
+func addDiff(diff map[string]FieldChange, field string, before, after *string) {
+	if after == nil {
+		return
+	}
+
+	var previous string
+	if before != nil {
+		previous = *before
+	}
+	if previous != *after {
+		diff[field] = FieldChange{Before: previous, After: *after}
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+)
+
+type DigestFrequency string
+
+const (
+	NoDigest     DigestFrequency = "none"
+	DailyDigest  DigestFrequency = "daily"
+	WeeklyDigest DigestFrequency = "weekly"
+)
+
+// Period returns how long digest covers
+func (f DigestFrequency) Period() time.Duration {
+	if f == WeeklyDigest {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// DigestSubscription is a user choice of digest emails, LastSentAt is a bound of recent changes included into the next digest
+type DigestSubscription struct {
+	UserID     string          `json:"userID" gorm:"primary_key"`
+	Frequency  DigestFrequency `json:"frequency"`
+	LastSentAt *time.Time      `json:"lastSentAt,omitempty"`
+}
+
+// Digest is a summary of changes since the previous digest, tasks which need attention and upcoming absences
+type Digest struct {
+	User         User            `json:"user"`
+	Frequency    DigestFrequency `json:"frequency"`
+	Since        time.Time       `json:"since"`
+	Until        time.Time       `json:"until"`
+	Changes      []RecentChanges `json:"changes"`
+	OverdueTasks []TaskElastic   `json:"overdueTasks"`
+	BlockedTasks []TaskElastic   `json:"blockedTasks"`
+	Absences     []VacationDB    `json:"absences"`
+}
+
+// IsEmpty returns true if there is nothing to tell in the digest
+func (d Digest) IsEmpty() bool {
+	return len(d.Changes) == 0 && len(d.OverdueTasks) == 0 && len(d.BlockedTasks) == 0 && len(d.Absences) == 0
+}
+
+// DigestPreview is a rendered digest email
+type DigestPreview struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
@@ -0,0 +1,75 @@
+package models
+
+// ImportAction is what import does or would do in dry run with a row
+type ImportAction string
+
+const (
+	CreateImportAction ImportAction = "create"
+	// SkipImportAction is used for rows whose email already belongs to a user
+	SkipImportAction  ImportAction = "skip"
+	ErrorImportAction ImportAction = "error"
+)
+
+type ImportRow struct {
+	// Line is a line number in the file, header is the first line
+	Line int
+	// Values are keyed by column headers
+	Values map[string]string
+}
+
+type ImportRowResult struct {
+	Row    int          `json:"row"`
+	Email  string       `json:"email,omitempty"`
+	Action ImportAction `json:"action"`
+	UserID string       `json:"userID,omitempty"`
+	Errors []string     `json:"errors,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun  bool              `json:"dryRun"`
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// ExportColumns are columns which can be selected for directory export, in default order
+var ExportColumns = []string{
+	"id", "email", "firstName", "lastName", "position", "role", "status",
+	"mobilePhone", "dateOfBirth", "managerID", "departmentID", "location", "timeZone", "hireDate",
+}
+
+// ExportValue returns value of the user field exported in the column, ok is false for unknown columns
+func (u User) ExportValue(column string) (value string, ok bool) {
+	switch column {
+	case "id":
+		return u.ID.String(), true
+	case "email":
+		return u.Email, true
+	case "firstName":
+		return u.FirstName, true
+	case "lastName":
+		return u.LastName, true
+	case "position":
+		return u.Position, true
+	case "role":
+		return string(u.Role), true
+	case "status":
+		return string(u.Status), true
+	case "mobilePhone":
+		return u.MobilePhone, true
+	case "dateOfBirth":
+		return u.DateOfBirth, true
+	case "managerID":
+		return u.ManagerID, true
+	case "departmentID":
+		return u.DepartmentID, true
+	case "location":
+		return u.Location, true
+	case "timeZone":
+		return u.TimeZone, true
+	case "hireDate":
+		return u.HireDate, true
+	}
+	return "", false
+}
@@ -69,3 +69,55 @@ func IsErrInvalidData(err error) bool {
 
 	return ok
 }
+
+// ErrForbidden is error type that denotes that user has no rights to perform an action
+type ErrForbidden struct {
+	msg string
+}
+
+// Error so that ErrForbidden implements error interface
+func (e *ErrForbidden) Error() string {
+	return e.msg
+}
+
+// NewErrForbidden is constructor for ErrForbidden
+func NewErrForbidden(format string, a ...interface{}) *ErrForbidden {
+	return &ErrForbidden{
+		msg: fmt.Sprintf(format, a...),
+	}
+}
+
+// IsErrForbidden returns true if error is ErrForbidden
+func IsErrForbidden(err error) bool {
+	_, ok := err.(*ErrForbidden)
+
+	return ok
+}
+
+// ErrConflict is error type that denotes that value was changed concurrently since it was read
+type ErrConflict struct {
+	msg string
+}
+
+// Error so that ErrConflict implements error interface
+func (e *ErrConflict) Error() string {
+	return e.msg
+}
+
+// NewErrConflict is constructor for ErrConflict
+func NewErrConflict(format string, a ...interface{}) *ErrConflict {
+	return &ErrConflict{
+		msg: fmt.Sprintf(format, a...),
+	}
+}
+
+// IsErrConflict returns true if error is ErrConflict
+func IsErrConflict(err error) bool {
+	if elastic.IsConflict(err) {
+		return true
+	}
+
+	_, ok := err.(*ErrConflict)
+
+	return ok
+}
@@ -0,0 +1,49 @@
+package models
+
+import "github.com/google/uuid"
+
+// EmploymentStatus is a stage of employee lifecycle, users created before lifecycle was introduced have empty status and are active
+type EmploymentStatus string
+
+const (
+	// Invited user is signed up but has not set a password yet
+	Invited    EmploymentStatus = "invited"
+	Active     EmploymentStatus = "active"
+	OnLeave    EmploymentStatus = "on-leave"
+	Terminated EmploymentStatus = "terminated"
+)
+
+func (u User) IsTerminated() bool {
+	return u.Status == Terminated
+}
+
+type EmploymentStatusUpdate struct {
+	Status EmploymentStatus `json:"status"`
+}
+
+// TerminationReq describes termination, open tasks are reassigned to ReassignTo or to manager of the user if it is empty
+type TerminationReq struct {
+	ReassignTo string `json:"reassignTo,omitempty"`
+}
+
+type TerminationResult struct {
+	User              User        `json:"user"`
+	ReassignedTo      string      `json:"reassignedTo,omitempty"`
+	ReassignedTasks   []uuid.UUID `json:"reassignedTasks"`
+	CanceledVacations []uuid.UUID `json:"canceledVacations"`
+}
+
+// OnboardingTask is a template of task created for a new user, {user} in title and description is replaced with user full name
+type OnboardingTask struct {
+	Title       string `json:"Title"`
+	Description string `json:"Description"`
+	// Assignee is "user" for the new user, "manager" for manager of the new user, otherwise it is an id of assigned user
+	Assignee string `json:"Assignee"`
+	// DueInDays sets due date relative to onboarding day, task has no due date if it is zero
+	DueInDays int `json:"DueInDays"`
+}
+
+const (
+	OnboardingUserAssignee    = "user"
+	OnboardingManagerAssignee = "manager"
+)
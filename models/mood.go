@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mood scale of check-ins from awful to great
+const (
+	MinMoodScore = 1
+	MaxMoodScore = 5
+)
+
+// MoodCheckIn is a mood of the user at the moment, check-ins are visible only to the user
+type MoodCheckIn struct {
+	ID        uuid.UUID `json:"id" gorm:"primary_key"`
+	UserID    string    `json:"userID" gorm:"index"`
+	Score     int       `json:"score"`
+	Note      string    `json:"note,omitempty" gorm:"type:text"`
+	CreatedAt time.Time `json:"createdAt" gorm:"index"`
+}
+
+type MoodCheckInReq struct {
+	Score int    `json:"score"`
+	Note  string `json:"note"`
+}
+
+// PulseFilter selects team of the pulse, reports of the manager down the hierarchy or members of the department
+type PulseFilter struct {
+	ManagerID    string
+	DepartmentID string
+	Weeks        int
+}
+
+// TeamPulse is an anonymized mood of the team, individual check-ins are never exposed
+type TeamPulse struct {
+	TeamSize int `json:"teamSize"`
+	// MinGroupSize is a number of respondents required to show average of the week
+	MinGroupSize int         `json:"minGroupSize"`
+	Weeks        []PulseWeek `json:"weeks"`
+	// Trend is a change of average between the last two weeks which are not hidden
+	Trend *float64 `json:"trend,omitempty"`
+}
+
+// PulseWeek aggregates check-ins of the week, every respondent is counted once with average of own check-ins
+type PulseWeek struct {
+	// Start is Monday of the week
+	Start        string  `json:"start"`
+	Respondents  int     `json:"respondents"`
+	ResponseRate float64 `json:"responseRate"`
+	// Average is omitted if the week is hidden
+	Average *float64 `json:"average,omitempty"`
+	// Hidden is set if the week has fewer respondents than minimum group size or its respondents differ
+	// from respondents of another team by fewer than minimum group size, so averages cannot be subtracted
+	Hidden bool `json:"hidden"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type NotificationChannel string
+
+const (
+	EmailChannel   NotificationChannel = "email"
+	WebhookChannel NotificationChannel = "webhook"
+	SlackChannel   NotificationChannel = "slack"
+)
+
+type DeliveryStatus string
+
+const (
+	// DeliveryPending marks delivery which is queued or waits for the next attempt
+	DeliveryPending DeliveryStatus = "Pending"
+	Delivered       DeliveryStatus = "Delivered"
+	Failed          DeliveryStatus = "Failed"
+)
+
+// NotificationPreference defines which recent changes are delivered to the user through the channel.
+// Target is an email address for email channel (user email is used if it is empty) or URL for webhooks.
+type NotificationPreference struct {
+	ID         uuid.UUID           `json:"id" gorm:"primary_key"`
+	UserID     string              `json:"userID"`
+	Channel    NotificationChannel `json:"channel"`
+	Target     string              `json:"target,omitempty"`
+	EventTypes pq.StringArray      `json:"eventTypes" gorm:"type:text[]"`
+}
+
+// Accepts returns true if changes of the type should be delivered
+func (p NotificationPreference) Accepts(changeType ChangesType) bool {
+	for _, t := range p.EventTypes {
+		if ChangesType(t) == changeType {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationDelivery is a queued delivery of recent change and a record of delivery log
+type NotificationDelivery struct {
+	ID         uuid.UUID           `json:"id" gorm:"primary_key"`
+	ChangeID   uuid.UUID           `json:"changeID" gorm:"unique_index:idx_notification_delivery_change"`
+	ChangeType ChangesType         `json:"changeType"`
+	UserID     string              `json:"userID" gorm:"unique_index:idx_notification_delivery_change"`
+	Channel    NotificationChannel `json:"channel" gorm:"unique_index:idx_notification_delivery_change"`
+	// Target is empty for email delivery to user email until it is sent
+	Target        string         `json:"target" gorm:"unique_index:idx_notification_delivery_change"`
+	Payload       string         `json:"-" gorm:"type:text"`
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"lastError,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	NextAttemptAt time.Time      `json:"nextAttemptAt"`
+	DeliveredAt   *time.Time     `json:"deliveredAt,omitempty"`
+}
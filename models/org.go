@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Department is a unit of organization, teams are departments nested into another department
+type Department struct {
+	ID        uuid.UUID `json:"id" gorm:"primary_key"`
+	Name      string    `json:"name"`
+	ParentID  string    `json:"parentID,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type DepartmentReq struct {
+	Name     string `json:"name"`
+	ParentID string `json:"parentID"`
+}
+
+// OrgNode is a user with own reports in org chart
+type OrgNode struct {
+	User    User       `json:"user"`
+	Reports []*OrgNode `json:"reports"`
+}
+
+// IsTeammate returns true if users are from the same department, have the same manager or one manages another
+func (u User) IsTeammate(o User) bool {
+	return (u.DepartmentID != "" && u.DepartmentID == o.DepartmentID) ||
+		(u.ManagerID != "" && u.ManagerID == o.ManagerID) ||
+		u.ManagerID == o.ID.String() ||
+		o.ManagerID == u.ID.String()
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EventTopic string
+
+const (
+	RecentChangeTopic EventTopic = "recent_change"
+	AuditTopic        EventTopic = "audit"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the domain change and published by relay afterwards.
+// Events are delivered at least once, ID is an idempotency key so subscribers can ignore duplicates.
+type OutboxEvent struct {
+	ID            uuid.UUID  `json:"id" gorm:"primary_key"`
+	Seq           int64      `json:"seq" gorm:"AUTO_INCREMENT;unique_index"`
+	Topic         EventTopic `json:"topic"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	PublishedAt   *time.Time `json:"publishedAt,omitempty"`
+	FailedAt      *time.Time `json:"failedAt,omitempty"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"nextAttemptAt"`
+	LastError     string     `json:"lastError,omitempty"`
+}
@@ -0,0 +1,68 @@
+package models
+
+// Permission is a right to perform an action, it is granted to roles within a scope
+type Permission string
+
+const (
+	ViewSensitiveProfile Permission = "profile.sensitive.view"
+	ManageUsers          Permission = "users.manage"
+	ManageDepartments    Permission = "departments.manage"
+	ApproveVacations     Permission = "vacations.approve"
+	ManageVacations      Permission = "vacations.manage"
+	ManageNotifications  Permission = "notifications.manage"
+	ManageWebhooks       Permission = "webhooks.manage"
+	ViewAudit            Permission = "audit.view"
+	ManagePrivacy        Permission = "privacy.manage"
+	RunJobs              Permission = "jobs.run"
+	ViewTeamPulse        Permission = "pulse.view"
+	ManageTasks          Permission = "tasks.manage"
+	ViewActivity         Permission = "activity.view"
+)
+
+// Scope defines users whom permission applies to, wider scope includes narrower ones
+type Scope int
+
+const (
+	NoScope Scope = iota
+	// SelfScope applies permission to the user only
+	SelfScope
+	// SubtreeScope applies permission to the user and everyone who reports to the user directly or indirectly
+	SubtreeScope
+	// AllScope applies permission to everyone
+	AllScope
+)
+
+// rolePermissions grants permissions to roles, admin has all permissions in AllScope
+var rolePermissions = map[Role]map[Permission]Scope{
+	UserRole: {
+		ViewSensitiveProfile: SelfScope,
+		ManageVacations:      SelfScope,
+		ManageTasks:          SelfScope,
+		ViewActivity:         SelfScope,
+	},
+	ManagerRole: {
+		ViewSensitiveProfile: SubtreeScope,
+		ApproveVacations:     SubtreeScope,
+		ManageVacations:      SubtreeScope,
+		ViewTeamPulse:        SubtreeScope,
+		ManageTasks:          SubtreeScope,
+		ViewActivity:         SubtreeScope,
+	},
+	HRRole: {
+		ViewSensitiveProfile: AllScope,
+		ApproveVacations:     AllScope,
+		ManageVacations:      AllScope,
+		ManageDepartments:    AllScope,
+		ViewTeamPulse:        AllScope,
+		ManageTasks:          SelfScope,
+		ViewActivity:         AllScope,
+	},
+}
+
+// Scope returns scope in which permission is granted to the role
+func (r Role) Scope(p Permission) Scope {
+	if r == AdminRole {
+		return AllScope
+	}
+	return rolePermissions[r][p]
+}
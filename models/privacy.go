@@ -0,0 +1,109 @@
+package models
+
+import "time"
+
+// Visibility defines audience of a profile field, each next level is narrower than previous one
+type Visibility string
+
+const (
+	// PublicVisibility shows field to every authenticated user
+	PublicVisibility Visibility = "public"
+	// TeamVisibility shows field to users from the same department, users with the same manager and direct manager or reports
+	TeamVisibility Visibility = "team"
+	// ManagerVisibility shows field to managers up the hierarchy
+	ManagerVisibility Visibility = "manager"
+	// HRVisibility shows field to users who can see sensitive profile fields of everyone
+	HRVisibility Visibility = "hr"
+	// SelfVisibility shows field only to the user
+	SelfVisibility Visibility = "self"
+)
+
+var visibilityRank = map[Visibility]int{
+	PublicVisibility:  0,
+	TeamVisibility:    1,
+	ManagerVisibility: 2,
+	HRVisibility:      3,
+	SelfVisibility:    4,
+}
+
+func (v Visibility) IsValid() bool {
+	_, ok := visibilityRank[v]
+	return ok
+}
+
+// IsNarrowerThan returns true if field with visibility v is shown to less users than with o
+func (v Visibility) IsNarrowerThan(o Visibility) bool {
+	return visibilityRank[v] > visibilityRank[o]
+}
+
+// Includes returns true if audience v is allowed to see field with visibility o
+func (v Visibility) Includes(o Visibility) bool {
+	return !o.IsNarrowerThan(v)
+}
+
+// ProfileField is a user profile field which visibility can be configured
+type ProfileField string
+
+const (
+	PhoneField    ProfileField = "mobilePhone"
+	BirthdayField ProfileField = "dateOfBirth"
+	HireDateField ProfileField = "hireDate"
+)
+
+// PrivateFields are profile fields with configurable visibility and their default visibility
+var PrivateFields = map[ProfileField]Visibility{
+	PhoneField:    ManagerVisibility,
+	BirthdayField: ManagerVisibility,
+	HireDateField: PublicVisibility,
+}
+
+// ProfilePrivacy keeps visibility chosen by the user, default visibility is used for missing fields
+type ProfilePrivacy map[ProfileField]Visibility
+
+// PrivacyPolicy is a minimum visibility enforced by admins, users cannot make the field more visible
+type PrivacyPolicy struct {
+	Field     ProfileField `json:"field" gorm:"primary_key"`
+	Minimum   Visibility   `json:"minimum"`
+	UpdatedBy string       `json:"updatedBy"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+type PrivacyPolicyReq struct {
+	Minimum Visibility `json:"minimum"`
+}
+
+// FieldPrivacy describes visibility of the field for the user
+type FieldPrivacy struct {
+	Field ProfileField `json:"field"`
+	// Visibility is chosen by the user or default one
+	Visibility Visibility `json:"visibility"`
+	// Minimum is enforced by admins
+	Minimum Visibility `json:"minimum"`
+	// Effective is the narrowest of Visibility and Minimum
+	Effective Visibility `json:"effective"`
+}
+
+// Hide clears value of the field
+func (u *User) Hide(field ProfileField) {
+	switch field {
+	case PhoneField:
+		u.MobilePhone = ""
+	case BirthdayField:
+		u.DateOfBirth = ""
+	case HireDateField:
+		u.HireDate = ""
+	}
+}
+
+// HasValue returns true if the field is filled in
+func (u User) HasValue(field ProfileField) bool {
+	switch field {
+	case PhoneField:
+		return u.MobilePhone != ""
+	case BirthdayField:
+		return u.DateOfBirth != ""
+	case HireDateField:
+		return u.HireDate != ""
+	}
+	return false
+}
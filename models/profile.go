@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type Proficiency string
+
+const (
+	BeginnerProficiency     Proficiency = "beginner"
+	IntermediateProficiency Proficiency = "intermediate"
+	AdvancedProficiency     Proficiency = "advanced"
+	ExpertProficiency       Proficiency = "expert"
+)
+
+type LanguageLevel string
+
+const (
+	BasicLevel          LanguageLevel = "basic"
+	ConversationalLevel LanguageLevel = "conversational"
+	FluentLevel         LanguageLevel = "fluent"
+	NativeLevel         LanguageLevel = "native"
+)
+
+// Profile contains sections of user profile which are edited by the user and visible to everyone.
+// Fields are not omitted when empty, so partial update of user document clears removed sections.
+type Profile struct {
+	Skills    []Skill    `json:"skills"`
+	Languages []Language `json:"languages"`
+	// Location is an office the user works at
+	Location string `json:"location"`
+	// TimeZone is an IANA time zone name, e.g. Europe/Kiev
+	TimeZone string `json:"timeZone"`
+	Bio      string `json:"bio"`
+	Links    []Link `json:"links"`
+}
+
+type Skill struct {
+	Name        string      `json:"name"`
+	Proficiency Proficiency `json:"proficiency"`
+}
+
+type Language struct {
+	Name  string        `json:"name"`
+	Level LanguageLevel `json:"level"`
+}
+
+type Link struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// Validate checks values which cannot be checked by schema: time zone and duplicated skills or languages
+func (p Profile) Validate() error {
+	if p.TimeZone != "" {
+		if _, err := time.LoadLocation(p.TimeZone); err != nil {
+			return NewErrInvalidData("unknown time zone %s", p.TimeZone)
+		}
+	}
+
+	skills := make(map[string]bool, len(p.Skills))
+	for _, skill := range p.Skills {
+		name := strings.ToLower(skill.Name)
+		if skills[name] {
+			return NewErrInvalidData("skill %s is duplicated", skill.Name)
+		}
+		skills[name] = true
+	}
+
+	languages := make(map[string]bool, len(p.Languages))
+	for _, language := range p.Languages {
+		name := strings.ToLower(language.Name)
+		if languages[name] {
+			return NewErrInvalidData("language %s is duplicated", language.Name)
+		}
+		languages[name] = true
+	}
+	return nil
+}
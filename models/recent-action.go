@@ -14,6 +14,14 @@ const (
 	TaskDeletion         ChangesType = "TaskDeletion"
 	VacationStatusChange ChangesType = "VacationStatusChange"
 	VacationRequest      ChangesType = "VacationRequest"
+	VacationApprovalStep ChangesType = "VacationApprovalStep"
+	VacationCommentAdded ChangesType = "VacationCommentAdded"
+	VacationShortened    ChangesType = "VacationShortened"
+	Delegation           ChangesType = "Delegation"
+	Mention              ChangesType = "Mention"
+	Birthday             ChangesType = "Birthday"
+	WorkAnniversary      ChangesType = "WorkAnniversary"
+	ProfileChangeReview  ChangesType = "ProfileChangeReview"
 )
 
 // Assignment task, status task, vacation-approve
@@ -29,4 +37,19 @@ type RecentChanges struct {
 	UpdatedByID   string      `json:"updatedByID"`
 	ChangeTime    time.Time   `json:"changeTime"`
 	Status        string      `json:"status"`
+	Comment       string      `json:"comment,omitempty"`
+	Read          bool        `json:"read" gorm:"-"`
+}
+
+// RecentChangeMark keeps read and dismissal state of recent change for particular user,
+// change without mark is unread
+type RecentChangeMark struct {
+	ChangeID    uuid.UUID `gorm:"primary_key"`
+	UserID      string    `gorm:"primary_key"`
+	ReadAt      *time.Time
+	DismissedAt *time.Time
+}
+
+type UnreadCount struct {
+	Count int `json:"count"`
 }
@@ -27,26 +27,48 @@ type Task struct {
 	CreatedAt   time.Time `json:"createdAt"`
 	Status      statuses  `json:"status"`
 	IsDeleted   bool      `json:"isDeleted"`
+	// DueDate is optional deadline, tasks which are not Done after it are overdue
+	DueDate *time.Time `json:"dueDate,omitempty"`
+	// Mentions are users mentioned in title or description
+	Mentions []TaskMention `json:"mentions,omitempty"`
+	// CoveredBy is a delegate of assigned user who is on vacation
+	CoveredBy *User `json:"coveredBy,omitempty"`
+	// Warning describes adjustments made while saving the task
+	Warning string `json:"warning,omitempty"`
 }
 
 type TaskElastic struct {
-	ID          uuid.UUID `json:"id"`
-	Number      uint64    `json:"number"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	AssignedID  string    `json:"assignedID"`
-	CreatedByID string    `json:"createdByID"`
-	UpdatedByID string    `json:"updatedByID"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	CreatedAt   time.Time `json:"createdAt"`
-	Status      statuses  `json:"status"`
-	IsDeleted   bool      `json:"isDeleted"`
+	ID          uuid.UUID     `json:"id"`
+	Number      uint64        `json:"number"`
+	Title       string        `json:"title"`
+	Description string        `json:"description"`
+	AssignedID  string        `json:"assignedID"`
+	CreatedByID string        `json:"createdByID"`
+	UpdatedByID string        `json:"updatedByID"`
+	UpdatedAt   time.Time     `json:"updatedAt"`
+	CreatedAt   time.Time     `json:"createdAt"`
+	Status      statuses      `json:"status"`
+	IsDeleted   bool          `json:"isDeleted"`
+	DueDate     *time.Time    `json:"dueDate,omitempty"`
+	Mentions    []TaskMention `json:"mentions,omitempty"`
+}
+
+// TaskMention is a resolved @firstName.lastName or @<uuid> mention
+type TaskMention struct {
+	Token    string `json:"token"`
+	UserID   string `json:"userID"`
+	UserName string `json:"userName"`
 }
 
 func (t TaskElastic) IsAssigned() bool {
 	return t.AssignedID != ""
 }
 
+// IsOverdue returns true if task is not done after its due date
+func (t TaskElastic) IsOverdue(now time.Time) bool {
+	return t.DueDate != nil && t.Status != Done && t.DueDate.Before(now)
+}
+
 type TaskSearch struct {
 	Search string `json:"search"`
 }
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
@@ -14,8 +16,11 @@ const (
 	RoleAttribute  = "custom:role"
 	EmailAttribute = "email"
 
-	AdminRole Role = "admin"
-	UserRole  Role = "user"
+	AdminRole   Role = "admin"
+	HRRole      Role = "hr"
+	ManagerRole Role = "manager"
+	// UserRole is a regular employee
+	UserRole Role = "user"
 
 	AccessToken  = "access_token"
 	RefreshToken = "refresh_token"
@@ -34,18 +39,74 @@ type User struct {
 	Position    string    `json:"position"`
 	MobilePhone string    `json:"mobilePhone,omitempty"`
 	DateOfBirth string    `json:"dateOfBirth,omitempty"`
-	ImageURL    string    `json:"imageURL,omitempty"`
-	Role        Role      `json:"role"`
-	Mood        string    `json:"mood"`
+	// HireDate is the first working day, work anniversaries are counted from it
+	HireDate string `json:"hireDate,omitempty"`
+	ImageURL string `json:"imageURL,omitempty"`
+	Role     Role   `json:"role"`
+	// Mood is deprecated and read-only, it keeps mood set before check-ins were introduced until clients move to check-ins
+	Mood string `json:"mood"`
+	// ManagerID is a user whom the user reports to, top managers have no manager
+	ManagerID    string `json:"managerID,omitempty"`
+	DepartmentID string `json:"departmentID,omitempty"`
+	// Privacy is shown only to the user
+	Privacy ProfilePrivacy   `json:"privacy,omitempty"`
+	Status  EmploymentStatus `json:"status,omitempty"`
+	// OnboardedAt is set when onboarding tasks are created
+	OnboardedAt  *time.Time `json:"onboardedAt,omitempty"`
+	TerminatedAt *time.Time `json:"terminatedAt,omitempty"`
+	// Version is a revision of stored user it was read at, write of the user fails if it was changed since
+	Version *DocVersion `json:"-"`
+	Profile
 	Credentials
 }
 
+// DocVersion identifies revision of elasticsearch document
+type DocVersion struct {
+	SeqNo       int64
+	PrimaryTerm int64
+}
+
 type UserUpdate struct {
 	ID          string `json:"id"`
 	MobilePhone string `json:"mobilePhone,omitempty"`
 	DateOfBirth string `json:"dateOfBirth,omitempty"`
 	ImageURL    string `json:"imageURL,omitempty"`
-	Mood        string `json:"mood,omitempty"`
+	Profile
+}
+
+// UserPatch changes fields of the user which are present, fields which are not present are kept
+type UserPatch struct {
+	MobilePhone  *string `json:"mobilePhone"`
+	DateOfBirth  *string `json:"dateOfBirth"`
+	HireDate     *string `json:"hireDate"`
+	FirstName    *string `json:"firstName"`
+	LastName     *string `json:"lastName"`
+	Position     *string `json:"position"`
+	Role         *Role   `json:"role"`
+	ManagerID    *string `json:"managerID"`
+	DepartmentID *string `json:"departmentID"`
+}
+
+// Apply returns the user with the patch applied
+func (p UserPatch) Apply(u User) User {
+	setString(&u.MobilePhone, p.MobilePhone)
+	setString(&u.DateOfBirth, p.DateOfBirth)
+	setString(&u.HireDate, p.HireDate)
+	setString(&u.FirstName, p.FirstName)
+	setString(&u.LastName, p.LastName)
+	setString(&u.Position, p.Position)
+	setString(&u.ManagerID, p.ManagerID)
+	setString(&u.DepartmentID, p.DepartmentID)
+	if p.Role != nil {
+		u.Role = *p.Role
+	}
+	return u
+}
+
+func setString(field *string, value *string) {
+	if value != nil {
+		*field = *value
+	}
 }
 
 type UserAccess struct {
@@ -55,15 +116,40 @@ type UserAccess struct {
 }
 
 type UserSearch struct {
+	// Query matches words of name, email, position and skills by prefix or with typos
+	Query string `json:"query"`
+	// ByName matches words of first and last name by prefix or with typos
 	ByName     string `json:"name"`
 	ByPosition string `json:"position"`
+	// BySkills and ByLanguages match users who have all of them
+	BySkills     []string `json:"skills"`
+	ByLanguages  []string `json:"languages"`
+	ByLocation   string   `json:"location"`
+	ByDepartment string   `json:"departmentID"`
+	Limit        int      `json:"limit"`
+	Offset       int      `json:"offset"`
+}
+
+// UserSearchResult contains found users and facets counted over all matching users
+type UserSearchResult struct {
+	Total int64     `json:"total"`
+	Users []UserHit `json:"users"`
+	// Facets are keyed by position, departmentID and location
+	Facets map[string][]FacetBucket `json:"facets"`
+}
+
+type UserHit struct {
+	User
+	// Highlights contain matched fragments keyed by field, matched words are wrapped in <em> tags
+	Highlights map[string][]string `json:"highlights,omitempty"`
+}
+
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
 }
 
 type Credentials struct {
 	Email    string `json:"email"`
 	Password string `json:"password,omitempty"`
 }
-
-func (r Role) IsAdmin() bool {
-	return r == AdminRole
-}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestUserPatchApply(t *testing.T) {
+	user := User{
+		FirstName:    "Ann",
+		LastName:     "Lee",
+		Position:     "Developer",
+		MobilePhone:  "+380501234567",
+		DateOfBirth:  "1990-01-02",
+		HireDate:     "2019-03-01",
+		Role:         UserRole,
+		ManagerID:    "manager",
+		DepartmentID: "department",
+		Profile:      Profile{Bio: "bio"},
+	}
+
+	var patch UserPatch
+	err := json.Unmarshal([]byte(`{"position":"Lead","role":"manager","mobilePhone":"","managerID":"","departmentID":null}`), &patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := patch.Apply(user)
+
+	expected := user
+	expected.Position = "Lead"
+	expected.Role = ManagerRole
+	expected.MobilePhone = ""
+	expected.ManagerID = ""
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	if user.Position != "Developer" || user.ManagerID != "manager" {
+		t.Fatalf("original user is modified: %+v", user)
+	}
+
+	if got = (UserPatch{}).Apply(user); !reflect.DeepEqual(got, user) {
+		t.Fatalf("empty patch changed user: %+v", got)
+	}
+}
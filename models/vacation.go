@@ -14,11 +14,14 @@ const (
 	Rejected = "Rejected"
 	Canceled = "Canceled"
 	Expired  = "Expired"
+	// CancelRequested is a status of Approved vacation which cancellation waits for approver sign-off
+	CancelRequested = "CancelRequested"
 )
 
 type VacationReq struct {
-	StartDate string
-	EndDate   string
+	StartDate  string
+	EndDate    string
+	DelegateID string
 }
 
 type Vacation struct {
@@ -31,6 +34,12 @@ type Vacation struct {
 	UpdateTime    time.Time      `json:"updateTime"`
 	StatusChanger *User          `json:"statusChanger,omitempty"`
 	WasApproved   bool           `json:"wasApproved"`
+	StatusComment string         `json:"statusComment,omitempty"`
+	Taken         bool           `json:"taken"`
+	// Delegate covers tasks of the user while vacation is active
+	DelegateID       string             `json:"delegateID,omitempty"`
+	DelegateFullName string             `json:"delegateFullName,omitempty"`
+	Approvals        []VacationApproval `json:"approvals,omitempty"`
 }
 
 type VacationDB struct {
@@ -45,8 +54,107 @@ type VacationDB struct {
 	StatusChangerFullName string         `json:"statusChangerFullName"`
 	StatusChangerID       string         `json:"statusChangerID"`
 	WasApproved           bool           `json:"wasApproved"`
+	StatusComment         string         `json:"statusComment"`
+	// Taken is set for Approved vacation when it is finished
+	Taken            bool   `json:"taken"`
+	DelegateID       string `json:"delegateID"`
+	DelegateFullName string `json:"delegateFullName"`
+}
+
+type VacationEndDateUpdate struct {
+	EndDate string `json:"endDate"`
 }
 
 type VacationStatusUpdate struct {
-	Status VacationStatus `json:"status"`
+	Status  VacationStatus `json:"status"`
+	Comment string         `json:"comment,omitempty"`
+}
+
+type ApproverType string
+
+const (
+	// AdminApprover step can be approved by any admin
+	AdminApprover ApproverType = "admin"
+	// UserApprover step can be approved only by the user specified in the rule
+	UserApprover ApproverType = "user"
+	// ManagerApprover step is assigned to the direct manager of the requester, it can be also approved
+	// by anyone who has ApproveVacations permission on the requester
+	ManagerApprover ApproverType = "manager"
+)
+
+// Skipped is a decision for approval steps which were not reached because of rejection
+const Skipped = "Skipped"
+
+// ApprovalRule describes one step of vacation approval chain
+type ApprovalRule struct {
+	Approver ApproverType `json:"Approver"`
+	UserID   string       `json:"UserID"`
+	// LongerThanDays makes step required only for vacations longer than specified amount of days
+	LongerThanDays int `json:"LongerThanDays"`
+}
+
+// Validate checks that the step can be decided by someone, user step requires id of the approver
+func (r ApprovalRule) Validate() error {
+	switch r.Approver {
+	case AdminApprover, ManagerApprover:
+		return nil
+	case UserApprover:
+		if _, err := uuid.Parse(r.UserID); err != nil {
+			return NewErrInvalidData("user approver step requires UserID, got %q", r.UserID)
+		}
+		return nil
+	}
+	return NewErrInvalidData("unknown approver %q", r.Approver)
+}
+
+// VacationApproval is a single step of vacation approval chain
+type VacationApproval struct {
+	ID               uuid.UUID      `json:"id" gorm:"primary_key"`
+	VacationID       uuid.UUID      `json:"vacationID"`
+	Step             int            `json:"step"`
+	ApproverType     ApproverType   `json:"approverType"`
+	ApproverID       string         `json:"approverID,omitempty"`
+	ApproverFullName string         `json:"approverFullName,omitempty"`
+	Decision         VacationStatus `json:"decision"`
+	Comment          string         `json:"comment,omitempty"`
+	DecisionTime     *time.Time     `json:"decisionTime,omitempty"`
+}
+
+// IsPending returns true if decision on the step is not made yet
+func (a VacationApproval) IsPending() bool {
+	return a.Decision == Pending
+}
+
+// CanBeDecidedBy returns true if user has rights to make a decision on the step
+func (a VacationApproval) CanBeDecidedBy(ua UserAccess) bool {
+	switch a.ApproverType {
+	case AdminApprover:
+		return ua.Role.Scope(ApproveVacations) == AllScope
+	case UserApprover, ManagerApprover:
+		return a.ApproverID == ua.UserID
+	}
+	return false
+}
+
+// VacationComment is a message in discussion thread of vacation request
+type VacationComment struct {
+	ID             uuid.UUID `json:"id" gorm:"primary_key"`
+	VacationID     uuid.UUID `json:"vacationID"`
+	AuthorID       string    `json:"authorID"`
+	AuthorFullName string    `json:"authorFullName"`
+	Text           string    `json:"text"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type VacationCommentReq struct {
+	Text string `json:"text"`
+}
+
+// VacationBalance keeps amount of vacation days used by the user in a year, vacation is counted in the year it starts
+type VacationBalance struct {
+	UserID   string `json:"userID" gorm:"primary_key"`
+	Year     int    `json:"year" gorm:"primary_key;auto_increment:false"`
+	UsedDays int    `json:"usedDays"`
+	// LeftDays is calculated from yearly allowance, it is not stored
+	LeftDays int `json:"leftDays" gorm:"-"`
 }
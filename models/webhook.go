@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "Pending"
+	WebhookDelivered WebhookDeliveryStatus = "Delivered"
+	// WebhookDead marks delivery which ran out of attempts, it stays in dead-letter list until it is replayed
+	WebhookDead WebhookDeliveryStatus = "Dead"
+)
+
+// WebhookSubscription is an integrator endpoint which receives recent changes of listed event types
+type WebhookSubscription struct {
+	ID          uuid.UUID      `json:"id" gorm:"primary_key"`
+	URL         string         `json:"url"`
+	Secret      string         `json:"-"`
+	EventTypes  pq.StringArray `json:"eventTypes" gorm:"type:text[]"`
+	Active      bool           `json:"active"`
+	CreatedByID string         `json:"createdByID"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// Accepts returns true if changes of the type should be delivered
+func (s WebhookSubscription) Accepts(changeType ChangesType) bool {
+	for _, t := range s.EventTypes {
+		if ChangesType(t) == changeType {
+			return true
+		}
+	}
+	return false
+}
+
+// CreatedWebhookSubscription is a response of subscription creation, it is the only response which contains the secret
+type CreatedWebhookSubscription struct {
+	WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// WebhookSubscriptionReq is a body of subscription create and update requests, secret is generated if it is empty
+type WebhookSubscriptionReq struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`
+	EventTypes []string `json:"eventTypes"`
+	Active     *bool    `json:"active"`
+}
+
+// WebhookDelivery is a signed POST of recent change to subscription URL, EventID is an idempotency key for receivers
+type WebhookDelivery struct {
+	ID             uuid.UUID             `json:"id" gorm:"primary_key"`
+	SubscriptionID uuid.UUID             `json:"subscriptionID" gorm:"unique_index:idx_webhook_delivery_event"`
+	EventID        uuid.UUID             `json:"eventID" gorm:"unique_index:idx_webhook_delivery_event"`
+	EventType      ChangesType           `json:"eventType"`
+	Payload        string                `json:"payload" gorm:"type:text"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	ResponseCode   int                   `json:"responseCode,omitempty"`
+	LastError      string                `json:"lastError,omitempty"`
+	CreatedAt      time.Time             `json:"createdAt"`
+	NextAttemptAt  time.Time             `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time            `json:"deliveredAt,omitempty"`
+}
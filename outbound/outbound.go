@@ -0,0 +1,124 @@
+// Package outbound guards requests which service sends to URLs provided by users,
+// so they cannot be used to reach the service network or cloud metadata endpoints.
+package outbound
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+// blockedNetworks are loopback, private, link-local, shared, multicast and reserved ranges
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+// ErrBlockedAddress is returned when URL points to address which is not allowed
+var ErrBlockedAddress = errors.New("address is not allowed")
+
+// StatusError is returned when target responds with non-success status code
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d", e.Code)
+}
+
+// ValidateURL checks that URL is absolute https URL which host is not a blocked address
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return models.NewErrInvalidData("invalid URL: %s", err)
+	}
+
+	if u.Scheme != "https" {
+		return models.NewErrInvalidData("URL scheme should be https, got %q", u.Scheme)
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return models.NewErrInvalidData("URL host %q is not allowed", host)
+	}
+
+	if ip := net.ParseIP(host); ip != nil && IsBlocked(ip) {
+		return models.NewErrInvalidData("URL host %q is not allowed", host)
+	}
+	return nil
+}
+
+// IsBlocked returns true if requests to the IP are not allowed
+func IsBlocked(ip net.IP) bool {
+	for _, n := range blockedNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClient returns HTTP client which refuses to connect to blocked addresses. Address is checked
+// after name resolution, so host names resolving to internal addresses and redirects to them are refused too.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || IsBlocked(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// proxy from environment would be dialed instead of the target, so it is not used
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			TLSHandshakeTimeout: timeout,
+		},
+	}
+}
+
+// Describe returns error description which can be shown to the user who owns the target,
+// details of connection errors are hidden as they tell about the service network
+func Describe(err error) string {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Error()
+	}
+	if errors.Is(err, ErrBlockedAddress) {
+		return ErrBlockedAddress.Error()
+	}
+	return "request failed"
+}
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, n)
+	}
+	return networks
+}
@@ -0,0 +1,67 @@
+package outbound
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{url: "https://hooks.slack.com/services/T000/B000/XXXX", valid: true},
+		{url: "https://example.com:8443/hook", valid: true},
+		{url: "http://example.com/hook"},
+		{url: "file:///etc/passwd"},
+		{url: "gopher://example.com"},
+		{url: "https://localhost/hook"},
+		{url: "https://api.localhost/hook"},
+		{url: "https://127.0.0.1/hook"},
+		{url: "https://10.1.2.3/hook"},
+		{url: "https://169.254.169.254/latest/meta-data"},
+		{url: "https://[::1]/hook"},
+		{url: "https://[::ffff:192.168.0.1]/hook"},
+		{url: "/relative/path"},
+	}
+
+	for _, tt := range tests {
+		err := ValidateURL(tt.url)
+		if tt.valid && err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.url, err)
+		}
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("%s: expected to be rejected", tt.url)
+			} else if _, ok := err.(*models.ErrInvalidData); !ok {
+				t.Errorf("%s: expected ErrInvalidData, got %T", tt.url, err)
+			}
+		}
+	}
+}
+
+func TestClientRefusesBlockedAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("request to loopback address is not expected to be sent")
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(time.Second).Get(srv.URL)
+	if err == nil {
+		t.Fatal("expected request to be refused")
+	}
+
+	if got := Describe(err); got != ErrBlockedAddress.Error() {
+		t.Errorf("expected %q, got %q", ErrBlockedAddress.Error(), got)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	if got := Describe(&StatusError{Code: http.StatusBadGateway}); got != "unexpected status code 502" {
+		t.Errorf("unexpected description of status error: %q", got)
+	}
+}
@@ -0,0 +1,234 @@
+// Package outbox publishes domain events written to postgres outbox table to subscribers.
+// Relay delivers events in order they were written, only one service instance relays events at a time.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	outboxRepo "github.com/Dimitriy14/staff-manager/repository/outbox"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+
+	// handlersSavepoint isolates changes of handlers, so their failure does not abort relay transaction
+	handlersSavepoint = "outbox_handlers"
+)
+
+type Config struct {
+	PollIntervalInSec   int `json:"PollIntervalInSec"`
+	BatchSize           int `json:"BatchSize"`
+	MaxAttempts         int `json:"MaxAttempts"`
+	InitialBackoffInSec int `json:"InitialBackoffInSec"`
+}
+
+// Handler processes event, it should be idempotent as the same event can be delivered more than once
+type Handler func(ctx context.Context, event models.OutboxEvent) error
+
+// NewEvent creates event with payload marshaled to JSON
+func NewEvent(topic models.EventTopic, payload interface{}) (models.OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return models.OutboxEvent{}, errors.Wrapf(err, "marshaling %s event", topic)
+	}
+
+	now := time.Now().UTC()
+	return models.OutboxEvent{
+		ID:            uuid.New(),
+		Topic:         topic,
+		Payload:       string(data),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}, nil
+}
+
+func NewRelay(cfg Config, dbCfg db.Config, tx repository.Transactor, repo repository.OutboxRepository, log logger.Logger) *Relay {
+	if cfg.PollIntervalInSec < 1 {
+		cfg.PollIntervalInSec = 1
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	r := &Relay{
+		cfg:      cfg,
+		tx:       tx,
+		repo:     repo,
+		log:      log,
+		handlers: make(map[models.EventTopic][]Handler),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	r.listener = pq.NewListener(dbCfg.DataSourceName(), minReconnectInterval, maxReconnectInterval, r.logListenerEvent)
+	return r
+}
+
+type Relay struct {
+	cfg      Config
+	tx       repository.Transactor
+	repo     repository.OutboxRepository
+	listener *pq.Listener
+	log      logger.Logger
+	handlers map[models.EventTopic][]Handler
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Subscribe adds handler of the topic events, it should be called before Start
+func (r *Relay) Subscribe(topic models.EventTopic, h Handler) {
+	r.handlers[topic] = append(r.handlers[topic], h)
+}
+
+func (r *Relay) Start() error {
+	err := r.listener.Listen(outboxRepo.Channel)
+	if err != nil {
+		return errors.Wrap(err, "listening to outbox channel")
+	}
+
+	go r.run()
+	return nil
+}
+
+func (r *Relay) Stop() error {
+	close(r.stop)
+	<-r.done
+	return r.listener.Close()
+}
+
+func (r *Relay) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(time.Duration(r.cfg.PollIntervalInSec) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		r.relay(transactionID.NewIDContext(context.Background()))
+
+		select {
+		case <-r.listener.Notify:
+		case <-ticker.C:
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// relay publishes unpublished events until there are no events ready for delivery
+func (r *Relay) relay(ctx context.Context) {
+	txID := transactionID.FromContext(ctx)
+
+	for {
+		var published int
+		err := r.tx.InTransaction(ctx, func(ctx context.Context) (err error) {
+			published, err = r.publishBatch(ctx)
+			return err
+		})
+		if err != nil {
+			r.log.Errorf(txID, "outbox relay failed: err=%s", err)
+			return
+		}
+
+		if published < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+// publishBatch delivers events in order, delivery stops at the first event which is waiting for retry
+// so subscribers never see events out of order. It returns number of processed events.
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	locked, err := r.repo.Lock(ctx)
+	if err != nil || !locked {
+		return 0, err
+	}
+
+	events, err := r.repo.GetUnpublished(ctx, r.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, event := range events {
+		now := time.Now().UTC()
+		if event.NextAttemptAt.After(now) {
+			return i, nil
+		}
+
+		err = r.publish(ctx, event)
+		if err == nil {
+			event.PublishedAt = &now
+			event.LastError = ""
+			if err = r.repo.Update(ctx, event); err != nil {
+				return i, err
+			}
+			continue
+		}
+
+		r.failAttempt(ctx, &event, err)
+		if err = r.repo.Update(ctx, event); err != nil {
+			return i, err
+		}
+
+		if event.FailedAt == nil {
+			return i, nil
+		}
+	}
+	return len(events), nil
+}
+
+// publish runs handlers of the event in a savepoint, so changes of failed handlers are rolled back
+// while attempt of the event is still recorded in relay transaction
+func (r *Relay) publish(ctx context.Context, event models.OutboxEvent) error {
+	return r.tx.InSavepoint(ctx, handlersSavepoint, func(ctx context.Context) error {
+		for _, h := range r.handlers[event.Topic] {
+			if err := h(ctx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// failAttempt schedules next attempt with exponential backoff or gives up when attempts are over
+func (r *Relay) failAttempt(ctx context.Context, event *models.OutboxEvent, err error) {
+	var (
+		txID    = transactionID.FromContext(ctx)
+		now     = time.Now().UTC()
+		backoff = time.Duration(r.cfg.InitialBackoffInSec) * time.Second
+	)
+
+	event.Attempts++
+	event.LastError = err.Error()
+
+	if event.Attempts >= r.cfg.MaxAttempts {
+		event.FailedAt = &now
+		r.log.Errorf(txID, "giving up %s event %s after %d attempts: err=%s", event.Topic, event.ID, event.Attempts, err)
+		return
+	}
+
+	event.NextAttemptAt = now.Add(backoff << uint(event.Attempts-1))
+	r.log.Warnf(txID, "attempt %d of %s event %s failed, next attempt at %s: err=%s",
+		event.Attempts, event.Topic, event.ID, event.NextAttemptAt, err)
+}
+
+func (r *Relay) logListenerEvent(event pq.ListenerEventType, err error) {
+	if err != nil {
+		r.log.Warnf("", "outbox listener event %d: err=%s", event, err)
+	}
+}
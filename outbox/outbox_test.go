@@ -0,0 +1,103 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/google/uuid"
+)
+
+type txStub struct {
+	savepoints  int
+	rolledBack  int
+	transaction int
+}
+
+func (t *txStub) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	t.transaction++
+	return fn(ctx)
+}
+
+func (t *txStub) InSavepoint(ctx context.Context, _ string, fn func(ctx context.Context) error) error {
+	t.savepoints++
+	err := fn(ctx)
+	if err != nil {
+		t.rolledBack++
+	}
+	return err
+}
+
+type outboxRepoStub struct {
+	events  []models.OutboxEvent
+	updated map[uuid.UUID]models.OutboxEvent
+}
+
+func (r *outboxRepoStub) Save(context.Context, ...models.OutboxEvent) error { return nil }
+
+func (r *outboxRepoStub) Lock(context.Context) (bool, error) { return true, nil }
+
+func (r *outboxRepoStub) GetUnpublished(context.Context, int) ([]models.OutboxEvent, error) {
+	return r.events, nil
+}
+
+func (r *outboxRepoStub) Update(_ context.Context, event models.OutboxEvent) error {
+	r.updated[event.ID] = event
+	return nil
+}
+
+func TestPublishBatchRecordsFailedAttempt(t *testing.T) {
+	log, err := logger.Load(logger.Config{LogLevel: "panic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		past   = time.Now().UTC().Add(-time.Minute)
+		failed = models.OutboxEvent{ID: uuid.New(), Topic: models.RecentChangeTopic, NextAttemptAt: past}
+		next   = models.OutboxEvent{ID: uuid.New(), Topic: models.RecentChangeTopic, NextAttemptAt: past}
+		repo   = &outboxRepoStub{events: []models.OutboxEvent{failed, next}, updated: make(map[uuid.UUID]models.OutboxEvent)}
+		tx     = &txStub{}
+		r      = &Relay{
+			cfg:      Config{BatchSize: 10, MaxAttempts: 3, InitialBackoffInSec: 10},
+			tx:       tx,
+			repo:     repo,
+			log:      log,
+			handlers: make(map[models.EventTopic][]Handler),
+		}
+	)
+
+	r.Subscribe(models.RecentChangeTopic, func(_ context.Context, event models.OutboxEvent) error {
+		if event.ID == failed.ID {
+			return errors.New("handler failed")
+		}
+		return nil
+	})
+
+	processed, err := r.publishBatch(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if processed != 0 {
+		t.Errorf("expected relay to stop at event waiting for retry, processed %d", processed)
+	}
+	if tx.rolledBack != 1 {
+		t.Errorf("expected handlers of failed event to be rolled back to savepoint, got %d rollbacks", tx.rolledBack)
+	}
+
+	got, ok := repo.updated[failed.ID]
+	if !ok {
+		t.Fatal("expected attempt of failed event to be recorded")
+	}
+	if got.Attempts != 1 || got.LastError != "handler failed" || !got.NextAttemptAt.After(past) || got.PublishedAt != nil {
+		t.Errorf("unexpected state of failed event: %+v", got)
+	}
+	if _, ok := repo.updated[next.ID]; ok {
+		t.Error("expected next event to wait for failed one")
+	}
+}
@@ -0,0 +1,57 @@
+package approval
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+func NewApprovalRepo(client *db.Client) *approvalRepo {
+	return &approvalRepo{client}
+}
+
+type approvalRepo struct {
+	*db.Client
+}
+
+func (r *approvalRepo) Save(ctx context.Context, approvals ...models.VacationApproval) error {
+	return r.InTransaction(ctx, func(ctx context.Context) error {
+		for _, approval := range approvals {
+			if err := r.Conn(ctx).Create(&approval).Error; err != nil {
+				return errors.Wrapf(err, "saving approval step %d for vacation id = %s", approval.Step, approval.VacationID)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *approvalRepo) Update(ctx context.Context, approval models.VacationApproval) error {
+	errs := r.Conn(ctx).Save(&approval).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "updating approval step error")
+	}
+	return nil
+}
+
+func (r *approvalRepo) GetForVacation(ctx context.Context, vacationID string) ([]models.VacationApproval, error) {
+	approvals := make([]models.VacationApproval, 0)
+	errs := r.Conn(ctx).Where("vacation_id = ?", vacationID).
+		Order("step").
+		Find(&approvals).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting vacation approval steps error")
+	}
+	return approvals, nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// chainLockKey is a key of advisory lock which keeps audit hash chain linear
+const chainLockKey = 7302
+
+func NewAuditRepo(client *db.Client) *auditRepo {
+	return &auditRepo{client}
+}
+
+type auditRepo struct {
+	*db.Client
+}
+
+func (r *auditRepo) LockChain(ctx context.Context) error {
+	err := r.Conn(ctx).Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error
+	if err != nil {
+		return errors.Wrap(err, "acquiring audit chain lock")
+	}
+	return nil
+}
+
+// GetLast returns the last entry of the chain or nil if the chain is empty
+func (r *auditRepo) GetLast(ctx context.Context) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	err := r.Conn(ctx).Order("seq desc").First(&entry).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "getting last audit entry error")
+	}
+	return &entry, nil
+}
+
+func (r *auditRepo) Exists(ctx context.Context, id string) (bool, error) {
+	var count int
+	err := r.Conn(ctx).Model(&models.AuditEntry{}).Where("id = ?", id).Count(&count).Error
+	if err != nil {
+		return false, errors.Wrap(err, "checking audit entry error")
+	}
+	return count > 0, nil
+}
+
+func (r *auditRepo) Create(ctx context.Context, entry models.AuditEntry) error {
+	errs := r.Conn(ctx).Create(&entry).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving audit entry error")
+	}
+	return nil
+}
+
+func (r *auditRepo) Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	entries := make([]models.AuditEntry, 0)
+	query := r.Conn(ctx)
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.From != nil {
+		query = query.Where("time >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("time < ?", *filter.To)
+	}
+	if filter.BeforeSeq > 0 {
+		query = query.Where("seq < ?", filter.BeforeSeq)
+	}
+
+	errs := query.Order("seq desc").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&entries).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "searching audit entries error")
+	}
+	return entries, nil
+}
+
+func (r *auditRepo) GetAfter(ctx context.Context, seq int64, limit int) ([]models.AuditEntry, error) {
+	entries := make([]models.AuditEntry, 0)
+	errs := r.Conn(ctx).Where("seq > ?", seq).
+		Order("seq").
+		Limit(limit).
+		Find(&entries).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting audit entries error")
+	}
+	return entries, nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
@@ -0,0 +1,89 @@
+package changerequest
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+// userLockClass is a class of advisory locks which let one request of the user be submitted at a time
+const userLockClass = 7304
+
+func NewChangeRequestRepo(client *db.Client) *changeRequestRepo {
+	return &changeRequestRepo{client}
+}
+
+type changeRequestRepo struct {
+	*db.Client
+}
+
+func (r *changeRequestRepo) Save(ctx context.Context, req models.ProfileChangeRequest) error {
+	errs := r.Conn(ctx).Save(&req).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving change request error")
+	}
+	return nil
+}
+
+func (r *changeRequestRepo) GetByID(ctx context.Context, id string) (models.ProfileChangeRequest, error) {
+	var req models.ProfileChangeRequest
+	err := r.Conn(ctx).Where("id = ?", id).First(&req).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return req, models.NewErrNotFound("change request with id = %s is not found", id)
+	}
+	if err != nil {
+		return req, errors.Wrap(err, "getting change request error")
+	}
+	return req, nil
+}
+
+// GetForUpdate returns the request and locks it until the end of transaction
+func (r *changeRequestRepo) GetForUpdate(ctx context.Context, id string) (models.ProfileChangeRequest, error) {
+	var req models.ProfileChangeRequest
+	err := r.Conn(ctx).Set("gorm:query_option", "FOR UPDATE").Where("id = ?", id).First(&req).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return req, models.NewErrNotFound("change request with id = %s is not found", id)
+	}
+	if err != nil {
+		return req, errors.Wrap(err, "locking change request error")
+	}
+	return req, nil
+}
+
+// LockUser holds lock on requests of the user until the end of transaction
+func (r *changeRequestRepo) LockUser(ctx context.Context, userID string) error {
+	err := r.Conn(ctx).Exec("SELECT pg_advisory_xact_lock(?, hashtext(?))", userLockClass, userID).Error
+	if err != nil {
+		return errors.Wrap(err, "acquiring change requests lock error")
+	}
+	return nil
+}
+
+func (r *changeRequestRepo) Search(ctx context.Context, filter models.ChangeRequestFilter) ([]models.ProfileChangeRequest, error) {
+	requests := make([]models.ProfileChangeRequest, 0)
+	query := r.Conn(ctx)
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	errs := query.Order("created_at desc").Find(&requests).GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "searching change requests error")
+	}
+	return requests, nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
@@ -0,0 +1,67 @@
+package department
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+func NewDepartmentRepo(client *db.Client) *departmentRepo {
+	return &departmentRepo{client}
+}
+
+type departmentRepo struct {
+	*db.Client
+}
+
+func (r *departmentRepo) Save(ctx context.Context, department models.Department) error {
+	errs := r.Conn(ctx).Save(&department).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving department error")
+	}
+	return nil
+}
+
+func (r *departmentRepo) GetByID(ctx context.Context, id string) (models.Department, error) {
+	var department models.Department
+	err := r.Conn(ctx).Where("id = ?", id).First(&department).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return department, models.NewErrNotFound("department with id = %s is not found", id)
+	}
+	if err != nil {
+		return department, errors.Wrap(err, "getting department error")
+	}
+	return department, nil
+}
+
+func (r *departmentRepo) GetAll(ctx context.Context) ([]models.Department, error) {
+	departments := make([]models.Department, 0)
+	errs := r.Conn(ctx).Order("name").Find(&departments).GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting departments error")
+	}
+	return departments, nil
+}
+
+func (r *departmentRepo) Delete(ctx context.Context, id string) error {
+	db := r.Conn(ctx).Where("id = ?", id).Delete(models.Department{})
+	if db.Error != nil {
+		return errors.Wrap(db.Error, "deleting department error")
+	}
+	if db.RowsAffected == 0 {
+		return models.NewErrNotFound("department with id = %s is not found", id)
+	}
+	return nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
@@ -0,0 +1,55 @@
+package feedtoken
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+func NewFeedTokenRepo(client *db.Client) *feedTokenRepo {
+	return &feedTokenRepo{client}
+}
+
+type feedTokenRepo struct {
+	*db.Client
+}
+
+func (r *feedTokenRepo) Save(_ context.Context, token models.FeedToken) error {
+	errs := r.Session.Create(&token).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving feed token error")
+	}
+	return nil
+}
+
+func (r *feedTokenRepo) GetByToken(_ context.Context, token string) (models.FeedToken, error) {
+	var t models.FeedToken
+	err := r.Session.Where("token = ?", token).First(&t).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return models.FeedToken{}, models.NewErrNotFound("feed token is not found")
+	}
+	if err != nil {
+		return models.FeedToken{}, errors.Wrap(err, "getting feed token error")
+	}
+	return t, nil
+}
+
+func (r *feedTokenRepo) DeleteForUser(_ context.Context, userID string) error {
+	errs := r.Session.Where("user_id = ?", userID).Delete(models.FeedToken{}).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "deleting feed tokens error")
+	}
+	return nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
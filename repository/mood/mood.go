@@ -0,0 +1,51 @@
+package mood
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+func NewMoodRepo(client *db.Client) *moodRepo {
+	return &moodRepo{client}
+}
+
+type moodRepo struct {
+	*db.Client
+}
+
+func (r *moodRepo) Save(ctx context.Context, checkIn models.MoodCheckIn) error {
+	errs := r.Conn(ctx).Create(&checkIn).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving mood check-in error")
+	}
+	return nil
+}
+
+func (r *moodRepo) GetForUsers(ctx context.Context, userIDs []string, from, to time.Time) ([]models.MoodCheckIn, error) {
+	checkIns := make([]models.MoodCheckIn, 0)
+	if len(userIDs) == 0 {
+		return checkIns, nil
+	}
+
+	errs := r.Conn(ctx).Where("user_id IN (?) AND created_at >= ? AND created_at < ?", userIDs, from, to).
+		Order("created_at").
+		Find(&checkIns).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting mood check-ins error")
+	}
+	return checkIns, nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
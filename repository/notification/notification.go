@@ -0,0 +1,149 @@
+package notification
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+func NewNotificationRepo(client *db.Client) *notificationRepo {
+	return &notificationRepo{client}
+}
+
+type notificationRepo struct {
+	*db.Client
+}
+
+func (r *notificationRepo) GetPreferences(ctx context.Context, userID string) ([]models.NotificationPreference, error) {
+	prefs := make([]models.NotificationPreference, 0)
+	errs := r.Conn(ctx).Where("user_id = ?", userID).
+		Find(&prefs).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting notification preferences error")
+	}
+	return prefs, nil
+}
+
+func (r *notificationRepo) ReplacePreferences(_ context.Context, userID string, prefs []models.NotificationPreference) error {
+	return r.Session.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(models.NotificationPreference{}).Error
+		if err != nil {
+			return errors.Wrap(err, "deleting notification preferences error")
+		}
+
+		for _, pref := range prefs {
+			if err = tx.Create(&pref).Error; err != nil {
+				return errors.Wrap(err, "saving notification preference error")
+			}
+		}
+		return nil
+	})
+}
+
+func (r *notificationRepo) CreateDelivery(ctx context.Context, delivery models.NotificationDelivery) error {
+	err := r.Conn(ctx).Set("gorm:insert_option", "ON CONFLICT (change_id, user_id, channel, target) DO NOTHING").
+		Create(&delivery).Error
+	if err != nil {
+		return errors.Wrap(err, "saving notification delivery error")
+	}
+	return nil
+}
+
+func (r *notificationRepo) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.NotificationDelivery, error) {
+	deliveries := make([]models.NotificationDelivery, 0)
+	err := r.InTransaction(ctx, func(ctx context.Context) error {
+		errs := r.Conn(ctx).Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).
+			GetErrors()
+		if len(errs) > 0 {
+			return errors.Wrap(concatErrors(errs...), "locking due notification deliveries error")
+		}
+
+		ids := make([]uuid.UUID, 0, len(deliveries))
+		for i := range deliveries {
+			deliveries[i].NextAttemptAt = leaseUntil
+			ids = append(ids, deliveries[i].ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		err := r.Conn(ctx).Model(models.NotificationDelivery{}).Where("id IN (?)", ids).
+			Update("next_attempt_at", leaseUntil).Error
+		if err != nil {
+			return errors.Wrap(err, "claiming notification deliveries error")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *notificationRepo) SaveDelivery(ctx context.Context, delivery models.NotificationDelivery) error {
+	errs := r.Conn(ctx).Save(&delivery).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving notification delivery error")
+	}
+	return nil
+}
+
+func (r *notificationRepo) GetDeliveries(_ context.Context, userID string, limit int) ([]models.NotificationDelivery, error) {
+	deliveries := make([]models.NotificationDelivery, 0)
+	errs := r.Session.Where("user_id = ?", userID).
+		Order("created_at desc").
+		Limit(limit).
+		Find(&deliveries).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting notification deliveries error")
+	}
+	return deliveries, nil
+}
+
+func (r *notificationRepo) GetDigestSubscription(_ context.Context, userID string) (models.DigestSubscription, error) {
+	sub := models.DigestSubscription{UserID: userID, Frequency: models.NoDigest}
+	err := r.Session.Where("user_id = ?", userID).First(&sub).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return sub, errors.Wrap(err, "getting digest subscription error")
+	}
+	return sub, nil
+}
+
+func (r *notificationRepo) SaveDigestSubscription(_ context.Context, sub models.DigestSubscription) error {
+	errs := r.Session.Save(&sub).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving digest subscription error")
+	}
+	return nil
+}
+
+func (r *notificationRepo) GetDigestSubscriptions(_ context.Context, frequency models.DigestFrequency) ([]models.DigestSubscription, error) {
+	subs := make([]models.DigestSubscription, 0)
+	errs := r.Session.Where("frequency = ?", frequency).
+		Find(&subs).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting digest subscriptions error")
+	}
+	return subs, nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
@@ -0,0 +1,78 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Channel is notified on commit of transaction with new events
+	Channel = "outbox"
+
+	// relayLockKey is a key of advisory lock which lets only one relay publish events at a time
+	relayLockKey = 7301
+)
+
+func NewOutboxRepo(client *db.Client) *outboxRepo {
+	return &outboxRepo{client}
+}
+
+type outboxRepo struct {
+	*db.Client
+}
+
+// Save writes events within transaction from context, relays are notified when it is committed
+func (r *outboxRepo) Save(ctx context.Context, events ...models.OutboxEvent) error {
+	return r.InTransaction(ctx, func(ctx context.Context) error {
+		for _, event := range events {
+			if err := r.Conn(ctx).Create(&event).Error; err != nil {
+				return errors.Wrapf(err, "saving %s outbox event", event.Topic)
+			}
+		}
+
+		return r.Conn(ctx).Exec("SELECT pg_notify(?, '')", Channel).Error
+	})
+}
+
+// Lock acquires relay lock until the end of transaction from context, returns false if it is held by another relay
+func (r *outboxRepo) Lock(ctx context.Context) (bool, error) {
+	var locked bool
+	err := r.Conn(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", relayLockKey).Row().Scan(&locked)
+	if err != nil {
+		return false, errors.Wrap(err, "acquiring outbox relay lock")
+	}
+	return locked, nil
+}
+
+func (r *outboxRepo) GetUnpublished(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	events := make([]models.OutboxEvent, 0)
+	errs := r.Conn(ctx).Where("published_at IS NULL AND failed_at IS NULL").
+		Order("seq").
+		Limit(limit).
+		Find(&events).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting unpublished events error")
+	}
+	return events, nil
+}
+
+func (r *outboxRepo) Update(ctx context.Context, event models.OutboxEvent) error {
+	errs := r.Conn(ctx).Save(&event).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "updating outbox event error")
+	}
+	return nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
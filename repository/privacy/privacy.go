@@ -0,0 +1,43 @@
+package privacy
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+func NewPrivacyPolicyRepo(client *db.Client) *privacyPolicyRepo {
+	return &privacyPolicyRepo{client}
+}
+
+type privacyPolicyRepo struct {
+	*db.Client
+}
+
+func (r *privacyPolicyRepo) GetAll(ctx context.Context) ([]models.PrivacyPolicy, error) {
+	policies := make([]models.PrivacyPolicy, 0)
+	errs := r.Conn(ctx).Order("field").Find(&policies).GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting privacy policies error")
+	}
+	return policies, nil
+}
+
+func (r *privacyPolicyRepo) Save(ctx context.Context, policy models.PrivacyPolicy) error {
+	errs := r.Conn(ctx).Save(&policy).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving privacy policy error")
+	}
+	return nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
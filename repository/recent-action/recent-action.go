@@ -1,9 +1,13 @@
 package recent
 
 import (
+	"context"
+	"time"
+
 	"github.com/Dimitriy14/staff-manager/db"
 	"github.com/Dimitriy14/staff-manager/models"
 
+	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 )
 
@@ -15,12 +19,26 @@ type recentActionRepo struct {
 	*db.Client
 }
 
-func (r *recentActionRepo) Save(action models.RecentChanges) error {
-	errs := r.Session.Save(&action).GetErrors()
-	if len(errs) > 1 {
-		return errors.Wrap(concatErrors(errs...), "saving action error")
+// Create saves action if action with the same ID does not exist yet, returns true if it was saved.
+// It joins transaction from context, so the action is visible to others only after commit.
+func (r *recentActionRepo) Create(ctx context.Context, action models.RecentChanges) (bool, error) {
+	db := r.Conn(ctx).Set("gorm:insert_option", "ON CONFLICT (id) DO NOTHING").Create(&action)
+	if db.Error != nil {
+		return false, errors.Wrap(db.Error, "saving action error")
 	}
-	return nil
+	return db.RowsAffected > 0, nil
+}
+
+func (r *recentActionRepo) GetByID(id string) (models.RecentChanges, error) {
+	var action models.RecentChanges
+	err := r.Session.Where("id = ?", id).First(&action).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return models.RecentChanges{}, models.NewErrNotFound("recent change with id = %s is not found", id)
+	}
+	if err != nil {
+		return models.RecentChanges{}, errors.Wrap(err, "getting action error")
+	}
+	return action, nil
 }
 
 func (r *recentActionRepo) GetUserChanges(userID string) ([]models.RecentChanges, error) {
@@ -32,6 +50,130 @@ func (r *recentActionRepo) GetUserChanges(userID string) ([]models.RecentChanges
 	return actions, nil
 }
 
+// inboxRow is recent change joined with read mark of the user
+type inboxRow struct {
+	models.RecentChanges
+	IsRead bool
+}
+
+func (r *recentActionRepo) GetInbox(userID string, unreadOnly bool) ([]models.RecentChanges, error) {
+	rows := make([]inboxRow, 0)
+	query := r.userChanges(userID).
+		Select("recent_changes.*, m.read_at IS NOT NULL AS is_read").
+		Where("m.dismissed_at IS NULL")
+	if unreadOnly {
+		query = query.Where("m.read_at IS NULL")
+	}
+
+	errs := query.Order("change_time desc").Find(&rows).GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting inbox error")
+	}
+
+	actions := make([]models.RecentChanges, 0, len(rows))
+	for _, row := range rows {
+		row.RecentChanges.Read = row.IsRead
+		actions = append(actions, row.RecentChanges)
+	}
+	return actions, nil
+}
+
+func (r *recentActionRepo) GetUserChangesBetween(userID string, from, to time.Time) ([]models.RecentChanges, error) {
+	actions := make([]models.RecentChanges, 0)
+	errs := r.userChanges(userID).
+		Select("recent_changes.*").
+		Where("m.dismissed_at IS NULL AND change_time >= ? AND change_time < ?", from, to).
+		Order("change_time").
+		Find(&actions).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting changes error")
+	}
+	return actions, nil
+}
+
+func (r *recentActionRepo) CountUnread(userID string) (int, error) {
+	var count int
+	errs := r.userChanges(userID).
+		Where("m.read_at IS NULL AND m.dismissed_at IS NULL").
+		Count(&count).
+		GetErrors()
+	if len(errs) > 0 {
+		return 0, errors.Wrap(concatErrors(errs...), "counting unread changes error")
+	}
+	return count, nil
+}
+
+// MarkRead marks the change as read
+func (r *recentActionRepo) MarkRead(userID, changeID string) error {
+	return r.markOne(userID, "read_at", changeID)
+}
+
+// MarkAllRead marks changes of the user made not later than until as read, changes which came after that stay unread
+func (r *recentActionRepo) MarkAllRead(userID string, until time.Time) error {
+	_, err := r.mark(userID, "read_at", "change_time <= ?", until)
+	return err
+}
+
+func (r *recentActionRepo) MarkUnread(userID, changeID string) error {
+	db := r.Session.Model(&models.RecentChangeMark{}).
+		Where("user_id = ? AND change_id = ? AND dismissed_at IS NULL", userID, changeID).
+		Update("read_at", nil)
+	if db.Error != nil {
+		return errors.Wrap(db.Error, "marking change as unread error")
+	}
+	return nil
+}
+
+// Dismiss hides change from the inbox of the user, dismissed change is considered as read
+func (r *recentActionRepo) Dismiss(userID, changeID string) error {
+	return r.markOne(userID, "dismissed_at", changeID)
+}
+
+// userChanges selects changes related to the user joined with marks of the user
+func (r *recentActionRepo) userChanges(userID string) *gorm.DB {
+	return r.Session.Table("recent_changes").
+		Joins("LEFT JOIN recent_change_marks m ON m.change_id = recent_changes.id AND m.user_id = ?", userID).
+		Where("recent_changes.user_id = ? OR recent_changes.owner_id = ?", userID, userID)
+}
+
+// markOne sets mark column for the change of the user, not found is returned if user has no such change
+func (r *recentActionRepo) markOne(userID, column, changeID string) error {
+	marked, err := r.mark(userID, column, "id = ?", changeID)
+	if err != nil {
+		return err
+	}
+
+	if marked == 0 {
+		return models.NewErrNotFound("recent change is not found")
+	}
+	return nil
+}
+
+// mark sets mark column for changes of the user matching the filter keeping previously set time
+func (r *recentActionRepo) mark(userID, column, filter string, filterArg interface{}) (int64, error) {
+	var (
+		now   = time.Now().UTC()
+		query = `INSERT INTO recent_change_marks (change_id, user_id, read_at, dismissed_at)
+			SELECT id, ?, ?, ? FROM recent_changes WHERE (user_id = ? OR owner_id = ?) AND ` + filter
+		args = []interface{}{userID, now, nil, userID, userID, filterArg}
+	)
+
+	if column == "dismissed_at" {
+		args[2] = now
+	}
+
+	query += ` ON CONFLICT (change_id, user_id) DO UPDATE SET
+		read_at = COALESCE(recent_change_marks.read_at, EXCLUDED.read_at),
+		dismissed_at = COALESCE(recent_change_marks.dismissed_at, EXCLUDED.dismissed_at)`
+
+	db := r.Session.Exec(query, args...)
+	if db.Error != nil {
+		return 0, errors.Wrapf(db.Error, "setting %s mark error", column)
+	}
+	return db.RowsAffected, nil
+}
+
 func concatErrors(errs ...error) error {
 	var e string
 	for _, err := range errs {
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/Dimitriy14/staff-manager/models"
 )
@@ -9,18 +10,49 @@ import (
 type UserRepository interface {
 	GetUserByID(ctx context.Context, id string) (models.User, error)
 	GetAdmins(ctx context.Context) ([]models.User, error)
+	// Save replaces the whole user document, ErrConflict is returned if user read with Version was changed since
 	Save(ctx context.Context, u models.User) error
+	// Update merges non-empty fields of the user into stored document, empty fields keep stored values,
+	// ErrConflict is returned if user read with Version was changed since
 	Update(ctx context.Context, u models.User) error
+	// SearchUsers returns users matching the search, terminated users are excluded
 	SearchUsers(ctx context.Context, user models.UserSearch) ([]models.User, error)
+	// SearchDirectory returns users matching the search with highlighted fragments and facets
+	SearchDirectory(ctx context.Context, user models.UserSearch) (models.UserSearchResult, error)
+	// GetAll returns all users except terminated ones
+	GetAll(ctx context.Context) ([]models.User, error)
+	// GetAllWithTerminated returns all users including terminated ones
+	GetAllWithTerminated(ctx context.Context) ([]models.User, error)
+	// GetReports returns users who report directly to the manager, terminated users are excluded
+	GetReports(ctx context.Context, managerID string) ([]models.User, error)
+}
+
+// Transactor runs fn in a transaction shared by repositories which get context passed to fn
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// InSavepoint runs fn so that its failure rolls back only changes made by fn
+	InSavepoint(ctx context.Context, name string, fn func(ctx context.Context) error) error
 }
 
 type RecentActionRepository interface {
-	Save(action models.RecentChanges) error
+	Create(ctx context.Context, action models.RecentChanges) (bool, error)
+	GetByID(id string) (models.RecentChanges, error)
 	GetUserChanges(userID string) ([]models.RecentChanges, error)
+	// GetUserChangesBetween returns changes of the user made in [from, to) which are not dismissed
+	GetUserChangesBetween(userID string, from, to time.Time) ([]models.RecentChanges, error)
+	GetInbox(userID string, unreadOnly bool) ([]models.RecentChanges, error)
+	CountUnread(userID string) (int, error)
+	MarkRead(userID, changeID string) error
+	// MarkAllRead marks changes of the user made not later than until as read, changes which came after that stay unread
+	MarkAllRead(userID string, until time.Time) error
+	MarkUnread(userID, changeID string) error
+	Dismiss(userID, changeID string) error
 }
 
 type TaskRepository interface {
 	GetUserTasks(ctx context.Context, userID string) ([]models.TaskElastic, error)
+	// GetOpenAssignedTasks returns all not deleted tasks assigned to the user which are not Done
+	GetOpenAssignedTasks(ctx context.Context, userID string) ([]models.TaskElastic, error)
 	SaveTask(ctx context.Context, task models.TaskElastic) error
 	GetTasks(ctx context.Context, from, size int) ([]models.TaskElastic, error)
 	GetTaskByID(ctx context.Context, id string) (models.TaskElastic, error)
@@ -38,5 +70,116 @@ type VacationRepository interface {
 	GetPending(ctx context.Context) ([]models.VacationDB, error)
 	GetForUser(ctx context.Context, userID string) ([]models.VacationDB, error)
 	GetByID(ctx context.Context, vacationID string) (*models.VacationDB, error)
+	// GetForUpdate returns the vacation and locks it until the end of transaction
+	GetForUpdate(ctx context.Context, vacationID string) (*models.VacationDB, error)
 	GetPendingForUser(_ context.Context, userID string) ([]models.VacationDB, error)
+	GetPendingStartedBefore(ctx context.Context, date time.Time) ([]models.VacationDB, error)
+	GetApprovedFinishedBefore(ctx context.Context, date time.Time) ([]models.VacationDB, error)
+	GetApprovedBetween(ctx context.Context, from, to time.Time) ([]models.VacationDB, error)
+	GetActiveForUser(ctx context.Context, userID string, date time.Time) ([]models.VacationDB, error)
+	// GetDelegatedTo returns not finished vacations which tasks are covered by the delegate
+	GetDelegatedTo(ctx context.Context, delegateID string, date time.Time) ([]models.VacationDB, error)
+}
+
+type VacationApprovalRepository interface {
+	Save(ctx context.Context, approvals ...models.VacationApproval) error
+	Update(ctx context.Context, approval models.VacationApproval) error
+	GetForVacation(ctx context.Context, vacationID string) ([]models.VacationApproval, error)
+}
+
+type VacationCommentRepository interface {
+	Save(ctx context.Context, comment models.VacationComment) error
+	GetForVacation(ctx context.Context, vacationID string) ([]models.VacationComment, error)
+}
+
+type VacationBalanceRepository interface {
+	// AddUsedDays adds days to used days of the user in the year, negative days are returned to the user
+	AddUsedDays(ctx context.Context, userID string, year, days int) error
+	// Backfill creates balance of the user in the year from vacations approved before balances were kept,
+	// it should be called before balance is changed or read as such vacations were never charged
+	Backfill(ctx context.Context, userID string, year int) error
+	Get(ctx context.Context, userID string, year int) (models.VacationBalance, error)
+}
+
+type FeedTokenRepository interface {
+	Save(ctx context.Context, token models.FeedToken) error
+	GetByToken(ctx context.Context, token string) (models.FeedToken, error)
+	DeleteForUser(ctx context.Context, userID string) error
+}
+
+type NotificationRepository interface {
+	GetPreferences(ctx context.Context, userID string) ([]models.NotificationPreference, error)
+	ReplacePreferences(ctx context.Context, userID string, prefs []models.NotificationPreference) error
+	// CreateDelivery saves delivery unless the change is already queued for the target
+	CreateDelivery(ctx context.Context, delivery models.NotificationDelivery) error
+	// ClaimDueDeliveries returns pending deliveries which are due and postpones their next attempt until leaseUntil,
+	// so they are not claimed again while they are sent
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.NotificationDelivery, error)
+	SaveDelivery(ctx context.Context, delivery models.NotificationDelivery) error
+	GetDeliveries(ctx context.Context, userID string, limit int) ([]models.NotificationDelivery, error)
+	// GetDigestSubscription returns subscription with NoDigest frequency if user has not chosen it
+	GetDigestSubscription(ctx context.Context, userID string) (models.DigestSubscription, error)
+	SaveDigestSubscription(ctx context.Context, sub models.DigestSubscription) error
+	GetDigestSubscriptions(ctx context.Context, frequency models.DigestFrequency) ([]models.DigestSubscription, error)
+}
+
+type OutboxRepository interface {
+	Save(ctx context.Context, events ...models.OutboxEvent) error
+	Lock(ctx context.Context) (bool, error)
+	GetUnpublished(ctx context.Context, limit int) ([]models.OutboxEvent, error)
+	Update(ctx context.Context, event models.OutboxEvent) error
+}
+
+type WebhookRepository interface {
+	SaveSubscription(ctx context.Context, sub models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (models.WebhookSubscription, error)
+	GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+	CreateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, status models.WebhookDeliveryStatus, limit int) ([]models.WebhookDelivery, error)
+	// ClaimDueDeliveries returns pending deliveries which are due and postpones their next attempt till leaseUntil,
+	// so other instances do not send them while they are being sent
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+}
+
+type AuditRepository interface {
+	// LockChain serializes appending to the chain until the end of transaction from context
+	LockChain(ctx context.Context) error
+	GetLast(ctx context.Context) (*models.AuditEntry, error)
+	Exists(ctx context.Context, id string) (bool, error)
+	Create(ctx context.Context, entry models.AuditEntry) error
+	Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	GetAfter(ctx context.Context, seq int64, limit int) ([]models.AuditEntry, error)
+}
+
+type DepartmentRepository interface {
+	Save(ctx context.Context, department models.Department) error
+	GetByID(ctx context.Context, id string) (models.Department, error)
+	GetAll(ctx context.Context) ([]models.Department, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type ChangeRequestRepository interface {
+	Save(ctx context.Context, req models.ProfileChangeRequest) error
+	GetByID(ctx context.Context, id string) (models.ProfileChangeRequest, error)
+	// GetForUpdate returns the request and locks it until the end of transaction
+	GetForUpdate(ctx context.Context, id string) (models.ProfileChangeRequest, error)
+	// LockUser holds lock on requests of the user until the end of transaction
+	LockUser(ctx context.Context, userID string) error
+	// Search returns requests matching the filter, the newest first
+	Search(ctx context.Context, filter models.ChangeRequestFilter) ([]models.ProfileChangeRequest, error)
+}
+
+type MoodRepository interface {
+	Save(ctx context.Context, checkIn models.MoodCheckIn) error
+	// GetForUsers returns check-ins of the users made in [from, to) ordered by time
+	GetForUsers(ctx context.Context, userIDs []string, from, to time.Time) ([]models.MoodCheckIn, error)
+}
+
+type PrivacyPolicyRepository interface {
+	GetAll(ctx context.Context) ([]models.PrivacyPolicy, error)
+	Save(ctx context.Context, policy models.PrivacyPolicy) error
 }
@@ -17,8 +17,12 @@ const (
 	taskType  = "task"
 
 	assignedAttribute = "assignedID"
+	statusAttribute   = "status"
 
 	number = "number"
+
+	// maxTasks is a maximum result window of elasticsearch index
+	maxTasks = 10000
 )
 
 func NewRepository(es *elasticsearch.Client) *tasksRepo {
@@ -51,6 +55,28 @@ func (r *tasksRepo) GetUserTasks(ctx context.Context, userID string) ([]models.T
 	return tasks, err
 }
 
+func (r *tasksRepo) GetOpenAssignedTasks(ctx context.Context, userID string) ([]models.TaskElastic, error) {
+	q := elastic.NewBoolQuery().
+		Must(elastic.NewMatchQuery(assignedAttribute, userID)).
+		Filter(elastic.NewMatchQuery("isDeleted", false)).
+		MustNot(elastic.NewMatchQuery(statusAttribute, models.Done))
+	resp, err := r.es.ESClient.Search(taskIndex).
+		Query(q).
+		Size(maxTasks).
+		Do(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "searching open tasks for user(id=%s)", userID)
+	}
+
+	tasks := make([]models.TaskElastic, 0, resp.TotalHits())
+	for _, u := range resp.Each(reflect.TypeOf(models.TaskElastic{})) {
+		if task, ok := u.(models.TaskElastic); ok {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
 func (r *tasksRepo) SaveTask(ctx context.Context, task models.TaskElastic) error {
 	_, err := r.es.ESClient.Index().
 		Index(taskIndex).
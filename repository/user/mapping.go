@@ -0,0 +1,242 @@
+package user
+
+import (
+	"context"
+
+	elastic "github.com/olivere/elastic/v7"
+	"github.com/pkg/errors"
+)
+
+const (
+	// indexVersion is a physical index which elasticIndex alias points to, it is increased when mapping is changed
+	indexVersion = elasticIndex + "_v1"
+	// legacyIndex is an index used before mapping was introduced, it is left untouched by migration
+	legacyIndex = "staff"
+)
+
+const (
+	autocompleteField = ".autocomplete"
+	keywordField      = ".keyword"
+)
+
+// indexBody defines analyzers and mapping of users index.
+// Text fields are analyzed with folding analyzer for full words and typos, autocomplete subfields
+// contain edge n-grams for prefix search and keyword subfields are used for facets.
+const indexBody = `
+{
+    "settings": {
+        "number_of_shards": 1,
+        "analysis": {
+            "filter": {
+                "autocomplete_filter": {
+                    "type": "edge_ngram",
+                    "min_gram": 1,
+                    "max_gram": 20
+                }
+            },
+            "analyzer": {
+                "folding": {
+                    "type": "custom",
+                    "tokenizer": "standard",
+                    "filter": ["lowercase", "asciifolding"]
+                },
+                "autocomplete": {
+                    "type": "custom",
+                    "tokenizer": "standard",
+                    "filter": ["lowercase", "asciifolding", "autocomplete_filter"]
+                }
+            }
+        }
+    },
+    "mappings": {
+        "properties": {
+            "id": {"type": "keyword"},
+            "firstName": {
+                "type": "text",
+                "analyzer": "folding",
+                "fields": {
+                    "autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"}
+                }
+            },
+            "lastName": {
+                "type": "text",
+                "analyzer": "folding",
+                "fields": {
+                    "autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"}
+                }
+            },
+            "email": {
+                "type": "text",
+                "analyzer": "folding",
+                "fields": {
+                    "autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"},
+                    "keyword": {"type": "keyword", "ignore_above": 256}
+                }
+            },
+            "position": {
+                "type": "text",
+                "analyzer": "folding",
+                "fields": {
+                    "autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"},
+                    "keyword": {"type": "keyword", "ignore_above": 256}
+                }
+            },
+            "mobilePhone": {"type": "keyword"},
+            "dateOfBirth": {"type": "keyword"},
+            "hireDate": {"type": "keyword"},
+            "imageURL": {"type": "keyword", "index": false},
+            "role": {"type": "keyword"},
+            "mood": {"type": "text"},
+            "managerID": {"type": "keyword"},
+            "departmentID": {"type": "keyword"},
+            "privacy": {"type": "object", "enabled": false},
+            "status": {"type": "keyword"},
+            "onboardedAt": {"type": "date"},
+            "terminatedAt": {"type": "date"},
+            "skills": {
+                "properties": {
+                    "name": {
+                        "type": "text",
+                        "analyzer": "folding",
+                        "fields": {
+                            "autocomplete": {"type": "text", "analyzer": "autocomplete", "search_analyzer": "folding"},
+                            "keyword": {"type": "keyword", "ignore_above": 256}
+                        }
+                    },
+                    "proficiency": {"type": "keyword"}
+                }
+            },
+            "languages": {
+                "properties": {
+                    "name": {
+                        "type": "text",
+                        "analyzer": "folding",
+                        "fields": {
+                            "keyword": {"type": "keyword", "ignore_above": 256}
+                        }
+                    },
+                    "level": {"type": "keyword"}
+                }
+            },
+            "location": {
+                "type": "text",
+                "analyzer": "folding",
+                "fields": {
+                    "keyword": {"type": "keyword", "ignore_above": 256}
+                }
+            },
+            "timeZone": {"type": "keyword"},
+            "bio": {"type": "text", "analyzer": "folding"},
+            "links": {"type": "object", "enabled": false}
+        }
+    }
+}
+`
+
+// Migrate moves users to the current version of index behind elasticIndex alias. It is run once by migration command
+// before application instances of the new version are started. Users are copied from the index which the alias points to
+// or from legacyIndex if the alias does not exist yet, source indices are kept so the previous version can be restored.
+func (r *repo) Migrate(ctx context.Context) error {
+	client := r.es.ESClient
+
+	indices, err := r.aliasedIndices(ctx)
+	if err != nil {
+		return err
+	}
+
+	source := elasticIndex
+	for _, index := range indices {
+		if index == indexVersion {
+			return nil
+		}
+	}
+
+	if len(indices) == 0 {
+		exists, err := client.IndexExists(legacyIndex).Do(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "checking index %s", legacyIndex)
+		}
+
+		source = legacyIndex
+		if !exists {
+			source = ""
+		}
+	}
+
+	err = r.createIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	if source != "" {
+		_, err = client.Reindex().
+			SourceIndex(source).
+			DestinationIndex(indexVersion).
+			WaitForCompletion(true).
+			Refresh("true").
+			Do(ctx)
+		if err != nil {
+			return errors.Wrapf(err, "reindexing %s to %s", source, indexVersion)
+		}
+	}
+
+	actions := []elastic.AliasAction{elastic.NewAliasAddAction(elasticIndex).Index(indexVersion)}
+	for _, index := range indices {
+		actions = append(actions, elastic.NewAliasRemoveAction(elasticIndex).Index(index))
+	}
+
+	_, err = client.Alias().Action(actions...).Do(ctx)
+	return errors.Wrapf(err, "moving alias %s to index %s", elasticIndex, indexVersion)
+}
+
+// CheckIndex returns error if elasticIndex alias does not point to the current version of index,
+// so that users are not written to index which is created by elasticsearch without mapping
+func (r *repo) CheckIndex(ctx context.Context) error {
+	indices, err := r.aliasedIndices(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indices {
+		if index == indexVersion {
+			return nil
+		}
+	}
+	return errors.Errorf("users index %s is not migrated to %s, run migration first", elasticIndex, indexVersion)
+}
+
+// aliasedIndices returns indices which elasticIndex alias points to, it is empty if alias does not exist
+func (r *repo) aliasedIndices(ctx context.Context) ([]string, error) {
+	exists, err := r.es.ESClient.IndexExists(elasticIndex).Do(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "checking alias %s", elasticIndex)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	resp, err := r.es.ESClient.IndexGet(elasticIndex).Do(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting alias %s", elasticIndex)
+	}
+
+	indices := make([]string, 0, len(resp))
+	for index := range resp {
+		indices = append(indices, index)
+	}
+	return indices, nil
+}
+
+// createIndex creates current version of users index, index left by interrupted migration is reused
+func (r *repo) createIndex(ctx context.Context) error {
+	exists, err := r.es.ESClient.IndexExists(indexVersion).Do(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "checking index %s", indexVersion)
+	}
+	if exists {
+		return nil
+	}
+
+	_, err = r.es.ESClient.CreateIndex(indexVersion).BodyString(indexBody).Do(ctx)
+	return errors.Wrapf(err, "creating index %s", indexVersion)
+}
@@ -0,0 +1,124 @@
+package user
+
+import (
+	"strings"
+
+	"github.com/Dimitriy14/staff-manager/models"
+
+	elastic "github.com/olivere/elastic/v7"
+)
+
+const (
+	email        = "email"
+	departmentID = "departmentID"
+
+	defaultSearchSize = 20
+	maxSearchSize     = 100
+	facetSize         = 20
+
+	// typoFuzziness allows one typo in words of 3-5 letters and two in longer words
+	typoFuzziness = "AUTO"
+)
+
+type searchField struct {
+	name  string
+	boost float64
+}
+
+var (
+	nameFields = []searchField{{userName, 3}, {userSecondName, 3}}
+	// queryFields are matched by query, names are more relevant than other fields
+	queryFields = []searchField{{userName, 3}, {userSecondName, 3}, {email, 2}, {position, 1}, {skillName, 1}}
+
+	// facetFields are keyword fields which facets are counted by
+	facetFields = map[string]string{
+		"position":     position + keywordField,
+		"departmentID": departmentID,
+		"location":     location + keywordField,
+	}
+)
+
+// searchQuery builds query for all criteria of the search, terminated users are excluded
+func searchQuery(us models.UserSearch) *elastic.BoolQuery {
+	q := notTerminated()
+	textQuery(q, us.Query, queryFields)
+	textQuery(q, us.ByName, nameFields)
+
+	if us.ByPosition != "" {
+		q.Filter(elastic.NewMatchQuery(position, us.ByPosition))
+	}
+	if us.ByLocation != "" {
+		q.Filter(elastic.NewMatchQuery(location, us.ByLocation).Operator("and"))
+	}
+	if us.ByDepartment != "" {
+		q.Filter(elastic.NewTermQuery(departmentID, us.ByDepartment))
+	}
+	// phrase does not match words of different skills or languages of the same user
+	for _, skill := range us.BySkills {
+		q.Filter(elastic.NewMatchPhraseQuery(skillName, skill))
+	}
+	for _, language := range us.ByLanguages {
+		q.Filter(elastic.NewMatchPhraseQuery(languageName, language))
+	}
+	return q
+}
+
+// textQuery requires every word of the text to match any of the fields either by prefix or with a typo
+func textQuery(q *elastic.BoolQuery, text string, fields []searchField) {
+	for _, word := range strings.Fields(text) {
+		var (
+			prefix = elastic.NewMultiMatchQuery(word).Type("best_fields")
+			typo   = elastic.NewMultiMatchQuery(word).Type("best_fields").Fuzziness(typoFuzziness).PrefixLength(1)
+		)
+		for _, f := range fields {
+			prefix.FieldWithBoost(f.name+autocompleteField, f.boost)
+			typo.FieldWithBoost(f.name, f.boost)
+		}
+		q.Must(elastic.NewBoolQuery().Should(prefix, typo))
+	}
+}
+
+func searchSize(limit int) int {
+	switch {
+	case limit <= 0:
+		return defaultSearchSize
+	case limit > maxSearchSize:
+		return maxSearchSize
+	}
+	return limit
+}
+
+func searchHighlight() *elastic.Highlight {
+	h := elastic.NewHighlight()
+	for _, f := range queryFields {
+		h.Fields(elastic.NewHighlighterField(f.name), elastic.NewHighlighterField(f.name+autocompleteField))
+	}
+	return h
+}
+
+// highlights merges fragments of autocomplete subfields into fragments of their fields
+func highlights(hl elastic.SearchHitHighlight) map[string][]string {
+	if len(hl) == 0 {
+		return nil
+	}
+
+	merged := make(map[string][]string, len(hl))
+	for field, fragments := range hl {
+		field = strings.TrimSuffix(field, autocompleteField)
+		for _, fragment := range fragments {
+			if !contains(merged[field], fragment) {
+				merged[field] = append(merged[field], fragment)
+			}
+		}
+	}
+	return merged
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -3,8 +3,9 @@ package user
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"reflect"
-	"strings"
 
 	"github.com/Dimitriy14/staff-manager/elasticsearch"
 	"github.com/Dimitriy14/staff-manager/models"
@@ -14,13 +15,23 @@ import (
 )
 
 const (
-	elasticIndex = "staff"
+	// elasticIndex is an alias of the current version of users index
+	elasticIndex = "staff_users"
 	userType     = "user"
 
 	role           = "role"
 	userName       = "firstName"
 	userSecondName = "lastName"
 	position       = "position"
+	managerID      = "managerID"
+	status         = "status"
+	skillName      = "skills.name"
+	languageName   = "languages.name"
+	location       = "location"
+
+	// scrollSize is a number of users fetched by one scroll request
+	scrollSize      = 1000
+	scrollKeepAlive = "1m"
 )
 
 func NewRepository(es *elasticsearch.Client) *repo {
@@ -42,65 +53,106 @@ func (r *repo) GetUserByID(ctx context.Context, id string) (models.User, error)
 
 	var u models.User
 	err = json.Unmarshal(resp.Source, &u)
+	if resp.SeqNo != nil && resp.PrimaryTerm != nil {
+		u.Version = &models.DocVersion{SeqNo: *resp.SeqNo, PrimaryTerm: *resp.PrimaryTerm}
+	}
 	return u, err
 }
 
 func (r *repo) Save(ctx context.Context, u models.User) error {
-	_, err := r.es.ESClient.Index().
+	index := r.es.ESClient.Index().
 		Index(elasticIndex).
 		BodyJson(u).
-		Id(u.ID.String()).
-		Do(ctx)
+		Id(u.ID.String())
+	if u.Version != nil {
+		index = index.IfSeqNo(u.Version.SeqNo).IfPrimaryTerm(u.Version.PrimaryTerm)
+	}
 
-	return err
+	_, err := index.Do(ctx)
+	return conflictError(err, u)
 }
 
 func (r *repo) Update(ctx context.Context, u models.User) error {
-	_, err := r.es.ESClient.Update().
+	update := r.es.ESClient.Update().
 		Index(elasticIndex).
 		Doc(u).
-		Id(u.ID.String()).
-		Do(ctx)
+		Id(u.ID.String())
+	if u.Version != nil {
+		update = update.IfSeqNo(u.Version.SeqNo).IfPrimaryTerm(u.Version.PrimaryTerm)
+	}
 
-	return err
+	_, err := update.Do(ctx)
+	return conflictError(err, u)
 }
 
-func (r *repo) SearchUsers(ctx context.Context, us models.UserSearch) ([]models.User, error) {
-	q := elastic.NewBoolQuery()
-	strs := strings.Split(strings.TrimRight(us.ByName, " "), " ")
-	if us.ByPosition != "" {
-		q.Filter(elastic.NewMatchQuery(position, us.ByPosition))
-	}
-
-	switch {
-	case len(strs) == 1:
-		q.Must(elastic.NewMultiMatchQuery(strings.TrimSpace(strs[0]), userName, userSecondName).Type("phrase_prefix"))
-	case len(strs) > 1:
-		q.Must(
-			elastic.NewMultiMatchQuery(strings.TrimSpace(strs[0]), userName, userSecondName).Type("cross_fields"),
-			elastic.NewMultiMatchQuery(strings.TrimSpace(strs[1]), userName, userSecondName).Type("phrase_prefix"),
-		)
+// conflictError tells that user was changed by someone else since it was read
+func conflictError(err error, u models.User) error {
+	if elastic.IsConflict(err) {
+		return models.NewErrConflict("user with id = %s was changed concurrently, reload it and try again", u.ID)
 	}
+	return err
+}
 
+func (r *repo) SearchUsers(ctx context.Context, us models.UserSearch) ([]models.User, error) {
 	resp, err := r.es.ESClient.Search().
 		Index(elasticIndex).
-		Query(q).
+		Query(searchQuery(us)).
+		From(us.Offset).
+		Size(searchSize(us.Limit)).
 		Do(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "searching user by name %s", us.ByName)
 	}
 
-	users := make([]models.User, 0, resp.TotalHits())
-	for _, u := range resp.Each(reflect.TypeOf(models.User{})) {
-		if user, ok := u.(models.User); ok {
-			users = append(users, user)
+	return usersFromHits(resp), nil
+}
+
+func (r *repo) SearchDirectory(ctx context.Context, us models.UserSearch) (models.UserSearchResult, error) {
+	search := r.es.ESClient.Search().
+		Index(elasticIndex).
+		Query(searchQuery(us)).
+		From(us.Offset).
+		Size(searchSize(us.Limit)).
+		TrackTotalHits(true).
+		Highlight(searchHighlight())
+
+	for facet, field := range facetFields {
+		search.Aggregation(facet, elastic.NewTermsAggregation().Field(field).Size(facetSize))
+	}
+
+	resp, err := search.Do(ctx)
+	if err != nil {
+		return models.UserSearchResult{}, errors.Wrapf(err, "searching directory by query %s", us.Query)
+	}
+
+	result := models.UserSearchResult{
+		Total:  resp.TotalHits(),
+		Users:  make([]models.UserHit, 0, len(resp.Hits.Hits)),
+		Facets: make(map[string][]models.FacetBucket, len(facetFields)),
+	}
+
+	for _, hit := range resp.Hits.Hits {
+		var user models.User
+		if err := json.Unmarshal(hit.Source, &user); err != nil {
+			return models.UserSearchResult{}, errors.Wrapf(err, "unmarshaling user %s", hit.Id)
 		}
+		result.Users = append(result.Users, models.UserHit{User: user, Highlights: highlights(hit.Highlight)})
 	}
-	return users, nil
+
+	for facet := range facetFields {
+		buckets := make([]models.FacetBucket, 0)
+		if terms, ok := resp.Aggregations.Terms(facet); ok {
+			for _, bucket := range terms.Buckets {
+				buckets = append(buckets, models.FacetBucket{Value: fmt.Sprint(bucket.Key), Count: bucket.DocCount})
+			}
+		}
+		result.Facets[facet] = buckets
+	}
+	return result, nil
 }
 
 func (r *repo) GetAdmins(ctx context.Context) ([]models.User, error) {
-	q := elastic.NewMatchQuery(role, models.AdminRole)
+	q := notTerminated().Must(elastic.NewMatchQuery(role, models.AdminRole))
 	resp, err := r.es.ESClient.
 		Search(elasticIndex).
 		Query(q).
@@ -117,3 +169,63 @@ func (r *repo) GetAdmins(ctx context.Context) ([]models.User, error) {
 	}
 	return users, nil
 }
+
+func (r *repo) GetAll(ctx context.Context) ([]models.User, error) {
+	users, err := r.scrollUsers(ctx, notTerminated())
+	if err != nil {
+		return nil, errors.Wrapf(err, "searching all users")
+	}
+	return users, nil
+}
+
+func (r *repo) GetAllWithTerminated(ctx context.Context) ([]models.User, error) {
+	users, err := r.scrollUsers(ctx, elastic.NewMatchAllQuery())
+	if err != nil {
+		return nil, errors.Wrapf(err, "searching all users with terminated ones")
+	}
+	return users, nil
+}
+
+func (r *repo) GetReports(ctx context.Context, manager string) ([]models.User, error) {
+	users, err := r.scrollUsers(ctx, notTerminated().Filter(elastic.NewTermQuery(managerID, manager)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "searching reports of user(id=%s)", manager)
+	}
+	return users, nil
+}
+
+// scrollUsers returns all users matching the query, they are read in pages so result is not limited by result window of index
+func (r *repo) scrollUsers(ctx context.Context, q elastic.Query) ([]models.User, error) {
+	scroll := r.es.ESClient.Scroll(elasticIndex).
+		Query(q).
+		Size(scrollSize).
+		KeepAlive(scrollKeepAlive)
+	defer scroll.Clear(context.Background())
+
+	users := make([]models.User, 0)
+	for {
+		resp, err := scroll.Do(ctx)
+		if err == io.EOF {
+			return users, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, usersFromHits(resp)...)
+	}
+}
+
+// notTerminated matches users who are not terminated
+func notTerminated() *elastic.BoolQuery {
+	return elastic.NewBoolQuery().MustNot(elastic.NewTermQuery(status, models.Terminated))
+}
+
+func usersFromHits(resp *elastic.SearchResult) []models.User {
+	users := make([]models.User, 0, resp.TotalHits())
+	for _, u := range resp.Each(reflect.TypeOf(models.User{})) {
+		if user, ok := u.(models.User); ok {
+			users = append(users, user)
+		}
+	}
+	return users
+}
@@ -0,0 +1,65 @@
+package vacationbalance
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+func NewVacationBalanceRepo(client *db.Client) *vacationBalanceRepo {
+	return &vacationBalanceRepo{client}
+}
+
+type vacationBalanceRepo struct {
+	*db.Client
+}
+
+func (r *vacationBalanceRepo) AddUsedDays(ctx context.Context, userID string, year, days int) error {
+	errs := r.Conn(ctx).Exec(`INSERT INTO vacation_balances (user_id, year, used_days) VALUES (?, ?, ?)
+		ON CONFLICT (user_id, year) DO UPDATE SET used_days = vacation_balances.used_days + EXCLUDED.used_days`,
+		userID, year, days).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "updating vacation balance error")
+	}
+	return nil
+}
+
+// Backfill creates balance of the user in the year from days of vacations approved before balances were kept,
+// existing balance is not changed
+func (r *vacationBalanceRepo) Backfill(ctx context.Context, userID string, year int) error {
+	vacations := r.Conn(ctx).NewScope(&models.VacationDB{}).TableName()
+	errs := r.Conn(ctx).Exec(fmt.Sprintf(`INSERT INTO vacation_balances (user_id, year, used_days)
+		SELECT ?, ?, COALESCE(SUM(end_date::date - start_date::date + 1), 0) FROM %s
+		WHERE user_id = ? AND status IN (?, ?) AND EXTRACT(YEAR FROM start_date) = ?
+		ON CONFLICT (user_id, year) DO NOTHING`, vacations),
+		userID, year, userID, models.Approved, models.CancelRequested, year).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "backfilling vacation balance error")
+	}
+	return nil
+}
+
+func (r *vacationBalanceRepo) Get(ctx context.Context, userID string, year int) (models.VacationBalance, error) {
+	var b models.VacationBalance
+	err := r.Conn(ctx).Where("user_id = ? AND year = ?", userID, year).First(&b).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return models.VacationBalance{UserID: userID, Year: year}, nil
+	}
+	if err != nil {
+		return models.VacationBalance{}, errors.Wrap(err, "getting vacation balance error")
+	}
+	return b, nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
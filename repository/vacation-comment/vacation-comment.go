@@ -0,0 +1,46 @@
+package comment
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+func NewVacationCommentRepo(client *db.Client) *commentRepo {
+	return &commentRepo{client}
+}
+
+type commentRepo struct {
+	*db.Client
+}
+
+func (r *commentRepo) Save(ctx context.Context, comment models.VacationComment) error {
+	errs := r.Conn(ctx).Create(&comment).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving vacation comment error")
+	}
+	return nil
+}
+
+func (r *commentRepo) GetForVacation(ctx context.Context, vacationID string) ([]models.VacationComment, error) {
+	comments := make([]models.VacationComment, 0)
+	errs := r.Conn(ctx).Where("vacation_id = ?", vacationID).
+		Order("created_at").
+		Find(&comments).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting vacation comments error")
+	}
+	return comments, nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
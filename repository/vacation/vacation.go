@@ -2,10 +2,12 @@ package vacation
 
 import (
 	"context"
+	"time"
 
 	"github.com/Dimitriy14/staff-manager/db"
 	"github.com/Dimitriy14/staff-manager/models"
 
+	"github.com/jinzhu/gorm"
 	"github.com/pkg/errors"
 )
 
@@ -17,25 +19,25 @@ type vacationRepo struct {
 	*db.Client
 }
 
-func (r *vacationRepo) Save(_ context.Context, vacation models.VacationDB) (*models.VacationDB, error) {
-	errs := r.Session.Save(&vacation).GetErrors()
+func (r *vacationRepo) Save(ctx context.Context, vacation models.VacationDB) (*models.VacationDB, error) {
+	errs := r.Conn(ctx).Save(&vacation).GetErrors()
 	if len(errs) > 1 {
 		return nil, errors.Wrap(concatErrors(errs...), "saving vacation error")
 	}
 	return &vacation, nil
 }
 
-func (r *vacationRepo) Update(_ context.Context, vacation models.VacationDB) error {
-	errs := r.Session.Update(&vacation).GetErrors()
+func (r *vacationRepo) Update(ctx context.Context, vacation models.VacationDB) error {
+	errs := r.Conn(ctx).Save(&vacation).GetErrors()
 	if len(errs) > 1 {
 		return errors.Wrap(concatErrors(errs...), "updating vacation error")
 	}
 	return nil
 }
 
-func (r *vacationRepo) GetAll(_ context.Context) ([]models.VacationDB, error) {
+func (r *vacationRepo) GetAll(ctx context.Context) ([]models.VacationDB, error) {
 	vacations := make([]models.VacationDB, 0)
-	errs := r.Session.
+	errs := r.Conn(ctx).
 		Find(&vacations).
 		GetErrors()
 	if len(errs) > 1 {
@@ -44,9 +46,9 @@ func (r *vacationRepo) GetAll(_ context.Context) ([]models.VacationDB, error) {
 	return vacations, nil
 }
 
-func (r *vacationRepo) GetActual(_ context.Context) ([]models.VacationDB, error) {
+func (r *vacationRepo) GetActual(ctx context.Context) ([]models.VacationDB, error) {
 	vacations := make([]models.VacationDB, 0)
-	errs := r.Session.Where("status in (?, ?, ?)", models.Pending, models.Approved, models.Rejected).
+	errs := r.Conn(ctx).Where("status in (?, ?, ?, ?)", models.Pending, models.Approved, models.Rejected, models.CancelRequested).
 		Order("start_date").
 		Find(&vacations).
 		GetErrors()
@@ -56,9 +58,9 @@ func (r *vacationRepo) GetActual(_ context.Context) ([]models.VacationDB, error)
 	return vacations, nil
 }
 
-func (r *vacationRepo) GetPending(_ context.Context) ([]models.VacationDB, error) {
+func (r *vacationRepo) GetPending(ctx context.Context) ([]models.VacationDB, error) {
 	vacations := make([]models.VacationDB, 0)
-	errs := r.Session.Where("status = ?", models.Pending).
+	errs := r.Conn(ctx).Where("status = ?", models.Pending).
 		Order("start_date").
 		Find(&vacations).
 		GetErrors()
@@ -68,9 +70,9 @@ func (r *vacationRepo) GetPending(_ context.Context) ([]models.VacationDB, error
 	return vacations, nil
 }
 
-func (r *vacationRepo) GetForUser(_ context.Context, userID string) ([]models.VacationDB, error) {
+func (r *vacationRepo) GetForUser(ctx context.Context, userID string) ([]models.VacationDB, error) {
 	vacations := make([]models.VacationDB, 0)
-	errs := r.Session.Where("user_id = ?", userID).
+	errs := r.Conn(ctx).Where("user_id = ?", userID).
 		Order("start_date").
 		Find(&vacations).
 		GetErrors()
@@ -80,9 +82,9 @@ func (r *vacationRepo) GetForUser(_ context.Context, userID string) ([]models.Va
 	return vacations, nil
 }
 
-func (r *vacationRepo) GetPendingForUser(_ context.Context, userID string) ([]models.VacationDB, error) {
+func (r *vacationRepo) GetPendingForUser(ctx context.Context, userID string) ([]models.VacationDB, error) {
 	vacations := make([]models.VacationDB, 0)
-	errs := r.Session.Where("user_id = ? AND status = ?", userID, models.Pending).
+	errs := r.Conn(ctx).Where("user_id = ? AND status = ?", userID, models.Pending).
 		Order("start_date").
 		Find(&vacations).
 		GetErrors()
@@ -92,13 +94,90 @@ func (r *vacationRepo) GetPendingForUser(_ context.Context, userID string) ([]mo
 	return vacations, nil
 }
 
+func (r *vacationRepo) GetPendingStartedBefore(ctx context.Context, date time.Time) ([]models.VacationDB, error) {
+	vacations := make([]models.VacationDB, 0)
+	errs := r.Conn(ctx).Where("status = ? AND start_date < ?", models.Pending, date).
+		Order("start_date").
+		Find(&vacations).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting outdated pending vacation error")
+	}
+	return vacations, nil
+}
+
+func (r *vacationRepo) GetApprovedFinishedBefore(ctx context.Context, date time.Time) ([]models.VacationDB, error) {
+	vacations := make([]models.VacationDB, 0)
+	errs := r.Conn(ctx).Where("status in (?, ?) AND end_date < ? AND taken = ?", models.Approved, models.CancelRequested, date, false).
+		Order("start_date").
+		Find(&vacations).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting finished approved vacation error")
+	}
+	return vacations, nil
+}
+
+// GetApprovedBetween returns approved vacations which overlap with specified period
+func (r *vacationRepo) GetApprovedBetween(ctx context.Context, from, to time.Time) ([]models.VacationDB, error) {
+	vacations := make([]models.VacationDB, 0)
+	errs := r.Conn(ctx).Where("status in (?, ?) AND start_date <= ? AND end_date >= ?", models.Approved, models.CancelRequested, to, from).
+		Order("start_date").
+		Find(&vacations).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting approved vacation error")
+	}
+	return vacations, nil
+}
+
+// GetActiveForUser returns approved vacations of the user which include specified date
+func (r *vacationRepo) GetActiveForUser(ctx context.Context, userID string, date time.Time) ([]models.VacationDB, error) {
+	vacations := make([]models.VacationDB, 0)
+	errs := r.Conn(ctx).Where("user_id = ? AND status in (?, ?) AND start_date <= ? AND end_date >= ?",
+		userID, models.Approved, models.CancelRequested, date, date).
+		Find(&vacations).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting active user vacation error")
+	}
+	return vacations, nil
+}
+
+// GetDelegatedTo returns not finished vacations which tasks are covered by the delegate
+func (r *vacationRepo) GetDelegatedTo(ctx context.Context, delegateID string, date time.Time) ([]models.VacationDB, error) {
+	vacations := make([]models.VacationDB, 0)
+	errs := r.Conn(ctx).Where("delegate_id = ? AND status in (?, ?, ?) AND end_date >= ?",
+		delegateID, models.Pending, models.Approved, models.CancelRequested, date).
+		Find(&vacations).
+		GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting delegated vacations error")
+	}
+	return vacations, nil
+}
+
 func (r *vacationRepo) GetByID(ctx context.Context, vacationID string) (*models.VacationDB, error) {
 	var vacation = new(models.VacationDB)
-	errs := r.Session.Where("id = ?", vacationID).
-		Find(vacation).
-		GetErrors()
-	if len(errs) > 1 {
-		return nil, errors.Wrap(concatErrors(errs...), "getting user vacation error")
+	err := r.Conn(ctx).Where("id = ?", vacationID).First(vacation).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, models.NewErrNotFound("vacation with id = %s is not found", vacationID)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "getting user vacation error")
+	}
+	return vacation, nil
+}
+
+// GetForUpdate returns the vacation and locks it until the end of transaction
+func (r *vacationRepo) GetForUpdate(ctx context.Context, vacationID string) (*models.VacationDB, error) {
+	var vacation = new(models.VacationDB)
+	err := r.Conn(ctx).Set("gorm:query_option", "FOR UPDATE").Where("id = ?", vacationID).First(vacation).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, models.NewErrNotFound("vacation with id = %s is not found", vacationID)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "locking vacation error")
 	}
 	return vacation, nil
 }
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/db"
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/google/uuid"
+	"github.com/jinzhu/gorm"
+	"github.com/pkg/errors"
+)
+
+func NewWebhookRepo(client *db.Client) *webhookRepo {
+	return &webhookRepo{client}
+}
+
+type webhookRepo struct {
+	*db.Client
+}
+
+func (r *webhookRepo) SaveSubscription(ctx context.Context, sub models.WebhookSubscription) error {
+	errs := r.Conn(ctx).Save(&sub).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "saving webhook subscription error")
+	}
+	return nil
+}
+
+func (r *webhookRepo) GetSubscription(ctx context.Context, id string) (models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := r.Conn(ctx).Where("id = ?", id).First(&sub).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return models.WebhookSubscription{}, models.NewErrNotFound("webhook subscription with id = %s is not found", id)
+	}
+	if err != nil {
+		return models.WebhookSubscription{}, errors.Wrap(err, "getting webhook subscription error")
+	}
+	return sub, nil
+}
+
+func (r *webhookRepo) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subs := make([]models.WebhookSubscription, 0)
+	errs := r.Conn(ctx).Order("created_at").Find(&subs).GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting webhook subscriptions error")
+	}
+	return subs, nil
+}
+
+func (r *webhookRepo) GetActiveSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	subs := make([]models.WebhookSubscription, 0)
+	errs := r.Conn(ctx).Where("active = ?", true).Find(&subs).GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting active webhook subscriptions error")
+	}
+	return subs, nil
+}
+
+func (r *webhookRepo) DeleteSubscription(ctx context.Context, id string) error {
+	return r.InTransaction(ctx, func(ctx context.Context) error {
+		err := r.Conn(ctx).Where("subscription_id = ?", id).Delete(models.WebhookDelivery{}).Error
+		if err != nil {
+			return errors.Wrap(err, "deleting webhook deliveries error")
+		}
+
+		db := r.Conn(ctx).Where("id = ?", id).Delete(models.WebhookSubscription{})
+		if db.Error != nil {
+			return errors.Wrap(db.Error, "deleting webhook subscription error")
+		}
+		if db.RowsAffected == 0 {
+			return models.NewErrNotFound("webhook subscription with id = %s is not found", id)
+		}
+		return nil
+	})
+}
+
+// CreateDelivery saves delivery unless the event is already queued for the subscription
+func (r *webhookRepo) CreateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	err := r.Conn(ctx).Set("gorm:insert_option", "ON CONFLICT (subscription_id, event_id) DO NOTHING").
+		Create(&delivery).Error
+	if err != nil {
+		return errors.Wrap(err, "saving webhook delivery error")
+	}
+	return nil
+}
+
+func (r *webhookRepo) GetDelivery(ctx context.Context, id string) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.Conn(ctx).Where("id = ?", id).First(&delivery).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return models.WebhookDelivery{}, models.NewErrNotFound("webhook delivery with id = %s is not found", id)
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, errors.Wrap(err, "getting webhook delivery error")
+	}
+	return delivery, nil
+}
+
+func (r *webhookRepo) GetDeliveries(ctx context.Context, status models.WebhookDeliveryStatus, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	query := r.Conn(ctx)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	errs := query.Order("created_at desc").Limit(limit).Find(&deliveries).GetErrors()
+	if len(errs) > 0 {
+		return nil, errors.Wrap(concatErrors(errs...), "getting webhook deliveries error")
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepo) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	deliveries := make([]models.WebhookDelivery, 0)
+	err := r.InTransaction(ctx, func(ctx context.Context) error {
+		errs := r.Conn(ctx).Set("gorm:query_option", "FOR UPDATE SKIP LOCKED").
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).
+			GetErrors()
+		if len(errs) > 0 {
+			return errors.Wrap(concatErrors(errs...), "locking due webhook deliveries error")
+		}
+
+		ids := make([]uuid.UUID, 0, len(deliveries))
+		for i := range deliveries {
+			deliveries[i].NextAttemptAt = leaseUntil
+			ids = append(ids, deliveries[i].ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		err := r.Conn(ctx).Model(models.WebhookDelivery{}).Where("id IN (?)", ids).
+			Update("next_attempt_at", leaseUntil).Error
+		if err != nil {
+			return errors.Wrap(err, "claiming webhook deliveries error")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *webhookRepo) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	errs := r.Conn(ctx).Save(&delivery).GetErrors()
+	if len(errs) > 0 {
+		return errors.Wrap(concatErrors(errs...), "updating webhook delivery error")
+	}
+	return nil
+}
+
+func concatErrors(errs ...error) error {
+	var e string
+	for _, err := range errs {
+		e += err.Error()
+	}
+	return errors.New(e)
+}
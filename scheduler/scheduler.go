@@ -0,0 +1,79 @@
+package scheduler
+
+import (
+	"context"
+	"hash/crc32"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// jobLockClass is a class of advisory locks which let only one service instance run a job at a time
+const jobLockClass = 7303
+
+// Locker runs fn holding lock (class, key) shared by all service instances, returns false if lock is held by someone else
+type Locker interface {
+	TryLock(ctx context.Context, class, key int32, fn func(ctx context.Context)) (bool, error)
+}
+
+// Job is a function which is run by scheduler, each run receives context with new TransactionID
+type Job func(ctx context.Context)
+
+type Scheduler struct {
+	cron   *cron.Cron
+	locker Locker
+	log    logger.Logger
+}
+
+func New(locker Locker, log logger.Logger) *Scheduler {
+	return &Scheduler{
+		cron:   cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger))),
+		locker: locker,
+		log:    log,
+	}
+}
+
+// AddJob registers job to be run according to cron expression (e.g. "0 1 * * *"),
+// run is skipped if the job is being run by another service instance
+func (s *Scheduler) AddJob(name, spec string, job Job) error {
+	_, err := s.cron.AddFunc(spec, func() {
+		ctx := transactionID.NewIDContext(context.Background())
+		txID := transactionID.FromContext(ctx)
+
+		_, err := s.Run(ctx, name, job)
+		if err != nil {
+			s.log.Errorf(txID, "scheduled job %s lock failed: err=%s", name, err)
+		}
+	})
+	return errors.Wrapf(err, "scheduling job %s with spec %q", name, spec)
+}
+
+// Run runs job out of schedule (e.g. on demand) holding the same lock as its scheduled runs,
+// it returns false without running job if the job is being run by any service instance
+func (s *Scheduler) Run(ctx context.Context, name string, job Job) (bool, error) {
+	key := int32(crc32.ChecksumIEEE([]byte(name)))
+	txID := transactionID.FromContext(ctx)
+
+	locked, err := s.locker.TryLock(ctx, jobLockClass, key, func(ctx context.Context) {
+		s.log.Infof(txID, "job %s started", name)
+		job(ctx)
+		s.log.Infof(txID, "job %s finished", name)
+	})
+	if err == nil && !locked {
+		s.log.Infof(txID, "job %s is skipped, it is run by another instance", name)
+	}
+	return locked, err
+}
+
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops scheduler and waits for running jobs
+func (s *Scheduler) Stop() error {
+	<-s.cron.Stop().Done()
+	return nil
+}
@@ -0,0 +1,50 @@
+package access
+
+import (
+	"context"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+)
+
+type Authorizer interface {
+	// Can returns true if user has permission on the target user,
+	// empty target checks that permission is granted to the user in any scope
+	Can(ctx context.Context, ua models.UserAccess, p models.Permission, targetUserID string) (bool, error)
+}
+
+func NewAuthorizer(org org.OrgUsecase) *authorizer {
+	return &authorizer{org: org}
+}
+
+type authorizer struct {
+	org org.OrgUsecase
+}
+
+func (a *authorizer) Can(ctx context.Context, ua models.UserAccess, p models.Permission, targetUserID string) (bool, error) {
+	scope := ua.Role.Scope(p)
+
+	switch {
+	case scope == models.NoScope:
+		return false, nil
+	case scope == models.AllScope, targetUserID == "", targetUserID == ua.UserID:
+		return true, nil
+	case scope == models.SelfScope:
+		return false, nil
+	}
+
+	chain, err := a.org.GetChain(ctx, targetUserID)
+	if err != nil {
+		if models.IsErrNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, manager := range chain {
+		if manager.ID.String() == ua.UserID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
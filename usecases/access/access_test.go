@@ -0,0 +1,77 @@
+package access
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+
+	"github.com/google/uuid"
+)
+
+// orgStub keeps managers of users, chain is resolved by following them up
+type orgStub struct {
+	org.OrgUsecase
+	managers map[string]string
+}
+
+func (o *orgStub) GetChain(_ context.Context, userID string) ([]models.User, error) {
+	if _, ok := o.managers[userID]; !ok {
+		return nil, models.NewErrNotFound("user with id=%s is not found", userID)
+	}
+
+	chain := make([]models.User, 0)
+	for manager := o.managers[userID]; manager != ""; manager = o.managers[manager] {
+		chain = append(chain, models.User{ID: uuid.MustParse(manager)})
+	}
+	return chain, nil
+}
+
+func TestCan(t *testing.T) {
+	var (
+		head     = uuid.New().String()
+		manager  = uuid.New().String()
+		employee = uuid.New().String()
+		outsider = uuid.New().String()
+		a        = NewAuthorizer(&orgStub{managers: map[string]string{
+			head:     "",
+			manager:  head,
+			employee: manager,
+			outsider: "",
+		}})
+	)
+
+	tests := []struct {
+		name     string
+		ua       models.UserAccess
+		p        models.Permission
+		target   string
+		expected bool
+	}{
+		{name: "user on self", ua: models.UserAccess{UserID: employee, Role: models.UserRole}, p: models.ManageVacations, target: employee, expected: true},
+		{name: "user on manager", ua: models.UserAccess{UserID: employee, Role: models.UserRole}, p: models.ManageVacations, target: manager, expected: false},
+		{name: "user without permission", ua: models.UserAccess{UserID: employee, Role: models.UserRole}, p: models.ApproveVacations, target: employee, expected: false},
+		{name: "user any scope", ua: models.UserAccess{UserID: employee, Role: models.UserRole}, p: models.ManageTasks, expected: true},
+		{name: "manager on direct report", ua: models.UserAccess{UserID: manager, Role: models.ManagerRole}, p: models.ApproveVacations, target: employee, expected: true},
+		{name: "manager on indirect report", ua: models.UserAccess{UserID: head, Role: models.ManagerRole}, p: models.ApproveVacations, target: employee, expected: true},
+		{name: "manager on own manager", ua: models.UserAccess{UserID: manager, Role: models.ManagerRole}, p: models.ApproveVacations, target: head, expected: false},
+		{name: "manager outside subtree", ua: models.UserAccess{UserID: manager, Role: models.ManagerRole}, p: models.ApproveVacations, target: outsider, expected: false},
+		{name: "manager on unknown user", ua: models.UserAccess{UserID: manager, Role: models.ManagerRole}, p: models.ApproveVacations, target: uuid.New().String(), expected: false},
+		{name: "hr on everyone", ua: models.UserAccess{UserID: outsider, Role: models.HRRole}, p: models.ApproveVacations, target: employee, expected: true},
+		{name: "hr without permission", ua: models.UserAccess{UserID: outsider, Role: models.HRRole}, p: models.ManageUsers, target: employee, expected: false},
+		{name: "admin", ua: models.UserAccess{UserID: outsider, Role: models.AdminRole}, p: models.ManageUsers, target: employee, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Can(context.Background(), tt.ua, tt.p, tt.target)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbox"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 1000
+	verifyBatch  = 500
+	redacted     = "***"
+)
+
+// sensitiveFields are never written to the log
+var sensitiveFields = map[string]bool{
+	"password": true,
+	"secret":   true,
+}
+
+type AuditUsecase interface {
+	Record(ctx context.Context, entry models.AuditEntry, rec *Record) error
+	// Queue writes entry to the outbox in transaction from ctx without falling back to direct append,
+	// so failed write rolls back the mutation
+	Queue(ctx context.Context, entry models.AuditEntry, rec *Record) error
+	// Handle appends entry from audit outbox event to the chain
+	Handle(ctx context.Context, event models.OutboxEvent) error
+	Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error)
+	Verify(ctx context.Context) (*models.AuditVerification, error)
+}
+
+func NewAuditUsecase(repo repository.AuditRepository, outboxRepo repository.OutboxRepository, tx repository.Transactor) *auditUsecase {
+	return &auditUsecase{
+		repo:       repo,
+		outboxRepo: outboxRepo,
+		tx:         tx,
+	}
+}
+
+type auditUsecase struct {
+	repo       repository.AuditRepository
+	outboxRepo repository.OutboxRepository
+	tx         repository.Transactor
+}
+
+// Record fills entry with collected record and queues it to the outbox, the entry is appended to the chain by Handle.
+// If the outbox is not available the entry is appended directly.
+func (u *auditUsecase) Record(ctx context.Context, entry models.AuditEntry, rec *Record) error {
+	entry, event, err := newEvent(entry, rec)
+	if err != nil {
+		return err
+	}
+	if err = u.outboxRepo.Save(ctx, event); err == nil {
+		return nil
+	}
+
+	if appendErr := u.append(ctx, entry); appendErr != nil {
+		return errors.Wrapf(appendErr, "queueing audit entry: %s", err)
+	}
+	return nil
+}
+
+func (u *auditUsecase) Queue(ctx context.Context, entry models.AuditEntry, rec *Record) error {
+	_, event, err := newEvent(entry, rec)
+	if err != nil {
+		return err
+	}
+	return u.outboxRepo.Save(ctx, event)
+}
+
+func (u *auditUsecase) Handle(ctx context.Context, event models.OutboxEvent) error {
+	var entry models.AuditEntry
+	if err := json.Unmarshal([]byte(event.Payload), &entry); err != nil {
+		return errors.Wrap(err, "unmarshaling audit entry")
+	}
+	return u.append(ctx, entry)
+}
+
+// append links entry to the last entry of the chain, entry which is already in the chain is skipped
+func (u *auditUsecase) append(ctx context.Context, entry models.AuditEntry) error {
+	return u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		if err := u.repo.LockChain(ctx); err != nil {
+			return err
+		}
+
+		exists, err := u.repo.Exists(ctx, entry.ID.String())
+		if err != nil || exists {
+			return err
+		}
+
+		last, err := u.repo.GetLast(ctx)
+		if err != nil {
+			return err
+		}
+		entry.Seq = 0
+		entry.PrevHash = ""
+		if last != nil {
+			entry.PrevHash = last.Hash
+		}
+
+		entry.Hash, err = Hash(entry)
+		if err != nil {
+			return err
+		}
+		return u.repo.Create(ctx, entry)
+	})
+}
+
+// newEvent fills entry with collected record and wraps it into audit outbox event
+func newEvent(entry models.AuditEntry, rec *Record) (models.AuditEntry, models.OutboxEvent, error) {
+	entry, err := prepare(entry, rec)
+	if err != nil {
+		return entry, models.OutboxEvent{}, err
+	}
+
+	event, err := outbox.NewEvent(models.AuditTopic, entry)
+	return entry, event, err
+}
+
+// prepare fills entry with collected record
+func prepare(entry models.AuditEntry, rec *Record) (models.AuditEntry, error) {
+	rec.mu.Lock()
+	before, err := snapshot(rec.Before)
+	if err != nil {
+		rec.mu.Unlock()
+		return entry, err
+	}
+	after, err := snapshot(rec.After)
+	if err != nil {
+		rec.mu.Unlock()
+		return entry, err
+	}
+	entry.ActorID = rec.ActorID
+	if rec.Action != "" {
+		entry.Action = rec.Action
+	}
+	if rec.TargetType != "" {
+		entry.TargetType = rec.TargetType
+	}
+	if rec.TargetID != "" {
+		entry.TargetID = rec.TargetID
+	}
+	rec.mu.Unlock()
+
+	entry.ID = uuid.New()
+	entry.Time = time.Now().UTC().Truncate(time.Microsecond)
+	if entry.Before, err = encode(before); err != nil {
+		return entry, err
+	}
+	if entry.After, err = encode(after); err != nil {
+		return entry, err
+	}
+	if entry.Diff, err = encode(diff(before, after)); err != nil {
+		return entry, err
+	}
+	return entry, nil
+}
+
+func (u *auditUsecase) Search(ctx context.Context, filter models.AuditFilter) ([]models.AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultLimit
+	}
+	if filter.Limit > maxLimit {
+		filter.Limit = maxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return u.repo.Search(ctx, filter)
+}
+
+// Verify walks the whole chain and recalculates hashes of entries
+func (u *auditUsecase) Verify(ctx context.Context) (*models.AuditVerification, error) {
+	var (
+		result   = &models.AuditVerification{Valid: true}
+		prevHash string
+		seq      int64
+	)
+
+	for {
+		entries, err := u.repo.GetAfter(ctx, seq, verifyBatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			hash, err := Hash(entry)
+			if err != nil {
+				return nil, err
+			}
+
+			if entry.PrevHash != prevHash || entry.Hash != hash {
+				result.Valid = false
+				result.BrokenAt = entry.Seq
+				return result, nil
+			}
+
+			result.Checked++
+			prevHash = entry.Hash
+			seq = entry.Seq
+		}
+
+		if len(entries) < verifyBatch {
+			return result, nil
+		}
+	}
+}
+
+// hashedEntry is a stable representation of entry used for hashing, sequence number is not covered
+// since it is assigned by database
+type hashedEntry struct {
+	ID            uuid.UUID `json:"id"`
+	Time          string    `json:"time"`
+	ActorID       string    `json:"actorID"`
+	Action        string    `json:"action"`
+	TargetType    string    `json:"targetType"`
+	TargetID      string    `json:"targetID"`
+	Before        string    `json:"before"`
+	After         string    `json:"after"`
+	Diff          string    `json:"diff"`
+	StatusCode    int       `json:"statusCode"`
+	TransactionID string    `json:"transactionID"`
+	SourceIP      string    `json:"sourceIP"`
+	PrevHash      string    `json:"prevHash"`
+}
+
+// Hash returns hex encoded SHA-256 of entry fields and hash of previous entry
+func Hash(entry models.AuditEntry) (string, error) {
+	body, err := json.Marshal(hashedEntry{
+		ID:            entry.ID,
+		Time:          entry.Time.UTC().Format(time.RFC3339Nano),
+		ActorID:       entry.ActorID,
+		Action:        entry.Action,
+		TargetType:    entry.TargetType,
+		TargetID:      entry.TargetID,
+		Before:        entry.Before,
+		After:         entry.After,
+		Diff:          entry.Diff,
+		StatusCode:    entry.StatusCode,
+		TransactionID: entry.TransactionID,
+		SourceIP:      entry.SourceIP,
+		PrevHash:      entry.PrevHash,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling audit entry")
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// snapshot converts state into generic JSON value with sensitive fields redacted
+func snapshot(state interface{}) (interface{}, error) {
+	if state == nil {
+		return nil, nil
+	}
+	if v := reflect.ValueOf(state); v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil, nil
+	}
+
+	body, err := json.Marshal(state)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling audit state")
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling audit state")
+	}
+
+	if fields, ok := value.(map[string]interface{}); ok {
+		for field := range fields {
+			if sensitiveFields[field] {
+				fields[field] = redacted
+			}
+		}
+	}
+	return value, nil
+}
+
+// diff returns changed top-level fields of objects
+func diff(before, after interface{}) map[string]models.FieldChange {
+	if before == nil && after == nil {
+		return nil
+	}
+
+	beforeFields, _ := before.(map[string]interface{})
+	afterFields, _ := after.(map[string]interface{})
+	changes := make(map[string]models.FieldChange)
+
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = models.FieldChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = models.FieldChange{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func encode(value interface{}) (string, error) {
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Map && reflect.ValueOf(value).IsNil() {
+		return "", nil
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.Wrap(err, "marshaling audit value")
+	}
+	return string(body), nil
+}
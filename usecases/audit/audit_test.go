@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+)
+
+type txStub struct{}
+
+func (txStub) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (txStub) InSavepoint(ctx context.Context, _ string, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// auditRepoStub keeps the chain in memory
+type auditRepoStub struct {
+	repository.AuditRepository
+	entries []models.AuditEntry
+}
+
+func (r *auditRepoStub) LockChain(context.Context) error { return nil }
+
+func (r *auditRepoStub) Exists(_ context.Context, id string) (bool, error) {
+	for _, entry := range r.entries {
+		if entry.ID.String() == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *auditRepoStub) GetLast(context.Context) (*models.AuditEntry, error) {
+	if len(r.entries) == 0 {
+		return nil, nil
+	}
+	last := r.entries[len(r.entries)-1]
+	return &last, nil
+}
+
+func (r *auditRepoStub) Create(_ context.Context, entry models.AuditEntry) error {
+	entry.Seq = int64(len(r.entries) + 1)
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *auditRepoStub) GetAfter(_ context.Context, seq int64, limit int) ([]models.AuditEntry, error) {
+	entries := make([]models.AuditEntry, 0)
+	for _, entry := range r.entries {
+		if entry.Seq > seq && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+type outboxRepoStub struct {
+	repository.OutboxRepository
+	events []models.OutboxEvent
+	err    error
+}
+
+func (r *outboxRepoStub) Save(_ context.Context, events ...models.OutboxEvent) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func TestRecordAppendsChainThroughOutbox(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		repo   = &auditRepoStub{}
+		queue  = &outboxRepoStub{}
+		u      = NewAuditUsecase(repo, queue, txStub{})
+		_, rec = WithRecord(ctx, nil)
+	)
+
+	rec.ActorID = "admin"
+	rec.Before = map[string]interface{}{"name": "old", "password": "secret"}
+	rec.After = map[string]interface{}{"name": "new", "password": "secret"}
+
+	for i := 0; i < 2; i++ {
+		if err := u.Record(ctx, models.AuditEntry{Action: "PUT /staff/user/{id}"}, rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(repo.entries) != 0 {
+		t.Fatalf("entries are appended before relay: %d", len(repo.entries))
+	}
+	if len(queue.events) != 2 || queue.events[0].Topic != models.AuditTopic {
+		t.Fatalf("unexpected queued events: %+v", queue.events)
+	}
+
+	for _, event := range queue.events {
+		if err := u.Handle(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// redelivered event must not be appended twice
+	if err := u.Handle(ctx, queue.events[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(repo.entries))
+	}
+	first, second := repo.entries[0], repo.entries[1]
+	if first.PrevHash != "" || second.PrevHash != first.Hash {
+		t.Fatalf("entries are not chained: %q -> %q", first.Hash, second.PrevHash)
+	}
+	if first.ActorID != "admin" || first.Diff != `{"name":{"before":"old","after":"new"}}` {
+		t.Fatalf("unexpected entry: %+v", first)
+	}
+	if first.Before != `{"name":"old","password":"***"}` {
+		t.Fatalf("sensitive field is not redacted: %s", first.Before)
+	}
+
+	result, err := u.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Checked != 2 {
+		t.Fatalf("unexpected verification of valid chain: %+v", result)
+	}
+
+	repo.entries[0].After = `{"name":"forged"}`
+	result, err = u.Verify(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.BrokenAt != 1 {
+		t.Fatalf("modified entry is not detected: %+v", result)
+	}
+}
+
+func TestRecordAppendsDirectlyWhenOutboxFails(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		repo   = &auditRepoStub{}
+		u      = NewAuditUsecase(repo, &outboxRepoStub{err: errors.New("outbox is down")}, txStub{})
+		_, rec = WithRecord(ctx, nil)
+	)
+
+	if err := u.Record(ctx, models.AuditEntry{Action: "DELETE /staff/task/{id}"}, rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.entries) != 1 || repo.entries[0].Hash == "" {
+		t.Fatalf("entry is not appended: %+v", repo.entries)
+	}
+}
+
+func TestQueueWritesEntryWithMutation(t *testing.T) {
+	var (
+		repo     = &auditRepoStub{}
+		queue    = &outboxRepoStub{}
+		u        = NewAuditUsecase(repo, queue, txStub{})
+		entry    = models.AuditEntry{Action: "PUT /staff/vacations/{id}/cancel"}
+		ctx, rec = WithRecord(context.Background(), func(ctx context.Context, rec *Record) error {
+			return u.Queue(ctx, entry, rec)
+		})
+	)
+
+	if err := Queue(context.Background()); err != nil {
+		t.Fatalf("request which is not audited should be skipped: %s", err)
+	}
+
+	SetChange(ctx, "vacation", "1", nil, map[string]interface{}{"status": "Canceled"})
+	if err := Queue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Queued() || len(queue.events) != 1 || queue.events[0].Topic != models.AuditTopic {
+		t.Fatalf("entry is not queued: queued=%t events=%+v", rec.Queued(), queue.events)
+	}
+
+	queue.err = errors.New("transaction is aborted")
+	ctx, rec = WithRecord(context.Background(), func(ctx context.Context, rec *Record) error {
+		return u.Queue(ctx, entry, rec)
+	})
+	if err := Queue(ctx); err == nil {
+		t.Fatal("failed queueing should roll back the mutation")
+	}
+	if rec.Queued() || len(repo.entries) != 0 {
+		t.Fatalf("failed entry should be left to middleware: queued=%t entries=%d", rec.Queued(), len(repo.entries))
+	}
+}
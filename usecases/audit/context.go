@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+type recordKey struct{}
+
+// QueueFunc writes entry of the record to the outbox using transaction from ctx
+type QueueFunc func(ctx context.Context, rec *Record) error
+
+// Record collects details of audited request, handlers and usecases fill it while request is processed
+type Record struct {
+	mu         sync.Mutex
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	Before     interface{}
+	After      interface{}
+	queue      QueueFunc
+	queued     bool
+}
+
+// WithRecord puts empty record into context, queue is used by Queue to write entry together with the mutation
+func WithRecord(ctx context.Context, queue QueueFunc) (context.Context, *Record) {
+	rec := &Record{queue: queue}
+	return context.WithValue(ctx, recordKey{}, rec), rec
+}
+
+// Queued reports whether entry of the record is already written together with the mutation
+func (rec *Record) Queued() bool {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.queued
+}
+
+func fromContext(ctx context.Context) *Record {
+	rec, _ := ctx.Value(recordKey{}).(*Record)
+	return rec
+}
+
+// SetActor sets who performs the request, does nothing when request is not audited
+func SetActor(ctx context.Context, actorID string) {
+	if rec := fromContext(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.ActorID = actorID
+		rec.mu.Unlock()
+	}
+}
+
+// SetAction overrides action which is derived from route by default
+func SetAction(ctx context.Context, action string) {
+	if rec := fromContext(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.Action = action
+		rec.mu.Unlock()
+	}
+}
+
+// SetChange sets mutated entity and its state before and after mutation, nil state means entity did not exist
+func SetChange(ctx context.Context, targetType, targetID string, before, after interface{}) {
+	if rec := fromContext(ctx); rec != nil {
+		rec.mu.Lock()
+		rec.TargetType = targetType
+		rec.TargetID = targetID
+		rec.Before = before
+		rec.After = after
+		rec.mu.Unlock()
+	}
+}
+
+// Queue writes entry of the request to the outbox in transaction from ctx, so the entry is committed or rolled back
+// together with the mutation. It is called as the last step of the transaction after SetChange,
+// does nothing when request is not audited.
+func Queue(ctx context.Context) error {
+	rec := fromContext(ctx)
+	if rec == nil || rec.queue == nil {
+		return nil
+	}
+
+	if err := rec.queue(ctx, rec); err != nil {
+		return err
+	}
+
+	rec.mu.Lock()
+	rec.queued = true
+	rec.mu.Unlock()
+	return nil
+}
@@ -21,6 +21,11 @@ type Authentication interface {
 	GetUserAccess(ctx context.Context, token string) (ua *models.UserAccess, isTokenExpired bool, err error)
 	RefreshToken(ctx context.Context, oldToken string) (aout *models.AuthOutput, err error)
 	UpdateUserRole(ctx context.Context, email string, role models.Role) error
+	// DisableUser prevents user from signing in and revokes issued tokens
+	DisableUser(ctx context.Context, email string) error
+	EnableUser(ctx context.Context, email string) error
+	// GetUserID returns id attribute of the user registered with the email
+	GetUserID(ctx context.Context, email string) (string, error)
 }
 
 func NewAuthUsecase(cognito *awservices.CognitoProvider, log logger.Logger) *auth {
@@ -214,3 +219,69 @@ func (a *auth) UpdateUserRole(ctx context.Context, email string, role models.Rol
 	}
 	return nil
 }
+
+func (a *auth) DisableUser(ctx context.Context, email string) error {
+	var (
+		txID = transactionID.FromContext(ctx)
+	)
+
+	_, err := a.cognito.Provider.AdminDisableUser(&cip.AdminDisableUserInput{
+		UserPoolId: aws.String(a.cognito.UserPoolID),
+		Username:   aws.String(email),
+	})
+	if err != nil {
+		a.log.Warnf(txID, "cannot disable user in cognito: err=%s", err)
+		return err
+	}
+
+	_, err = a.cognito.Provider.AdminUserGlobalSignOut(&cip.AdminUserGlobalSignOutInput{
+		UserPoolId: aws.String(a.cognito.UserPoolID),
+		Username:   aws.String(email),
+	})
+	if err != nil {
+		a.log.Warnf(txID, "cannot sign out user in cognito: err=%s", err)
+		return err
+	}
+	return nil
+}
+
+func (a *auth) EnableUser(ctx context.Context, email string) error {
+	var (
+		txID = transactionID.FromContext(ctx)
+	)
+
+	_, err := a.cognito.Provider.AdminEnableUser(&cip.AdminEnableUserInput{
+		UserPoolId: aws.String(a.cognito.UserPoolID),
+		Username:   aws.String(email),
+	})
+	if err != nil {
+		a.log.Warnf(txID, "cannot enable user in cognito: err=%s", err)
+		return err
+	}
+	return nil
+}
+
+func (a *auth) GetUserID(ctx context.Context, email string) (string, error) {
+	var (
+		txID = transactionID.FromContext(ctx)
+	)
+
+	out, err := a.cognito.Provider.AdminGetUser(&cip.AdminGetUserInput{
+		UserPoolId: aws.String(a.cognito.UserPoolID),
+		Username:   aws.String(email),
+	})
+	if err != nil {
+		if _, ok := err.(*cip.UserNotFoundException); ok {
+			return "", models.NewErrNotFound("user with email = %s is not found in cognito", email)
+		}
+		a.log.Warnf(txID, "cannot get user from cognito: err=%s", err)
+		return "", err
+	}
+
+	for _, attribute := range out.UserAttributes {
+		if *attribute.Name == models.IDAttribute {
+			return *attribute.Value, nil
+		}
+	}
+	return "", errors.Errorf("user with email = %s has no id attribute", email)
+}
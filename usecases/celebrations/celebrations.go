@@ -0,0 +1,214 @@
+package celebrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbox"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
+
+	"github.com/google/uuid"
+)
+
+// announcementNamespace makes ids of announcements stable, so repeated job run does not announce the same celebration twice
+var announcementNamespace = uuid.MustParse("0b6f4f2e-5a8c-4d55-9a4c-2f7d3c1e8b90")
+
+type CelebrationsUsecase interface {
+	// Upcoming returns birthdays and work anniversaries of the next days starting from today ordered by date,
+	// celebrations which dates current user is not allowed to see are skipped
+	Upcoming(ctx context.Context, days int) ([]models.Celebration, error)
+	// Announce notifies teammates about today celebrations, celebrations are announced only if their dates are visible to team
+	Announce(ctx context.Context)
+}
+
+func NewCelebrationsUsecase(userRepo repository.UserRepository, outboxRepo repository.OutboxRepository, privacy privacy.PrivacyUsecase, log logger.Logger) *celebrationsUsecase {
+	return &celebrationsUsecase{
+		userRepo:   userRepo,
+		outboxRepo: outboxRepo,
+		privacy:    privacy,
+		log:        log,
+	}
+}
+
+type celebrationsUsecase struct {
+	userRepo   repository.UserRepository
+	outboxRepo repository.OutboxRepository
+	privacy    privacy.PrivacyUsecase
+	log        logger.Logger
+}
+
+func (u *celebrationsUsecase) Upcoming(ctx context.Context, days int) ([]models.Celebration, error) {
+	users, err := u.activeUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u.privacy.Apply(ctx, privacy.Users(users)...)
+
+	var (
+		from         = today()
+		to           = from.AddDate(0, 0, days)
+		celebrations = make([]models.Celebration, 0)
+	)
+
+	for _, user := range users {
+		for _, c := range celebrationsOf(user, from) {
+			if date, _ := time.Parse(models.DateLayout, c.Date); date.Before(to) {
+				celebrations = append(celebrations, c)
+			}
+		}
+	}
+
+	sort.SliceStable(celebrations, func(i, j int) bool {
+		if celebrations[i].Date != celebrations[j].Date {
+			return celebrations[i].Date < celebrations[j].Date
+		}
+		return celebrations[i].UserName < celebrations[j].UserName
+	})
+	return celebrations, nil
+}
+
+func (u *celebrationsUsecase) Announce(ctx context.Context) {
+	var (
+		txID = transactionID.FromContext(ctx)
+		date = today()
+	)
+
+	users, err := u.activeUsers(ctx)
+	if err != nil {
+		u.log.Errorf(txID, "cannot get users to announce celebrations: err=%s", err)
+		return
+	}
+
+	announced := 0
+	for _, user := range users {
+		for _, c := range celebrationsOf(user, date) {
+			if c.Date != date.Format(models.DateLayout) {
+				continue
+			}
+
+			err = u.announce(ctx, user, c, users)
+			if err != nil {
+				u.log.Errorf(txID, "cannot announce %s of user %s: err=%s", c.Type, user.ID, err)
+				continue
+			}
+			announced++
+		}
+	}
+
+	u.log.Infof(txID, "%d celebrations are announced", announced)
+}
+
+// announce publishes recent change about the celebration to every teammate of the user
+func (u *celebrationsUsecase) announce(ctx context.Context, user models.User, c models.Celebration, users []models.User) error {
+	field, changeType, title := models.BirthdayField, models.Birthday, fmt.Sprintf("Happy birthday, %s!", c.UserName)
+	if c.Type == models.AnniversaryCelebration {
+		field, changeType, title = models.HireDateField, models.WorkAnniversary, fmt.Sprintf("%s celebrates %s at the company", c.UserName, yearsText(c.Years))
+	}
+
+	visibility, err := u.privacy.Visibility(ctx, user, field)
+	if err != nil {
+		return err
+	}
+	if !models.TeamVisibility.Includes(visibility) {
+		return nil
+	}
+
+	events := make([]models.OutboxEvent, 0)
+	for _, teammate := range users {
+		if teammate.ID == user.ID || !teammate.IsTeammate(user) {
+			continue
+		}
+
+		change := models.RecentChanges{
+			ID:         uuid.NewSHA1(announcementNamespace, []byte(fmt.Sprintf("%s/%s/%s/%s", c.Type, user.ID, teammate.ID, c.Date))),
+			Title:      title,
+			IncidentID: user.ID,
+			Type:       changeType,
+			UserName:   c.UserName,
+			UserID:     teammate.ID.String(),
+			OwnerID:    user.ID.String(),
+			ChangeTime: time.Now().UTC(),
+		}
+
+		event, err := outbox.NewEvent(models.RecentChangeTopic, change)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+	return u.outboxRepo.Save(ctx, events...)
+}
+
+// activeUsers returns users who are not terminated
+func (u *celebrationsUsecase) activeUsers(ctx context.Context) ([]models.User, error) {
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make([]models.User, 0, len(users))
+	for _, user := range users {
+		if !user.IsTerminated() {
+			active = append(active, user)
+		}
+	}
+	return active, nil
+}
+
+// celebrationsOf returns the next birthday and work anniversary of the user starting from the date,
+// dates which cannot be parsed are ignored
+func celebrationsOf(user models.User, from time.Time) []models.Celebration {
+	var (
+		celebrations []models.Celebration
+		name         = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
+	)
+
+	if birthday, err := models.ParseDate(user.DateOfBirth); user.DateOfBirth != "" && err == nil {
+		celebrations = append(celebrations, models.Celebration{
+			Type:     models.BirthdayCelebration,
+			Date:     models.NextOccurrence(birthday, from).Format(models.DateLayout),
+			UserID:   user.ID.String(),
+			UserName: name,
+			ImageURL: user.ImageURL,
+		})
+	}
+
+	if hired, err := models.ParseDate(user.HireDate); user.HireDate != "" && err == nil {
+		next := models.NextOccurrence(hired, from)
+		if years := next.Year() - hired.Year(); years > 0 {
+			celebrations = append(celebrations, models.Celebration{
+				Type:     models.AnniversaryCelebration,
+				Date:     next.Format(models.DateLayout),
+				UserID:   user.ID.String(),
+				UserName: name,
+				ImageURL: user.ImageURL,
+				Years:    years,
+			})
+		}
+	}
+	return celebrations
+}
+
+func yearsText(years int) string {
+	if years == 1 {
+		return "1 year"
+	}
+	return fmt.Sprintf("%d years", years)
+}
+
+// today returns current date in UTC, dates of birth and hire dates are parsed the same way
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
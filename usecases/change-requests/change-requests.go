@@ -0,0 +1,244 @@
+package changerequests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbox"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+
+	"github.com/google/uuid"
+)
+
+type ChangeRequestsUsecase interface {
+	// Submit creates pending request of the user, user can have only one pending request
+	Submit(ctx context.Context, userID string, req models.ProfileChangeRequestReq) (*models.ProfileChangeRequest, error)
+	// Cancel cancels pending request on behalf of its requester
+	Cancel(ctx context.Context, userID, id string) (*models.ProfileChangeRequest, error)
+	// Search returns requests with their diffs, diffs of pending requests are made against current values
+	Search(ctx context.Context, filter models.ChangeRequestFilter) ([]models.ProfileChangeRequest, error)
+	// Review approves or rejects pending request, approved values are applied to the user and requester is notified
+	Review(ctx context.Context, reviewerID, id string, review models.ChangeRequestReview) (*models.ProfileChangeRequest, error)
+}
+
+func NewChangeRequestsUsecase(requestRepo repository.ChangeRequestRepository, userRepo repository.UserRepository,
+	outboxRepo repository.OutboxRepository, tx repository.Transactor) *changeRequestsUsecase {
+	return &changeRequestsUsecase{
+		requestRepo: requestRepo,
+		userRepo:    userRepo,
+		outboxRepo:  outboxRepo,
+		tx:          tx,
+	}
+}
+
+type changeRequestsUsecase struct {
+	requestRepo repository.ChangeRequestRepository
+	userRepo    repository.UserRepository
+	outboxRepo  repository.OutboxRepository
+	tx          repository.Transactor
+}
+
+func (u *changeRequestsUsecase) Submit(ctx context.Context, userID string, req models.ProfileChangeRequestReq) (*models.ProfileChangeRequest, error) {
+	if req.IsEmpty() {
+		return nil, models.NewErrInvalidData("at least one field should be requested")
+	}
+
+	var request models.ProfileChangeRequest
+	err := u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		// concurrent submits of the user wait for each other so only one of them can find no pending request
+		err := u.requestRepo.LockUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		pending, err := u.requestRepo.Search(ctx, models.ChangeRequestFilter{UserID: userID, Status: models.ChangeRequestPending})
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return models.NewErrInvalidData("change request %s is pending, it should be reviewed or canceled first", pending[0].ID)
+		}
+
+		user, err := u.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		request = models.ProfileChangeRequest{
+			ID:             uuid.New(),
+			UserID:         userID,
+			ProfileChanges: req.ProfileChanges,
+			Previous:       req.Of(user),
+			Reason:         req.Reason,
+			Status:         models.ChangeRequestPending,
+			CreatedAt:      time.Now().UTC(),
+		}
+		request.FillDiff()
+		if len(request.Diff) == 0 {
+			return models.NewErrInvalidData("requested values are the same as current ones")
+		}
+
+		err = u.requestRepo.Save(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		audit.SetAction(ctx, "user.change_request")
+		audit.SetChange(ctx, "change_request", request.ID.String(), nil, request)
+		return audit.Queue(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (u *changeRequestsUsecase) Cancel(ctx context.Context, userID, id string) (*models.ProfileChangeRequest, error) {
+	var request models.ProfileChangeRequest
+	err := u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		request, err = u.requestRepo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if request.UserID != userID {
+			return models.NewErrForbidden("change request %s belongs to another user", id)
+		}
+		if request.Status != models.ChangeRequestPending {
+			return models.NewErrInvalidData("change request %s is %s, only pending request can be canceled", id, request.Status)
+		}
+
+		request.FillDiff()
+		before := request
+		request.Status = models.ChangeRequestCanceled
+		err = u.requestRepo.Save(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		audit.SetAction(ctx, "user.change_request_cancel")
+		audit.SetChange(ctx, "change_request", id, before, request)
+		return audit.Queue(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (u *changeRequestsUsecase) Search(ctx context.Context, filter models.ChangeRequestFilter) ([]models.ProfileChangeRequest, error) {
+	requests, err := u.requestRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]models.User)
+	for i, request := range requests {
+		if request.Status == models.ChangeRequestPending {
+			user, ok := users[request.UserID]
+			if !ok {
+				user, err = u.userRepo.GetUserByID(ctx, request.UserID)
+				if err != nil && !models.IsErrNotFound(err) {
+					return nil, err
+				}
+				ok = err == nil
+				users[request.UserID] = user
+			}
+			// stored values are kept if requester is removed
+			if ok {
+				requests[i].Previous = request.Of(user)
+			}
+		}
+		requests[i].FillDiff()
+	}
+	return requests, nil
+}
+
+func (u *changeRequestsUsecase) Review(ctx context.Context, reviewerID, id string, review models.ChangeRequestReview) (*models.ProfileChangeRequest, error) {
+	if review.Status != models.ChangeRequestApproved && review.Status != models.ChangeRequestRejected {
+		return nil, models.NewErrInvalidData("change request can be only %s or %s", models.ChangeRequestApproved, models.ChangeRequestRejected)
+	}
+
+	reviewer, err := u.userRepo.GetUserByID(ctx, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var request models.ProfileChangeRequest
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		// request is locked so concurrent review waits and finds it already reviewed
+		var err error
+		request, err = u.requestRepo.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if request.Status != models.ChangeRequestPending {
+			return models.NewErrInvalidData("change request %s is already %s", id, request.Status)
+		}
+
+		user, err := u.userRepo.GetUserByID(ctx, request.UserID)
+		if err != nil {
+			return err
+		}
+
+		request.FillDiff()
+		before := request
+		now := time.Now().UTC()
+		request.Previous = request.Of(user)
+		request.Status = review.Status
+		request.ReviewerID = reviewerID
+		request.ReviewComment = review.Comment
+		request.ReviewedAt = &now
+
+		// user is stored in elasticsearch, so it is saved before the request is marked as approved,
+		// the whole document is replaced so that cleared fields are removed as well
+		if review.Status == models.ChangeRequestApproved {
+			err = u.userRepo.Save(ctx, request.Patch().Apply(user))
+			if err != nil {
+				return err
+			}
+		}
+
+		err = u.requestRepo.Save(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		event, err := outbox.NewEvent(models.RecentChangeTopic, models.RecentChanges{
+			ID:            uuid.New(),
+			Title:         fmt.Sprintf("Profile change request %s", strings.ToLower(string(review.Status))),
+			IncidentID:    request.ID,
+			Type:          models.ProfileChangeReview,
+			UserName:      fmt.Sprintf("%s %s", user.FirstName, user.LastName),
+			UserID:        request.UserID,
+			OwnerID:       request.UserID,
+			UpdatedByName: fmt.Sprintf("%s %s", reviewer.FirstName, reviewer.LastName),
+			UpdatedByID:   reviewerID,
+			ChangeTime:    now,
+			Status:        string(review.Status),
+			Comment:       review.Comment,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = u.outboxRepo.Save(ctx, event)
+		if err != nil {
+			return err
+		}
+
+		request.FillDiff()
+		audit.SetAction(ctx, "user.change_request_review")
+		audit.SetChange(ctx, "change_request", id, before, request)
+		return audit.Queue(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
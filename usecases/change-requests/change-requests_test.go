@@ -0,0 +1,188 @@
+package changerequests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+)
+
+type txStub struct{}
+
+func (txStub) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (txStub) InSavepoint(ctx context.Context, _ string, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type requestRepoStub struct {
+	repository.ChangeRequestRepository
+	requests map[string]models.ProfileChangeRequest
+	locked   []string
+}
+
+func (r *requestRepoStub) GetForUpdate(_ context.Context, id string) (models.ProfileChangeRequest, error) {
+	r.locked = append(r.locked, id)
+	req, ok := r.requests[id]
+	if !ok {
+		return req, models.NewErrNotFound("change request with id = %s is not found", id)
+	}
+	return req, nil
+}
+
+func (r *requestRepoStub) LockUser(_ context.Context, userID string) error {
+	r.locked = append(r.locked, userID)
+	return nil
+}
+
+func (r *requestRepoStub) Search(_ context.Context, filter models.ChangeRequestFilter) ([]models.ProfileChangeRequest, error) {
+	requests := make([]models.ProfileChangeRequest, 0)
+	for _, req := range r.requests {
+		if req.UserID == filter.UserID && req.Status == filter.Status {
+			requests = append(requests, req)
+		}
+	}
+	return requests, nil
+}
+
+func (r *requestRepoStub) Save(_ context.Context, req models.ProfileChangeRequest) error {
+	r.requests[req.ID.String()] = req
+	return nil
+}
+
+type userRepoStub struct {
+	repository.UserRepository
+	users    map[string]models.User
+	saved    int
+	conflict error
+	versions []*models.DocVersion
+}
+
+func (r *userRepoStub) GetUserByID(_ context.Context, id string) (models.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.NewErrNotFound("user with id=%s is not found", id)
+	}
+	return u, nil
+}
+
+func (r *userRepoStub) Save(_ context.Context, u models.User) error {
+	r.versions = append(r.versions, u.Version)
+	if r.conflict != nil {
+		return r.conflict
+	}
+	r.saved++
+	r.users[u.ID.String()] = u
+	return nil
+}
+
+type outboxRepoStub struct {
+	repository.OutboxRepository
+	events []models.OutboxEvent
+}
+
+func (r *outboxRepoStub) Save(_ context.Context, events ...models.OutboxEvent) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func TestReviewAppliesRequestOnce(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		position = "Lead"
+		user     = models.User{ID: uuid.New(), FirstName: "Ann", Position: "Developer", MobilePhone: "+380501234567"}
+		reviewer = models.User{ID: uuid.New(), Role: models.AdminRole}
+		users    = &userRepoStub{users: map[string]models.User{user.ID.String(): user, reviewer.ID.String(): reviewer}}
+		requests = &requestRepoStub{requests: make(map[string]models.ProfileChangeRequest)}
+		queue    = &outboxRepoStub{}
+		u        = NewChangeRequestsUsecase(requests, users, queue, txStub{})
+	)
+
+	submitted, err := u.Submit(ctx, user.ID.String(), models.ProfileChangeRequestReq{ProfileChanges: models.ProfileChanges{Position: &position}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = u.Submit(ctx, user.ID.String(), models.ProfileChangeRequestReq{ProfileChanges: models.ProfileChanges{Position: &position}})
+	if !models.IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data for the second pending request, got %v", err)
+	}
+
+	id := submitted.ID.String()
+	reviewed, err := u.Review(ctx, reviewer.ID.String(), id, models.ChangeRequestReview{Status: models.ChangeRequestApproved})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reviewed.Status != models.ChangeRequestApproved || reviewed.ReviewerID != reviewer.ID.String() {
+		t.Fatalf("unexpected reviewed request: %+v", reviewed)
+	}
+
+	stored := users.users[user.ID.String()]
+	if stored.Position != position || stored.MobilePhone != user.MobilePhone {
+		t.Fatalf("request is not applied to the whole user: %+v", stored)
+	}
+
+	_, err = u.Review(ctx, reviewer.ID.String(), id, models.ChangeRequestReview{Status: models.ChangeRequestRejected})
+	if !models.IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data for reviewed request, got %v", err)
+	}
+	if users.saved != 1 || len(queue.events) != 1 {
+		t.Fatalf("request is applied %d times with %d notifications", users.saved, len(queue.events))
+	}
+
+	expectedLocks := []string{user.ID.String(), user.ID.String(), id, id}
+	if len(requests.locked) != len(expectedLocks) {
+		t.Fatalf("expected locks %v, got %v", expectedLocks, requests.locked)
+	}
+	for i := range expectedLocks {
+		if requests.locked[i] != expectedLocks[i] {
+			t.Fatalf("expected locks %v, got %v", expectedLocks, requests.locked)
+		}
+	}
+}
+
+func TestReviewKeepsRequestPendingOnConflict(t *testing.T) {
+	var (
+		ctx      = context.Background()
+		position = "Lead"
+		version  = &models.DocVersion{SeqNo: 3, PrimaryTerm: 1}
+		user     = models.User{ID: uuid.New(), FirstName: "Ann", Position: "Developer", Version: version}
+		reviewer = models.User{ID: uuid.New(), Role: models.AdminRole}
+		users    = &userRepoStub{users: map[string]models.User{user.ID.String(): user, reviewer.ID.String(): reviewer}}
+		requests = &requestRepoStub{requests: make(map[string]models.ProfileChangeRequest)}
+		queue    = &outboxRepoStub{}
+		u        = NewChangeRequestsUsecase(requests, users, queue, txStub{})
+	)
+
+	submitted, err := u.Submit(ctx, user.ID.String(), models.ProfileChangeRequestReq{ProfileChanges: models.ProfileChanges{Position: &position}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := submitted.ID.String()
+	users.conflict = models.NewErrConflict("user with id = %s was changed concurrently", user.ID)
+	_, err = u.Review(ctx, reviewer.ID.String(), id, models.ChangeRequestReview{Status: models.ChangeRequestApproved})
+	if !models.IsErrConflict(err) {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if requests.requests[id].Status != models.ChangeRequestPending || len(queue.events) != 0 {
+		t.Fatalf("request is reviewed despite conflict: %+v", requests.requests[id])
+	}
+
+	users.conflict = nil
+	_, err = u.Review(ctx, reviewer.ID.String(), id, models.ChangeRequestReview{Status: models.ChangeRequestApproved})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, got := range users.versions {
+		if got == nil || *got != *version {
+			t.Fatalf("expected user to be saved at version %+v it was read at, got %+v", *version, got)
+		}
+	}
+}
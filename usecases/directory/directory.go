@@ -0,0 +1,226 @@
+package directory
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	jsonvalidator "github.com/Dimitriy14/staff-manager/json-validator"
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/auth"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
+
+	"github.com/google/uuid"
+)
+
+const (
+	emailColumn = "email"
+
+	// formulaChars start values which spreadsheets evaluate as formulas
+	formulaChars = "=+-@\t\r"
+	// formulaEscape makes spreadsheets treat the value as text
+	formulaEscape = "'"
+)
+
+type DirectoryUsecase interface {
+	// Import creates users from rows, users are keyed by email so rows of existing users are skipped and import can be repeated.
+	// Nothing is created in dry run, report shows what would be done.
+	Import(ctx context.Context, rows []models.ImportRow, dryRun bool) (*models.ImportReport, error)
+	// Export writes CSV with the columns of all users, default columns are used if none are passed.
+	// Values which look like formulas are prefixed with ' so spreadsheets do not evaluate them.
+	Export(ctx context.Context, w io.Writer, columns []string) error
+}
+
+// NewDirectoryUsecase creates directory usecase, exported fields are not hidden if privacy is nil
+func NewDirectoryUsecase(userRepo repository.UserRepository, auth auth.Authentication, privacy privacy.PrivacyUsecase, log logger.Logger) *directoryUsecase {
+	return &directoryUsecase{
+		userRepo: userRepo,
+		auth:     auth,
+		privacy:  privacy,
+		log:      log,
+	}
+}
+
+type directoryUsecase struct {
+	userRepo repository.UserRepository
+	auth     auth.Authentication
+	privacy  privacy.PrivacyUsecase
+	log      logger.Logger
+}
+
+func (u *directoryUsecase) Import(ctx context.Context, rows []models.ImportRow, dryRun bool) (*models.ImportReport, error) {
+	existing, err := u.usersByEmail(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		txID   = transactionID.FromContext(ctx)
+		report = &models.ImportReport{DryRun: dryRun, Rows: make([]models.ImportRowResult, 0, len(rows))}
+		// seen keeps lines of emails met in the file to reject duplicates
+		seen = make(map[string]int, len(rows))
+	)
+
+	for _, row := range rows {
+		result := models.ImportRowResult{Row: row.Line, Email: row.Values[emailColumn]}
+		user, errs := parseRow(row)
+
+		switch key := emailKey(user.Email); {
+		case len(errs) > 0:
+			// invalid row is reported below
+		case seen[key] != 0:
+			errs = append(errs, "email is duplicated on line "+strconv.Itoa(seen[key]))
+		case existing[key] != nil:
+			result.Action = models.SkipImportAction
+			result.UserID = existing[key].ID.String()
+		default:
+			seen[key] = row.Line
+			result.Action = models.CreateImportAction
+			if !dryRun {
+				err = u.create(ctx, &user)
+				if err != nil {
+					u.log.Warnf(txID, "cannot import user from line %d: err=%s", row.Line, err)
+					errs = append(errs, err.Error())
+					break
+				}
+				result.UserID = user.ID.String()
+			}
+		}
+
+		if len(errs) > 0 {
+			result.Action = models.ErrorImportAction
+			result.Errors = errs
+		}
+
+		switch result.Action {
+		case models.CreateImportAction:
+			report.Created++
+		case models.SkipImportAction:
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	return report, nil
+}
+
+// create registers user in cognito and saves the user, identity created by previous interrupted import is reused
+func (u *directoryUsecase) create(ctx context.Context, user *models.User) error {
+	id, err := u.auth.GetUserID(ctx, user.Email)
+	switch {
+	case err == nil:
+		user.ID, err = uuid.Parse(id)
+		if err != nil {
+			return err
+		}
+	case models.IsErrNotFound(err):
+		user.ID = uuid.New()
+		err = u.auth.SignUp(ctx, *user)
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	user.Status = models.Invited
+	return u.userRepo.Save(ctx, *user)
+}
+
+func (u *directoryUsecase) Export(ctx context.Context, w io.Writer, columns []string) error {
+	if len(columns) == 0 {
+		columns = models.ExportColumns
+	}
+
+	for _, column := range columns {
+		if _, ok := (models.User{}).ExportValue(column); !ok {
+			return models.NewErrInvalidData("unknown column %s, available columns are %s", column, strings.Join(models.ExportColumns, ", "))
+		}
+	}
+
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	if u.privacy != nil {
+		u.privacy.Apply(ctx, privacy.Users(users)...)
+	}
+
+	cw := csv.NewWriter(w)
+	_ = cw.Write(columns)
+	for _, user := range users {
+		record := make([]string, 0, len(columns))
+		for _, column := range columns {
+			value, _ := user.ExportValue(column)
+			record = append(record, escapeFormula(value))
+		}
+		_ = cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// usersByEmail keeps terminated users as well because their emails are still taken in auth provider
+func (u *directoryUsecase) usersByEmail(ctx context.Context) (map[string]*models.User, error) {
+	users, err := u.userRepo.GetAllWithTerminated(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byEmail := make(map[string]*models.User, len(users))
+	for i := range users {
+		byEmail[emailKey(users[i].Email)] = &users[i]
+	}
+	return byEmail, nil
+}
+
+// parseRow validates the row as a user registration, validation errors are returned one per violation
+func parseRow(row models.ImportRow) (models.User, []string) {
+	var user models.User
+
+	data, err := json.Marshal(row.Values)
+	if err != nil {
+		return user, []string{err.Error()}
+	}
+
+	err = jsonvalidator.Validate(schemas.UserRegistration, data)
+	if err != nil {
+		return user, strings.Split(err.Error(), "; ")
+	}
+
+	err = json.Unmarshal(data, &user)
+	if err != nil {
+		return user, []string{err.Error()}
+	}
+	return user, nil
+}
+
+// emailKey is used to match emails, cognito usernames are case insensitive
+func emailKey(email string) string {
+	return strings.ToLower(email)
+}
+
+// escapeFormula prefixes value which spreadsheet would evaluate as formula so it is shown as text
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], formulaChars) {
+		return formulaEscape + value
+	}
+	return value
+}
+
+// unescapeFormula removes prefix added by escapeFormula so exported file can be imported back
+func unescapeFormula(value string) string {
+	if len(value) > 1 && strings.HasPrefix(value, formulaEscape) && strings.ContainsAny(value[1:2], formulaChars) {
+		return value[1:]
+	}
+	return value
+}
@@ -0,0 +1,49 @@
+package directory
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+)
+
+type userRepoStub struct {
+	repository.UserRepository
+	users []models.User
+}
+
+func (r *userRepoStub) GetAll(context.Context) ([]models.User, error) {
+	return r.users, nil
+}
+
+func TestExportEscapesFormulas(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		u   = NewDirectoryUsecase(&userRepoStub{users: []models.User{
+			{FirstName: "=HYPERLINK(\"http://evil\")", LastName: "Lee", Position: "-2+3", MobilePhone: "+380501234567"},
+			{FirstName: "@SUM(A1)", LastName: "O'Neil", Position: "Developer"},
+		}}, nil, nil, nil)
+	)
+
+	err := u.Export(context.Background(), &buf, []string{"firstName", "lastName", "position", "mobilePhone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "firstName,lastName,position,mobilePhone\n" +
+		"\"'=HYPERLINK(\"\"http://evil\"\")\",Lee,'-2+3,'+380501234567\n" +
+		"'@SUM(A1),O'Neil,Developer,\n"
+	if buf.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	rows, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows[0].Values["firstName"] != "=HYPERLINK(\"http://evil\")" || rows[0].Values["mobilePhone"] != "+380501234567" {
+		t.Fatalf("exported values are not read back: %+v", rows[0].Values)
+	}
+}
@@ -0,0 +1,272 @@
+package directory
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	workbookPath      = "xl/workbook.xml"
+	workbookRelsPath  = "xl/_rels/workbook.xml.rels"
+	sharedStringsPath = "xl/sharedStrings.xml"
+
+	sharedStringCell = "s"
+	inlineStringCell = "inlineStr"
+)
+
+// ReadCSV reads rows of CSV file, the first record is a header with column names.
+// Rows are numbered by records, so blank lines and line breaks inside quoted values are not counted.
+func ReadCSV(r io.Reader) ([]models.ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, models.NewErrInvalidData("cannot read CSV: %s", err)
+	}
+	return toRows(records, nil)
+}
+
+// ReadXLSX reads rows of the first sheet of XLSX workbook, the first row is a header with column names
+func ReadXLSX(data []byte) ([]models.ImportRow, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, models.NewErrInvalidData("cannot read XLSX: %s", err)
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared sharedStrings
+	if _, ok := files[sharedStringsPath]; ok {
+		err = decodeXML(files, sharedStringsPath, &shared)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet worksheet
+	err = decodeXML(files, sheetPath, &sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		records = make([][]string, 0, len(sheet.Rows))
+		lines   = make([]int, 0, len(sheet.Rows))
+	)
+	for i, row := range sheet.Rows {
+		line := row.Number
+		if line == 0 {
+			line = i + 1
+		}
+
+		record := make([]string, 0, len(row.Cells))
+		for j, c := range row.Cells {
+			column := columnIndex(c.Ref)
+			if column < 0 {
+				column = j
+			}
+			for len(record) <= column {
+				record = append(record, "")
+			}
+
+			record[column], err = c.value(shared)
+			if err != nil {
+				return nil, models.NewErrInvalidData("cannot read cell %s: %s", c.Ref, err)
+			}
+		}
+		records = append(records, record)
+		lines = append(lines, line)
+	}
+	return toRows(records, lines)
+}
+
+// toRows maps records to header columns, empty records are skipped, line numbers are taken from lines if they are passed.
+// Values escaped by export are unescaped.
+func toRows(records [][]string, lines []int) ([]models.ImportRow, error) {
+	if len(records) == 0 {
+		return nil, models.NewErrInvalidData("file is empty")
+	}
+
+	header := make([]string, 0, len(records[0]))
+	for _, column := range records[0] {
+		header = append(header, strings.TrimSpace(column))
+	}
+
+	rows := make([]models.ImportRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		line := i + 2
+		if lines != nil {
+			line = lines[i+1]
+		}
+
+		row := models.ImportRow{Line: line, Values: make(map[string]string, len(header))}
+		for j, value := range record {
+			value = unescapeFormula(strings.TrimSpace(value))
+			if value == "" {
+				continue
+			}
+
+			if j >= len(header) || header[j] == "" {
+				return nil, models.NewErrInvalidData("line %d has a value in column %d without header", line, j+1)
+			}
+			row.Values[header[j]] = value
+		}
+
+		if len(row.Values) > 0 {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+type workbook struct {
+	Sheets []struct {
+		RelationID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type relationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type sharedStrings struct {
+	Items []richText `xml:"si"`
+}
+
+// richText is either a plain text or runs of formatted text
+type richText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t richText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+
+	var sb strings.Builder
+	for _, run := range t.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type worksheet struct {
+	Rows []struct {
+		Number int    `xml:"r,attr"`
+		Cells  []cell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type cell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Value  string   `xml:"v"`
+	Inline richText `xml:"is"`
+}
+
+func (c cell) value(shared sharedStrings) (string, error) {
+	switch c.Type {
+	case sharedStringCell:
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(shared.Items) {
+			return "", errors.Errorf("invalid shared string index %q", c.Value)
+		}
+		return shared.Items[i].String(), nil
+	case inlineStringCell:
+		return c.Inline.String(), nil
+	}
+	return c.Value, nil
+}
+
+// firstSheetPath resolves path of the first sheet through workbook relationships
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var wb workbook
+	err := decodeXML(files, workbookPath, &wb)
+	if err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", models.NewErrInvalidData("workbook has no sheets")
+	}
+
+	var rels relationships
+	err = decodeXML(files, workbookRelsPath, &rels)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].RelationID {
+			continue
+		}
+
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join(path.Dir(workbookPath), rel.Target), nil
+	}
+	return "", models.NewErrInvalidData("first sheet of workbook is not found")
+}
+
+func decodeXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return models.NewErrInvalidData("XLSX has no %s", name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return models.NewErrInvalidData("cannot open %s: %s", name, err)
+	}
+	defer rc.Close()
+
+	content, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return models.NewErrInvalidData("cannot read %s: %s", name, err)
+	}
+
+	err = xml.Unmarshal(content, v)
+	if err != nil {
+		return models.NewErrInvalidData("cannot parse %s: %s", name, err)
+	}
+	return nil
+}
+
+// columnIndex converts letters of cell reference like "AB12" to zero based column index, it is -1 if reference has no letters
+func columnIndex(ref string) int {
+	index := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+	}
+	return index - 1
+}
@@ -0,0 +1,138 @@
+package directory
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+)
+
+func TestReadCSV(t *testing.T) {
+	data := "email, firstName ,lastName\n" +
+		"ann@example.com,Ann,\"Lee\nJr\"\n" +
+		"\n" +
+		" , , \n" +
+		"bob@example.com,Bob,'+Ray\n"
+
+	rows, err := ReadCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []models.ImportRow{
+		{Line: 2, Values: map[string]string{"email": "ann@example.com", "firstName": "Ann", "lastName": "Lee\nJr"}},
+		{Line: 4, Values: map[string]string{"email": "bob@example.com", "firstName": "Bob", "lastName": "+Ray"}},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rows)
+	}
+}
+
+func TestReadCSVInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "value without header", data: "email\nann@example.com,Ann\n"},
+		{name: "broken quotes", data: "email\n\"ann@example.com\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadCSV(strings.NewReader(tt.data)); !models.IsErrInvalidData(err) {
+				t.Fatalf("expected invalid data error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := xlsx(t, map[string]string{
+		workbookPath: `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+			xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="People" sheetId="1" r:id="rId2"/></sheets></workbook>`,
+		workbookRelsPath: `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId1" Target="styles.xml"/>
+			<Relationship Id="rId2" Target="worksheets/people.xml"/></Relationships>`,
+		sharedStringsPath: `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>email</t></si><si><t>firstName</t></si><si><r><t>An</t></r><r><t>n</t></r></si></sst>`,
+		"xl/worksheets/people.xml": `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+			<row r="3"><c r="A3" t="inlineStr"><is><t>ann@example.com</t></is></c><c r="C3" t="s"><v>2</v></c></row>
+			<row r="4"><c r="B4"><v>42</v></c></row>
+			</sheetData></worksheet>`,
+	})
+
+	rows, err := ReadXLSX(data)
+	if !models.IsErrInvalidData(err) || !strings.Contains(err.Error(), "line 4") {
+		t.Fatalf("expected error about value without header on line 4, got rows=%+v err=%v", rows, err)
+	}
+
+	data = xlsx(t, map[string]string{
+		workbookPath:      `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet r:id="rId1"/></sheets></workbook>`,
+		workbookRelsPath:  `<Relationships><Relationship Id="rId1" Target="/xl/worksheets/sheet1.xml"/></Relationships>`,
+		sharedStringsPath: `<sst><si><t>email</t></si><si><t>firstName</t></si><si><r><t>An</t></r><r><t>n</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+			<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>
+			<row r="3"><c r="A3" t="inlineStr"><is><t>ann@example.com</t></is></c><c r="C3" t="s"><v>2</v></c></row>
+			</sheetData></worksheet>`,
+	})
+
+	rows, err = ReadXLSX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []models.ImportRow{
+		{Line: 3, Values: map[string]string{"email": "ann@example.com", "firstName": "Ann"}},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, rows)
+	}
+}
+
+func TestReadXLSXInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not zip", data: []byte("email\nann@example.com")},
+		{name: "no workbook", data: xlsx(t, map[string]string{"xl/worksheets/sheet1.xml": `<worksheet/>`})},
+		{name: "invalid shared string", data: xlsx(t, map[string]string{
+			workbookPath:               `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet r:id="rId1"/></sheets></workbook>`,
+			workbookRelsPath:           `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+			"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>7</v></c></row></sheetData></worksheet>`,
+		})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadXLSX(tt.data); !models.IsErrInvalidData(err) {
+				t.Fatalf("expected invalid data error, got %v", err)
+			}
+		})
+	}
+}
+
+// xlsx zips files into workbook
+func xlsx(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,271 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/auth"
+	"github.com/Dimitriy14/staff-manager/usecases/tasks"
+	"github.com/Dimitriy14/staff-manager/usecases/vacation"
+	"github.com/Dimitriy14/staff-manager/util"
+
+	"github.com/google/uuid"
+)
+
+const (
+	userPlaceholder = "{user}"
+
+	terminationComment = "employment is terminated"
+)
+
+type LifecycleUsecase interface {
+	// UpdateStatus moves user between active and on-leave statuses, terminated user becomes able to sign in again when activated
+	UpdateStatus(ctx context.Context, userID string, status models.EmploymentStatus) (*models.User, error)
+	// Terminate disables sign-in of the user, reassigns open tasks, cancels vacations which have not started yet
+	// and removes the user from delegates of vacations of others,
+	// user is marked terminated after all steps succeed so failed termination can be repeated
+	Terminate(ctx context.Context, userID string, req models.TerminationReq) (*models.TerminationResult, error)
+	// Onboard creates configured onboarding tasks for the user, it can be done only once
+	Onboard(ctx context.Context, userID string) ([]models.Task, error)
+}
+
+func NewLifecycleUsecase(
+	userRepo repository.UserRepository,
+	taskRepo repository.TaskRepository,
+	feedTokenRepo repository.FeedTokenRepository,
+	tasks tasks.TaskUsecase,
+	vacations vacation.VacationsUsecase,
+	auth auth.Authentication,
+	onboarding []models.OnboardingTask) *lifecycleUsecase {
+	return &lifecycleUsecase{
+		userRepo:      userRepo,
+		taskRepo:      taskRepo,
+		feedTokenRepo: feedTokenRepo,
+		tasks:         tasks,
+		vacations:     vacations,
+		auth:          auth,
+		onboarding:    onboarding,
+	}
+}
+
+type lifecycleUsecase struct {
+	userRepo      repository.UserRepository
+	taskRepo      repository.TaskRepository
+	feedTokenRepo repository.FeedTokenRepository
+	tasks         tasks.TaskUsecase
+	vacations     vacation.VacationsUsecase
+	auth          auth.Authentication
+	onboarding    []models.OnboardingTask
+}
+
+func (u *lifecycleUsecase) UpdateStatus(ctx context.Context, userID string, status models.EmploymentStatus) (*models.User, error) {
+	if status != models.Active && status != models.OnLeave {
+		return nil, models.NewErrInvalidData("status can be changed only to %s or %s, use termination to terminate user", models.Active, models.OnLeave)
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsTerminated() {
+		if status != models.Active {
+			return nil, models.NewErrInvalidData("terminated user with id = %s can be only reinstated as %s", userID, models.Active)
+		}
+
+		err = u.auth.EnableUser(ctx, user.Email)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	user.Status = status
+	err = u.userRepo.Update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (u *lifecycleUsecase) Terminate(ctx context.Context, userID string, req models.TerminationReq) (*models.TerminationResult, error) {
+	ua := util.GetUserAccessFromCtx(ctx)
+	if userID == ua.UserID {
+		return nil, models.NewErrInvalidData("user cannot terminate themselves")
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsTerminated() {
+		return nil, models.NewErrInvalidData("user with id = %s is already terminated", userID)
+	}
+
+	reassignTo := req.ReassignTo
+	if reassignTo == "" {
+		reassignTo = user.ManagerID
+	}
+
+	if reassignTo != "" {
+		err = u.checkAssignee(ctx, userID, reassignTo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// sign-in is disabled first so the user cannot act while termination is in progress
+	err = u.auth.DisableUser(ctx, user.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	// calendar feed is read with a token instead of sign-in, so it is revoked as well
+	err = u.feedTokenRepo.DeleteForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		now    = time.Now().UTC()
+		result = &models.TerminationResult{
+			ReassignedTo:      reassignTo,
+			ReassignedTasks:   make([]uuid.UUID, 0),
+			CanceledVacations: make([]uuid.UUID, 0),
+		}
+	)
+
+	openTasks, err := u.taskRepo.GetOpenAssignedTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range openTasks {
+		task.AssignedID = reassignTo
+		task.UpdatedByID = ua.UserID
+		task.UpdatedAt = now
+
+		_, err = u.tasks.Update(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		result.ReassignedTasks = append(result.ReassignedTasks, task.ID)
+	}
+
+	canceled, err := u.vacations.CancelUpcoming(ctx, userID, terminationComment)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vacation := range canceled {
+		result.CanceledVacations = append(result.CanceledVacations, vacation.ID)
+	}
+
+	err = u.vacations.ClearDelegate(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// status is set last so termination which failed halfway is finished by repeating it
+	user.Status = models.Terminated
+	user.TerminatedAt = &now
+	err = u.userRepo.Update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	result.User = user
+	return result, nil
+}
+
+// checkAssignee checks that tasks of terminated user can be reassigned to the user
+func (u *lifecycleUsecase) checkAssignee(ctx context.Context, userID, assigneeID string) error {
+	if assigneeID == userID {
+		return models.NewErrInvalidData("tasks cannot be reassigned to terminated user")
+	}
+
+	assignee, err := u.userRepo.GetUserByID(ctx, assigneeID)
+	if err != nil {
+		if models.IsErrNotFound(err) {
+			return models.NewErrInvalidData("user with id = %s to reassign tasks to is not found", assigneeID)
+		}
+		return err
+	}
+
+	if assignee.IsTerminated() {
+		return models.NewErrInvalidData("tasks cannot be reassigned to terminated user with id = %s", assigneeID)
+	}
+	return nil
+}
+
+func (u *lifecycleUsecase) Onboard(ctx context.Context, userID string) ([]models.Task, error) {
+	if len(u.onboarding) == 0 {
+		return nil, models.NewErrInvalidData("onboarding tasks are not configured")
+	}
+
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsTerminated() {
+		return nil, models.NewErrInvalidData("user with id = %s is terminated", userID)
+	}
+
+	if user.OnboardedAt != nil {
+		return nil, models.NewErrInvalidData("user with id = %s is already onboarded at %s", userID, user.OnboardedAt.Format(time.RFC3339))
+	}
+
+	var (
+		ua       = util.GetUserAccessFromCtx(ctx)
+		now      = time.Now().UTC()
+		replacer = strings.NewReplacer(userPlaceholder, fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+		created  = make([]models.Task, 0, len(u.onboarding))
+	)
+
+	for _, template := range u.onboarding {
+		task := models.TaskElastic{
+			ID:          uuid.New(),
+			Title:       replacer.Replace(template.Title),
+			Description: replacer.Replace(template.Description),
+			AssignedID:  onboardingAssignee(template, user),
+			CreatedByID: ua.UserID,
+			UpdatedByID: ua.UserID,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		}
+
+		if template.DueInDays > 0 {
+			dueDate := now.AddDate(0, 0, template.DueInDays)
+			task.DueDate = &dueDate
+		}
+
+		t, err := u.tasks.SaveTask(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, t)
+	}
+
+	user.OnboardedAt = &now
+	err = u.userRepo.Update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// onboardingAssignee returns id of user who the template task is assigned to, task is unassigned if user has no manager
+func onboardingAssignee(template models.OnboardingTask, user models.User) string {
+	switch template.Assignee {
+	case models.OnboardingUserAssignee:
+		return user.ID.String()
+	case models.OnboardingManagerAssignee:
+		return user.ManagerID
+	}
+	return template.Assignee
+}
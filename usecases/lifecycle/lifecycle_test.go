@@ -0,0 +1,146 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/auth"
+	"github.com/Dimitriy14/staff-manager/usecases/tasks"
+	"github.com/Dimitriy14/staff-manager/usecases/vacation"
+
+	"github.com/google/uuid"
+)
+
+type userRepoStub struct {
+	repository.UserRepository
+	users map[string]models.User
+}
+
+func (r *userRepoStub) GetUserByID(_ context.Context, id string) (models.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.NewErrNotFound("user with id=%s is not found", id)
+	}
+	return u, nil
+}
+
+func (r *userRepoStub) Update(_ context.Context, u models.User) error {
+	r.users[u.ID.String()] = u
+	return nil
+}
+
+type taskRepoStub struct {
+	repository.TaskRepository
+	open []models.TaskElastic
+}
+
+func (r *taskRepoStub) GetOpenAssignedTasks(context.Context, string) ([]models.TaskElastic, error) {
+	return r.open, nil
+}
+
+// taskUsecaseStub applies reassignment to the repository stub so reassigned tasks are no longer open
+type taskUsecaseStub struct {
+	tasks.TaskUsecase
+	repo *taskRepoStub
+}
+
+func (u *taskUsecaseStub) Update(_ context.Context, task models.TaskElastic) (models.Task, error) {
+	open := u.repo.open[:0]
+	for _, t := range u.repo.open {
+		if t.ID != task.ID {
+			open = append(open, t)
+		}
+	}
+	u.repo.open = open
+	return models.Task{ID: task.ID}, nil
+}
+
+type vacationsStub struct {
+	vacation.VacationsUsecase
+	err       error
+	delegates []string
+}
+
+func (v *vacationsStub) ClearDelegate(_ context.Context, delegateID string) error {
+	v.delegates = append(v.delegates, delegateID)
+	return nil
+}
+
+func (v *vacationsStub) CancelUpcoming(context.Context, string, string) ([]models.Vacation, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	return []models.Vacation{{ID: uuid.New()}}, nil
+}
+
+type feedTokenRepoStub struct {
+	repository.FeedTokenRepository
+	revoked []string
+}
+
+func (r *feedTokenRepoStub) DeleteForUser(_ context.Context, userID string) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+type authStub struct {
+	auth.Authentication
+	disabled int
+}
+
+func (a *authStub) DisableUser(context.Context, string) error {
+	a.disabled++
+	return nil
+}
+
+func TestTerminateCanBeRepeatedAfterFailure(t *testing.T) {
+	var (
+		manager    = models.User{ID: uuid.New(), Status: models.Active}
+		user       = models.User{ID: uuid.New(), ManagerID: manager.ID.String(), Status: models.Active}
+		users      = &userRepoStub{users: map[string]models.User{user.ID.String(): user, manager.ID.String(): manager}}
+		taskRepo   = &taskRepoStub{open: []models.TaskElastic{{ID: uuid.New()}, {ID: uuid.New()}}}
+		vacations  = &vacationsStub{err: errors.New("postgres is unavailable")}
+		feedTokens = &feedTokenRepoStub{}
+		a          = &authStub{}
+		u          = NewLifecycleUsecase(users, taskRepo, feedTokens, &taskUsecaseStub{repo: taskRepo}, vacations, a, nil)
+		ctx        = context.WithValue(context.Background(), models.AccessKey,
+			&models.UserAccess{UserID: uuid.New().String(), Role: models.AdminRole})
+	)
+
+	if _, err := u.Terminate(ctx, user.ID.String(), models.TerminationReq{}); err == nil {
+		t.Fatal("expected error when vacations cannot be canceled")
+	}
+	if users.users[user.ID.String()].IsTerminated() {
+		t.Fatal("user is marked terminated before termination is finished")
+	}
+
+	vacations.err = nil
+	result, err := u.Terminate(ctx, user.ID.String(), models.TerminationReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := users.users[user.ID.String()]
+	if !stored.IsTerminated() || stored.TerminatedAt == nil || !result.User.IsTerminated() {
+		t.Fatalf("user is not terminated: %+v", stored)
+	}
+	if result.ReassignedTo != manager.ID.String() || len(result.CanceledVacations) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(taskRepo.open) != 0 || a.disabled != 2 {
+		t.Fatalf("termination is not finished: open tasks=%d, disabled=%d", len(taskRepo.open), a.disabled)
+	}
+	if len(feedTokens.revoked) == 0 || feedTokens.revoked[len(feedTokens.revoked)-1] != user.ID.String() {
+		t.Fatalf("expected feed tokens of the user to be revoked, got %v", feedTokens.revoked)
+	}
+	if len(vacations.delegates) != 1 || vacations.delegates[0] != user.ID.String() {
+		t.Fatalf("expected user to be removed from delegates once, got %v", vacations.delegates)
+	}
+
+	if _, err = u.Terminate(ctx, user.ID.String(), models.TerminationReq{}); !models.IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data for terminated user, got %v", err)
+	}
+}
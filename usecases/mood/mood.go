@@ -0,0 +1,343 @@
+package mood
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/config"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMinGroupSize = 5
+	week                = 7 * 24 * time.Hour
+)
+
+type MoodUsecase interface {
+	CheckIn(ctx context.Context, userID string, req models.MoodCheckInReq) (models.MoodCheckIn, error)
+	// History returns check-ins of the user made in [from, to)
+	History(ctx context.Context, userID string, from, to time.Time) ([]models.MoodCheckIn, error)
+	// TeamPulse returns weekly aggregates of the team for the last weeks including current one,
+	// current user should have ViewTeamPulse permission on every member of the team.
+	// Weeks which averages could be compared with another team to reveal scores of a few users are hidden.
+	TeamPulse(ctx context.Context, ua models.UserAccess, filter models.PulseFilter) (models.TeamPulse, error)
+}
+
+func NewMoodUsecase(moodRepo repository.MoodRepository, userRepo repository.UserRepository, org org.OrgUsecase,
+	authorizer access.Authorizer, cfg config.MoodConfig) *moodUsecase {
+	if cfg.MinGroupSize <= 0 {
+		cfg.MinGroupSize = defaultMinGroupSize
+	}
+
+	return &moodUsecase{
+		moodRepo:     moodRepo,
+		userRepo:     userRepo,
+		org:          org,
+		authorizer:   authorizer,
+		minGroupSize: cfg.MinGroupSize,
+	}
+}
+
+type moodUsecase struct {
+	moodRepo     repository.MoodRepository
+	userRepo     repository.UserRepository
+	org          org.OrgUsecase
+	authorizer   access.Authorizer
+	minGroupSize int
+}
+
+func (u *moodUsecase) CheckIn(ctx context.Context, userID string, req models.MoodCheckInReq) (models.MoodCheckIn, error) {
+	if req.Score < models.MinMoodScore || req.Score > models.MaxMoodScore {
+		return models.MoodCheckIn{}, models.NewErrInvalidData("score should be from %d to %d", models.MinMoodScore, models.MaxMoodScore)
+	}
+
+	checkIn := models.MoodCheckIn{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Score:     req.Score,
+		Note:      req.Note,
+		CreatedAt: time.Now().UTC(),
+	}
+	return checkIn, u.moodRepo.Save(ctx, checkIn)
+}
+
+func (u *moodUsecase) History(ctx context.Context, userID string, from, to time.Time) ([]models.MoodCheckIn, error) {
+	if !from.Before(to) {
+		return nil, models.NewErrInvalidData("from should be before to")
+	}
+	return u.moodRepo.GetForUsers(ctx, []string{userID}, from, to)
+}
+
+func (u *moodUsecase) TeamPulse(ctx context.Context, ua models.UserAccess, filter models.PulseFilter) (models.TeamPulse, error) {
+	members, err := u.members(ctx, ua, filter)
+	if err != nil {
+		return models.TeamPulse{}, err
+	}
+
+	// team smaller than minimum group size would expose response rate of individuals
+	if len(members) < u.minGroupSize {
+		return models.TeamPulse{}, models.NewErrInvalidData("team has %d members, pulse is available for teams of at least %d members",
+			len(members), u.minGroupSize)
+	}
+
+	groups, everyone, err := u.groups(ctx)
+	if err != nil {
+		return models.TeamPulse{}, err
+	}
+
+	var (
+		to   = weekStart(time.Now().UTC()).Add(week)
+		from = to.Add(-time.Duration(filter.Weeks) * week)
+	)
+
+	// check-ins of everyone are needed to compare respondents of the team with respondents of other teams
+	checkIns, err := u.moodRepo.GetForUsers(ctx, everyone, from, to)
+	if err != nil {
+		return models.TeamPulse{}, err
+	}
+
+	// scores[week][user] are scores of the user's check-ins during the week
+	scores := make([]map[string][]int, filter.Weeks)
+	for i := range scores {
+		scores[i] = make(map[string][]int)
+	}
+	for _, c := range checkIns {
+		i := int(c.CreatedAt.Sub(from) / week)
+		scores[i][c.UserID] = append(scores[i][c.UserID], c.Score)
+	}
+
+	pulse := models.TeamPulse{
+		TeamSize:     len(members),
+		MinGroupSize: u.minGroupSize,
+		Weeks:        make([]models.PulseWeek, 0, filter.Weeks),
+	}
+
+	var averages []float64
+	for i, all := range scores {
+		byUser := make(map[string][]int)
+		for _, id := range members {
+			if userScores, ok := all[id]; ok {
+				byUser[id] = userScores
+			}
+		}
+
+		w := models.PulseWeek{
+			Start:        from.Add(time.Duration(i) * week).Format(models.DateLayout),
+			Respondents:  len(byUser),
+			ResponseRate: round(float64(len(byUser)) / float64(len(members))),
+			Hidden:       len(byUser) < u.minGroupSize || u.isolatable(byUser, all, groups),
+		}
+
+		if !w.Hidden {
+			var sum float64
+			for _, userScores := range byUser {
+				sum += average(userScores)
+			}
+			avg := round(sum / float64(len(byUser)))
+			w.Average = &avg
+			averages = append(averages, avg)
+		}
+		pulse.Weeks = append(pulse.Weeks, w)
+	}
+
+	if n := len(averages); n > 1 {
+		trend := round(averages[n-1] - averages[n-2])
+		pulse.Trend = &trend
+	}
+	return pulse, nil
+}
+
+// members returns ids of active members of the team chosen by filter, reports of current user are chosen by default
+func (u *moodUsecase) members(ctx context.Context, ua models.UserAccess, filter models.PulseFilter) ([]string, error) {
+	if filter.DepartmentID != "" {
+		return u.departmentMembers(ctx, ua, filter.DepartmentID)
+	}
+
+	managerID := filter.ManagerID
+	if managerID == "" {
+		managerID = ua.UserID
+	}
+
+	// permission on the manager is granted on everyone who reports to the manager
+	ok, err := u.authorizer.Can(ctx, ua, models.ViewTeamPulse, managerID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, models.NewErrForbidden("team pulse of manager %s is not allowed", managerID)
+	}
+
+	reports, err := u.org.GetReports(ctx, managerID, true)
+	if err != nil {
+		return nil, err
+	}
+	return activeIDs(reports), nil
+}
+
+// departmentMembers returns ids of active members of the department and its teams
+func (u *moodUsecase) departmentMembers(ctx context.Context, ua models.UserAccess, departmentID string) ([]string, error) {
+	departments, err := u.org.GetDepartments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := subDepartments(departments, departmentID)
+	if len(ids) == 0 {
+		return nil, models.NewErrNotFound("department %s is not found", departmentID)
+	}
+
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]string, 0)
+	for _, user := range users {
+		if !ids[user.DepartmentID] || user.IsTerminated() {
+			continue
+		}
+
+		ok, err := u.authorizer.Can(ctx, ua, models.ViewTeamPulse, user.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, models.NewErrForbidden("team pulse of department %s is not allowed", departmentID)
+		}
+		members = append(members, user.ID.String())
+	}
+	return members, nil
+}
+
+// isolatable reports whether respondents of the team differ from respondents of another team which pulse can be requested for
+// by fewer than minimum group size, then comparing averages of both teams would reveal scores of the few differing users.
+// Teams with fewer respondents than minimum group size are not compared because their averages are hidden.
+func (u *moodUsecase) isolatable(respondents, all map[string][]int, groups [][]string) bool {
+	for _, group := range groups {
+		var count, common int
+		for _, id := range group {
+			if _, ok := all[id]; !ok {
+				continue
+			}
+			count++
+			if _, ok := respondents[id]; ok {
+				common++
+			}
+		}
+
+		if count < u.minGroupSize {
+			continue
+		}
+		if diff := len(respondents) - common + count - common; diff > 0 && diff < u.minGroupSize {
+			return true
+		}
+	}
+	return false
+}
+
+// groups returns members of every team which pulse can be requested for, that is reports of every manager
+// down the hierarchy and members of every department with nested departments, and ids of all active users
+func (u *moodUsecase) groups(ctx context.Context) ([][]string, []string, error) {
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	departments, err := u.org.GetDepartments(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		managers = make(map[string]string, len(users))
+		reports  = make(map[string][]string)
+		everyone = activeIDs(users)
+	)
+	for _, user := range users {
+		if !user.IsTerminated() {
+			managers[user.ID.String()] = user.ManagerID
+		}
+	}
+
+	for _, id := range everyone {
+		// visited guards against cycles made before cycle prevention existed
+		visited := map[string]bool{id: true}
+		for manager := managers[id]; manager != "" && !visited[manager]; manager = managers[manager] {
+			visited[manager] = true
+			reports[manager] = append(reports[manager], id)
+		}
+	}
+
+	groups := make([][]string, 0, len(reports)+len(departments))
+	for _, members := range reports {
+		groups = append(groups, members)
+	}
+
+	for _, d := range departments {
+		ids := subDepartments(departments, d.ID.String())
+
+		members := make([]string, 0)
+		for _, user := range users {
+			if ids[user.DepartmentID] && !user.IsTerminated() {
+				members = append(members, user.ID.String())
+			}
+		}
+		groups = append(groups, members)
+	}
+	return groups, everyone, nil
+}
+
+// subDepartments returns ids of the department and all departments nested into it
+func subDepartments(departments []models.Department, departmentID string) map[string]bool {
+	ids := make(map[string]bool)
+	for _, d := range departments {
+		if d.ID.String() == departmentID {
+			ids[departmentID] = true
+		}
+	}
+
+	for added := len(ids) > 0; added; {
+		added = false
+		for _, d := range departments {
+			if id := d.ID.String(); ids[d.ParentID] && !ids[id] {
+				ids[id] = true
+				added = true
+			}
+		}
+	}
+	return ids
+}
+
+func activeIDs(users []models.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		if !user.IsTerminated() {
+			ids = append(ids, user.ID.String())
+		}
+	}
+	return ids
+}
+
+// weekStart returns Monday of the week
+func weekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+func average(scores []int) float64 {
+	var sum int
+	for _, s := range scores {
+		sum += s
+	}
+	return float64(sum) / float64(len(scores))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,185 @@
+package mood
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/config"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+
+	"github.com/google/uuid"
+)
+
+type moodRepoStub struct {
+	repository.MoodRepository
+	checkIns []models.MoodCheckIn
+}
+
+func (r *moodRepoStub) GetForUsers(_ context.Context, userIDs []string, from, to time.Time) ([]models.MoodCheckIn, error) {
+	ids := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		ids[id] = true
+	}
+
+	checkIns := make([]models.MoodCheckIn, 0)
+	for _, c := range r.checkIns {
+		if ids[c.UserID] && !c.CreatedAt.Before(from) && c.CreatedAt.Before(to) {
+			checkIns = append(checkIns, c)
+		}
+	}
+	return checkIns, nil
+}
+
+type userRepoStub struct {
+	repository.UserRepository
+	users []models.User
+}
+
+func (r *userRepoStub) GetAll(context.Context) ([]models.User, error) {
+	return r.users, nil
+}
+
+// orgStub resolves reports from users of the repository stub
+type orgStub struct {
+	org.OrgUsecase
+	users []models.User
+}
+
+func (o *orgStub) GetReports(ctx context.Context, userID string, indirect bool) ([]models.User, error) {
+	reports := make([]models.User, 0)
+	for _, user := range o.users {
+		if user.ManagerID != userID {
+			continue
+		}
+
+		reports = append(reports, user)
+		if indirect {
+			sub, _ := o.GetReports(ctx, user.ID.String(), indirect)
+			reports = append(reports, sub...)
+		}
+	}
+	return reports, nil
+}
+
+func (o *orgStub) GetDepartments(context.Context) ([]models.Department, error) {
+	return nil, nil
+}
+
+func TestTeamPulse(t *testing.T) {
+	var (
+		head    = models.User{ID: uuid.New()}
+		manager = models.User{ID: uuid.New(), ManagerID: head.ID.String()}
+		users   = []models.User{head, manager}
+		team    []string
+	)
+	for i := 0; i < 5; i++ {
+		user := models.User{ID: uuid.New(), ManagerID: manager.ID.String()}
+		users = append(users, user)
+		team = append(team, user.ID.String())
+	}
+	users = append(users, models.User{ID: uuid.New(), ManagerID: manager.ID.String(), Status: models.Terminated})
+
+	var (
+		current  = weekStart(time.Now().UTC())
+		checkIns []models.MoodCheckIn
+	)
+	checkIn := func(weeksAgo int, userID string, score int) {
+		checkIns = append(checkIns, models.MoodCheckIn{
+			UserID:    userID,
+			Score:     score,
+			CreatedAt: current.Add(-time.Duration(weeksAgo)*week + time.Minute),
+		})
+	}
+
+	// 3 weeks ago: visible, average is 2
+	for _, id := range team[:3] {
+		checkIn(3, id, 2)
+	}
+	// 2 weeks ago: manager responded too, so pulse of head differs from pulse of the team by one person
+	for _, id := range team[:3] {
+		checkIn(2, id, 4)
+	}
+	checkIn(2, manager.ID.String(), 1)
+	// last week: too few respondents
+	checkIn(1, team[0], 5)
+	checkIn(1, team[1], 5)
+	// current week: every respondent is counted once with average of own check-ins
+	checkIn(0, team[0], 2)
+	checkIn(0, team[0], 4)
+	checkIn(0, team[1], 3)
+	checkIn(0, team[2], 5)
+	checkIn(0, team[3], 4)
+
+	var (
+		o     = &orgStub{users: users}
+		u     = NewMoodUsecase(&moodRepoStub{checkIns: checkIns}, &userRepoStub{users: users}, o, access.NewAuthorizer(o), config.MoodConfig{MinGroupSize: 3})
+		admin = models.UserAccess{UserID: uuid.New().String(), Role: models.AdminRole}
+	)
+
+	pulse, err := u.TeamPulse(context.Background(), admin, models.PulseFilter{ManagerID: manager.ID.String(), Weeks: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pulse.TeamSize != 5 || pulse.MinGroupSize != 3 || len(pulse.Weeks) != 4 {
+		t.Fatalf("unexpected pulse: %+v", pulse)
+	}
+
+	expected := []struct {
+		respondents int
+		average     float64
+		hidden      bool
+	}{
+		{respondents: 3, average: 2},
+		{respondents: 3, hidden: true},
+		{respondents: 2, hidden: true},
+		{respondents: 4, average: 3.75},
+	}
+	for i, w := range pulse.Weeks {
+		e := expected[i]
+		if w.Start != current.Add(-time.Duration(3-i)*week).Format(models.DateLayout) {
+			t.Fatalf("week %d starts at %s", i, w.Start)
+		}
+		if w.Respondents != e.respondents || w.Hidden != e.hidden || w.ResponseRate != round(float64(e.respondents)/5) {
+			t.Fatalf("week %d: unexpected %+v", i, w)
+		}
+		if e.hidden != (w.Average == nil) || (!e.hidden && *w.Average != e.average) {
+			t.Fatalf("week %d: unexpected average %v", i, w.Average)
+		}
+	}
+
+	if pulse.Trend == nil || *pulse.Trend != 1.75 {
+		t.Fatalf("unexpected trend %v", pulse.Trend)
+	}
+
+	// the same week is hidden in pulse of the head as well, so averages cannot be subtracted
+	pulse, err = u.TeamPulse(context.Background(), admin, models.PulseFilter{ManagerID: head.ID.String(), Weeks: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := pulse.Weeks[1]; w.Respondents != 4 || !w.Hidden || w.Average != nil {
+		t.Fatalf("overlapping week of head is not hidden: %+v", w)
+	}
+	if w := pulse.Weeks[0]; w.Hidden || *w.Average != 2 {
+		t.Fatalf("week with the same respondents should be visible: %+v", w)
+	}
+}
+
+func TestTeamPulseSmallTeam(t *testing.T) {
+	var (
+		manager = models.User{ID: uuid.New()}
+		users   = []models.User{manager, {ID: uuid.New(), ManagerID: manager.ID.String()}}
+		o       = &orgStub{users: users}
+		u       = NewMoodUsecase(&moodRepoStub{}, &userRepoStub{users: users}, o, access.NewAuthorizer(o), config.MoodConfig{})
+	)
+
+	_, err := u.TeamPulse(context.Background(), models.UserAccess{UserID: manager.ID.String(), Role: models.ManagerRole},
+		models.PulseFilter{Weeks: 4})
+	if !models.IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data for team smaller than minimum group size, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Dimitriy14/staff-manager/mail"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbound"
+)
+
+// Channel delivers recent change to the target (email address, webhook URL etc.)
+type Channel interface {
+	Send(ctx context.Context, target string, change models.RecentChanges) error
+}
+
+func NewEmailChannel(sender mail.Sender) *emailChannel {
+	return &emailChannel{sender: sender}
+}
+
+type emailChannel struct {
+	sender mail.Sender
+}
+
+func (c *emailChannel) Send(_ context.Context, target string, change models.RecentChanges) error {
+	return c.sender.Send(mail.Message{
+		To:      []string{target},
+		Subject: fmt.Sprintf("[Staff] %s", change.Title),
+		Text:    Describe(change),
+	})
+}
+
+// webhookPayload is a body of generic webhook
+type webhookPayload struct {
+	Text   string               `json:"text"`
+	Change models.RecentChanges `json:"change"`
+}
+
+func NewWebhookChannel(client *http.Client) *webhookChannel {
+	return &webhookChannel{client: client}
+}
+
+type webhookChannel struct {
+	client *http.Client
+}
+
+func (c *webhookChannel) Send(ctx context.Context, target string, change models.RecentChanges) error {
+	return postJSON(ctx, c.client, target, webhookPayload{Text: Describe(change), Change: change})
+}
+
+// slackPayload is a body of Slack-compatible incoming webhook
+type slackPayload struct {
+	Text string `json:"text"`
+}
+
+func NewSlackChannel(client *http.Client) *slackChannel {
+	return &slackChannel{client: client}
+}
+
+type slackChannel struct {
+	client *http.Client
+}
+
+func (c *slackChannel) Send(ctx context.Context, target string, change models.RecentChanges) error {
+	return postJSON(ctx, c.client, target, slackPayload{Text: Describe(change)})
+}
+
+// postJSON sends payload to the URL, URL is not included into errors as it may contain a secret (e.g. Slack webhook)
+func postJSON(ctx context.Context, client *http.Client, target string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return withoutURL(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return &outbound.StatusError{Code: resp.StatusCode}
+	}
+	return nil
+}
+
+// withoutURL strips URL which net/http and net/url add to their errors
+func withoutURL(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		return urlErr.Err
+	}
+	return err
+}
+
+// Describe returns human readable description of recent change
+func Describe(change models.RecentChanges) string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "%s: %s", change.Type, change.Title)
+	if change.Status != "" {
+		fmt.Fprintf(&buf, " (%s)", change.Status)
+	}
+	if change.UpdatedByName != "" {
+		fmt.Fprintf(&buf, " by %s", change.UpdatedByName)
+	}
+	if change.Comment != "" {
+		fmt.Fprintf(&buf, "\n%s", change.Comment)
+	}
+	return buf.String()
+}
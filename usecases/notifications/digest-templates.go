@@ -0,0 +1,87 @@
+package notifications
+
+import (
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+)
+
+const digestDateLayout = "Jan 2, 2006"
+
+var digestFuncs = map[string]interface{}{
+	"date": func(t time.Time) string {
+		return t.Format(digestDateLayout)
+	},
+	"dueDate": func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(digestDateLayout)
+	},
+	"describe": Describe,
+	"subject":  digestSubject,
+}
+
+func digestSubject(d models.Digest) string {
+	return strings.Title(string(d.Frequency)) + " digest: " + d.Since.Format(digestDateLayout) + " - " + d.Until.Format(digestDateLayout)
+}
+
+var digestTextTemplate = texttemplate.Must(texttemplate.New("digest.txt").Funcs(digestFuncs).Parse(
+	`Hi {{.User.FirstName}},
+
+here is your {{.Frequency}} digest for {{date .Since}} - {{date .Until}}.
+{{if .Changes}}
+Recent changes:
+{{range .Changes}}  * {{describe .}}
+{{end}}{{end}}{{if .OverdueTasks}}
+Overdue tasks:
+{{range .OverdueTasks}}  * #{{.Number}} {{.Title}} (due {{dueDate .DueDate}})
+{{end}}{{end}}{{if .BlockedTasks}}
+Blocked tasks:
+{{range .BlockedTasks}}  * #{{.Number}} {{.Title}}
+{{end}}{{end}}{{if .Absences}}
+Upcoming absences:
+{{range .Absences}}  * {{.UserFullName}}: {{date .StartDate}} - {{date .EndDate}}
+{{end}}{{end}}{{if .IsEmpty}}
+Nothing new since the last digest.
+{{end}}
+You receive this email because you have subscribed to {{.Frequency}} digest in Staff Manager.
+`))
+
+var digestHTMLTemplate = htmltemplate.Must(htmltemplate.New("digest.html").Funcs(digestFuncs).Parse(
+	`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{subject .}}</title></head>
+<body style="font-family: Arial, sans-serif; color: #333;">
+<p>Hi {{.User.FirstName}},</p>
+<p>here is your {{.Frequency}} digest for <b>{{date .Since}} - {{date .Until}}</b>.</p>
+{{if .Changes}}
+<h3>Recent changes</h3>
+<ul>
+{{range .Changes}}<li><b>{{.Type}}</b>: {{.Title}}{{if .Status}} ({{.Status}}){{end}}{{if .UpdatedByName}} by {{.UpdatedByName}}{{end}}{{if .Comment}}<br><i>{{.Comment}}</i>{{end}}</li>
+{{end}}</ul>
+{{end}}{{if .OverdueTasks}}
+<h3>Overdue tasks</h3>
+<ul>
+{{range .OverdueTasks}}<li>#{{.Number}} {{.Title}} <span style="color: #c00;">due {{dueDate .DueDate}}</span></li>
+{{end}}</ul>
+{{end}}{{if .BlockedTasks}}
+<h3>Blocked tasks</h3>
+<ul>
+{{range .BlockedTasks}}<li>#{{.Number}} {{.Title}}</li>
+{{end}}</ul>
+{{end}}{{if .Absences}}
+<h3>Upcoming absences</h3>
+<table cellpadding="4">
+{{range .Absences}}<tr><td>{{.UserFullName}}</td><td>{{date .StartDate}} - {{date .EndDate}}</td></tr>
+{{end}}</table>
+{{end}}{{if .IsEmpty}}
+<p>Nothing new since the last digest.</p>
+{{end}}
+<p style="font-size: small; color: #888;">You receive this email because you have subscribed to {{.Frequency}} digest in Staff Manager.</p>
+</body>
+</html>
+`))
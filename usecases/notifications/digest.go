@@ -0,0 +1,243 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/config"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/mail"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/pkg/errors"
+)
+
+const defaultAbsenceHorizonInDays = 14
+
+type DigestUsecase interface {
+	GetSubscription(ctx context.Context, userID string) (models.DigestSubscription, error)
+	UpdateSubscription(ctx context.Context, userID string, frequency models.DigestFrequency) (models.DigestSubscription, error)
+	// Preview renders digest which would be sent to the user now without sending it
+	Preview(ctx context.Context, userID string, frequency models.DigestFrequency) (*models.DigestPreview, error)
+}
+
+func NewDigester(
+	cfg config.DigestConfig,
+	repo repository.NotificationRepository,
+	recentRepo repository.RecentActionRepository,
+	taskRepo repository.TaskRepository,
+	vacationRepo repository.VacationRepository,
+	userRepo repository.UserRepository,
+	sender mail.Sender,
+	log logger.Logger,
+) *digester {
+	if cfg.AbsenceHorizonInDays < 1 {
+		cfg.AbsenceHorizonInDays = defaultAbsenceHorizonInDays
+	}
+
+	return &digester{
+		cfg:          cfg,
+		repo:         repo,
+		recentRepo:   recentRepo,
+		taskRepo:     taskRepo,
+		vacationRepo: vacationRepo,
+		userRepo:     userRepo,
+		sender:       sender,
+		log:          log,
+	}
+}
+
+type digester struct {
+	cfg          config.DigestConfig
+	repo         repository.NotificationRepository
+	recentRepo   repository.RecentActionRepository
+	taskRepo     repository.TaskRepository
+	vacationRepo repository.VacationRepository
+	userRepo     repository.UserRepository
+	sender       mail.Sender
+	log          logger.Logger
+}
+
+func (d *digester) GetSubscription(ctx context.Context, userID string) (models.DigestSubscription, error) {
+	return d.repo.GetDigestSubscription(ctx, userID)
+}
+
+func (d *digester) UpdateSubscription(ctx context.Context, userID string, frequency models.DigestFrequency) (models.DigestSubscription, error) {
+	sub, err := d.repo.GetDigestSubscription(ctx, userID)
+	if err != nil {
+		return sub, err
+	}
+
+	if sub.Frequency != frequency {
+		// changes made before subscription are not included into the first digest
+		now := time.Now().UTC()
+		sub.LastSentAt = &now
+	}
+	sub.Frequency = frequency
+
+	return sub, d.repo.SaveDigestSubscription(ctx, sub)
+}
+
+func (d *digester) Preview(ctx context.Context, userID string, frequency models.DigestFrequency) (*models.DigestPreview, error) {
+	sub, err := d.repo.GetDigestSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if frequency == "" {
+		frequency = sub.Frequency
+	}
+	if frequency == models.NoDigest {
+		frequency = models.DailyDigest
+	}
+
+	now := time.Now().UTC()
+	since := now.Add(-frequency.Period())
+	if sub.Frequency == frequency && sub.LastSentAt != nil {
+		since = *sub.LastSentAt
+	}
+
+	digest, err := d.build(ctx, userID, frequency, since, now)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := renderDigest(*digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.DigestPreview{
+		To:      digest.User.Email,
+		Subject: msg.Subject,
+		Text:    msg.Text,
+		HTML:    msg.HTML,
+	}, nil
+}
+
+// SendDaily is a scheduled job which sends daily digests
+func (d *digester) SendDaily(ctx context.Context) {
+	d.send(ctx, models.DailyDigest)
+}
+
+// SendWeekly is a scheduled job which sends weekly digests
+func (d *digester) SendWeekly(ctx context.Context) {
+	d.send(ctx, models.WeeklyDigest)
+}
+
+// send delivers digests to subscribers, digest of the user is retried by the next run if sending fails
+func (d *digester) send(ctx context.Context, frequency models.DigestFrequency) {
+	txID := transactionID.FromContext(ctx)
+
+	subs, err := d.repo.GetDigestSubscriptions(ctx, frequency)
+	if err != nil {
+		d.log.Errorf(txID, "cannot get %s digest subscriptions: %s", frequency, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	for _, sub := range subs {
+		since := now.Add(-frequency.Period())
+		if sub.LastSentAt != nil {
+			since = *sub.LastSentAt
+		}
+
+		digest, err := d.build(ctx, sub.UserID, frequency, since, now)
+		if err != nil {
+			d.log.Warnf(txID, "cannot build %s digest for user %s: %s", frequency, sub.UserID, err)
+			continue
+		}
+
+		// terminated users have no access to the app, their subscriptions are kept in case they are reinstated
+		if !digest.IsEmpty() && !digest.User.IsTerminated() {
+			msg, err := renderDigest(*digest)
+			if err != nil {
+				d.log.Warnf(txID, "cannot render %s digest for user %s: %s", frequency, sub.UserID, err)
+				continue
+			}
+
+			if err = d.sender.Send(msg); err != nil {
+				d.log.Warnf(txID, "cannot send %s digest to user %s: %s", frequency, sub.UserID, err)
+				continue
+			}
+		}
+
+		sub.LastSentAt = &now
+		if err = d.repo.SaveDigestSubscription(ctx, sub); err != nil {
+			d.log.Errorf(txID, "cannot save digest subscription of user %s: %s", sub.UserID, err)
+		}
+	}
+}
+
+func (d *digester) build(ctx context.Context, userID string, frequency models.DigestFrequency, since, until time.Time) (*models.Digest, error) {
+	user, err := d.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := d.recentRepo.GetUserChangesBetween(userID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := d.taskRepo.GetUserTasks(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := &models.Digest{
+		User:         user,
+		Frequency:    frequency,
+		Since:        since,
+		Until:        until,
+		Changes:      changes,
+		OverdueTasks: make([]models.TaskElastic, 0),
+		BlockedTasks: make([]models.TaskElastic, 0),
+		Absences:     make([]models.VacationDB, 0),
+	}
+
+	for _, task := range tasks {
+		switch {
+		case task.IsOverdue(until):
+			digest.OverdueTasks = append(digest.OverdueTasks, task)
+		case task.Status == models.Blocked:
+			digest.BlockedTasks = append(digest.BlockedTasks, task)
+		}
+	}
+
+	from := until.Truncate(24 * time.Hour)
+	vacations, err := d.vacationRepo.GetApprovedBetween(ctx, from, from.AddDate(0, 0, d.cfg.AbsenceHorizonInDays))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vacation := range vacations {
+		if vacation.UserID != userID {
+			digest.Absences = append(digest.Absences, vacation)
+		}
+	}
+
+	return digest, nil
+}
+
+func renderDigest(digest models.Digest) (mail.Message, error) {
+	var text, html bytes.Buffer
+
+	if err := digestTextTemplate.Execute(&text, digest); err != nil {
+		return mail.Message{}, errors.Wrap(err, "rendering text digest")
+	}
+
+	if err := digestHTMLTemplate.Execute(&html, digest); err != nil {
+		return mail.Message{}, errors.Wrap(err, "rendering HTML digest")
+	}
+
+	return mail.Message{
+		To:      []string{digest.User.Email},
+		Subject: digestSubject(digest),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
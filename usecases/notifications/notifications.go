@@ -0,0 +1,237 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/config"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbound"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	deliveryLimit = 100
+	// deliveryLease is added to time needed to send the batch, delivery is claimed again only if instance died while sending it
+	deliveryLease = time.Minute
+)
+
+type NotificationsUsecase interface {
+	GetPreferences(ctx context.Context, userID string) ([]models.NotificationPreference, error)
+	UpdatePreferences(ctx context.Context, userID string, prefs []models.NotificationPreference) ([]models.NotificationPreference, error)
+	GetDeliveries(ctx context.Context, userID string) ([]models.NotificationDelivery, error)
+}
+
+// Broadcaster passes recent changes to subscribers of the service instance,
+// change is passed once transaction from context is committed
+type Broadcaster interface {
+	Broadcast(ctx context.Context, change models.RecentChanges)
+}
+
+func NewDispatcher(cfg config.NotificationsConfig, repo repository.NotificationRepository, userRepo repository.UserRepository, log logger.Logger) *dispatcher {
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	return &dispatcher{
+		cfg:      cfg,
+		repo:     repo,
+		userRepo: userRepo,
+		log:      log,
+		channels: make(map[models.NotificationChannel]Channel),
+		lease:    time.Duration(cfg.BatchSize*cfg.WebhookTimeoutInSec)*time.Second + deliveryLease,
+	}
+}
+
+// dispatcher delivers recent changes to involved users through channels chosen in their preferences
+type dispatcher struct {
+	cfg      config.NotificationsConfig
+	repo     repository.NotificationRepository
+	userRepo repository.UserRepository
+	log      logger.Logger
+	channels map[models.NotificationChannel]Channel
+	lease    time.Duration
+}
+
+// Register adds delivery channel
+func (d *dispatcher) Register(name models.NotificationChannel, channel Channel) {
+	d.channels[name] = channel
+}
+
+// Handle queues deliveries of recent change according to preferences of involved users.
+// It is an outbox handler so deliveries are queued within relay transaction and are not lost on restart.
+func (d *dispatcher) Handle(ctx context.Context, event models.OutboxEvent) error {
+	var change models.RecentChanges
+	err := json.Unmarshal([]byte(event.Payload), &change)
+	if err != nil {
+		return errors.Wrapf(err, "unmarshaling recent change from event %s", event.ID)
+	}
+
+	now := time.Now().UTC()
+	for _, userID := range recipients(change) {
+		prefs, err := d.repo.GetPreferences(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, pref := range prefs {
+			if !pref.Accepts(change.Type) {
+				continue
+			}
+
+			err = d.repo.CreateDelivery(ctx, models.NotificationDelivery{
+				ID:            uuid.New(),
+				ChangeID:      change.ID,
+				ChangeType:    change.Type,
+				UserID:        pref.UserID,
+				Channel:       pref.Channel,
+				Target:        pref.Target,
+				Payload:       event.Payload,
+				Status:        models.DeliveryPending,
+				CreatedAt:     now,
+				NextAttemptAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Deliver sends due deliveries, it is run by schedule
+func (d *dispatcher) Deliver(ctx context.Context) {
+	var (
+		txID = transactionID.FromContext(ctx)
+		now  = time.Now().UTC()
+	)
+
+	deliveries, err := d.repo.ClaimDueDeliveries(ctx, now, now.Add(d.lease), d.cfg.BatchSize)
+	if err != nil {
+		d.log.Errorf(txID, "cannot claim notification deliveries: err=%s", err)
+		return
+	}
+
+	for i := range deliveries {
+		d.attempt(ctx, &deliveries[i])
+		err = d.repo.SaveDelivery(ctx, deliveries[i])
+		if err != nil {
+			d.log.Errorf(txID, "cannot save notification delivery %s: err=%s", deliveries[i].ID, err)
+		}
+	}
+}
+
+func (d *dispatcher) GetPreferences(ctx context.Context, userID string) ([]models.NotificationPreference, error) {
+	return d.repo.GetPreferences(ctx, userID)
+}
+
+func (d *dispatcher) UpdatePreferences(ctx context.Context, userID string, prefs []models.NotificationPreference) ([]models.NotificationPreference, error) {
+	for i := range prefs {
+		if _, ok := d.channels[prefs[i].Channel]; !ok {
+			return nil, models.NewErrInvalidData("notification channel %s is not supported", prefs[i].Channel)
+		}
+
+		if prefs[i].Channel != models.EmailChannel {
+			if prefs[i].Target == "" {
+				return nil, models.NewErrInvalidData("target URL is required for %s channel", prefs[i].Channel)
+			}
+			if err := outbound.ValidateURL(prefs[i].Target); err != nil {
+				return nil, err
+			}
+		}
+
+		prefs[i].ID = uuid.New()
+		prefs[i].UserID = userID
+	}
+
+	return prefs, d.repo.ReplacePreferences(ctx, userID, prefs)
+}
+
+func (d *dispatcher) GetDeliveries(ctx context.Context, userID string) ([]models.NotificationDelivery, error) {
+	return d.repo.GetDeliveries(ctx, userID, deliveryLimit)
+}
+
+// attempt sends delivery and updates its state, delivery is failed when attempts are over
+func (d *dispatcher) attempt(ctx context.Context, delivery *models.NotificationDelivery) {
+	var (
+		txID = transactionID.FromContext(ctx)
+		now  = time.Now().UTC()
+	)
+
+	err := d.send(ctx, delivery)
+	delivery.Attempts++
+	if err == nil {
+		delivery.Status = models.Delivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	// error details are logged only, delivery log is visible to the user
+	delivery.LastError = outbound.Describe(err)
+	d.log.Warnf(txID, "attempt %d of %s delivery for change %s failed: err=%s", delivery.Attempts, delivery.Channel, delivery.ChangeID, err)
+
+	if delivery.Attempts >= d.cfg.MaxAttempts {
+		delivery.Status = models.Failed
+		return
+	}
+
+	backoff := time.Duration(d.cfg.InitialBackoffInSec) * time.Second
+	delivery.NextAttemptAt = now.Add(backoff << uint(delivery.Attempts-1))
+}
+
+func (d *dispatcher) send(ctx context.Context, delivery *models.NotificationDelivery) error {
+	channel, ok := d.channels[delivery.Channel]
+	if !ok {
+		return errors.Errorf("channel %s is not supported", delivery.Channel)
+	}
+
+	var change models.RecentChanges
+	err := json.Unmarshal([]byte(delivery.Payload), &change)
+	if err != nil {
+		return errors.Wrapf(err, "unmarshaling recent change %s", delivery.ChangeID)
+	}
+
+	if delivery.Target == "" {
+		target, err := d.target(ctx, *delivery)
+		if err != nil {
+			return err
+		}
+		delivery.Target = target
+	}
+
+	return channel.Send(ctx, delivery.Target, change)
+}
+
+func (d *dispatcher) target(ctx context.Context, delivery models.NotificationDelivery) (string, error) {
+	if delivery.Channel != models.EmailChannel {
+		return "", errors.Errorf("target is not specified for %s channel", delivery.Channel)
+	}
+
+	user, err := d.userRepo.GetUserByID(ctx, delivery.UserID)
+	if err != nil {
+		return "", err
+	}
+	return user.Email, nil
+}
+
+// recipients returns users involved into the change except the one who made it
+func recipients(change models.RecentChanges) []string {
+	users := make([]string, 0, 2)
+	for _, id := range []string{change.UserID, change.OwnerID} {
+		if id == "" || id == change.UpdatedByID || (len(users) > 0 && users[0] == id) {
+			continue
+		}
+		users = append(users, id)
+	}
+	return users
+}
@@ -0,0 +1,224 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/config"
+	"github.com/Dimitriy14/staff-manager/logger"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+)
+
+type notificationRepoStub struct {
+	repository.NotificationRepository
+	prefs      map[string][]models.NotificationPreference
+	deliveries map[uuid.UUID]models.NotificationDelivery
+}
+
+func (r *notificationRepoStub) GetPreferences(_ context.Context, userID string) ([]models.NotificationPreference, error) {
+	return r.prefs[userID], nil
+}
+
+func (r *notificationRepoStub) CreateDelivery(_ context.Context, delivery models.NotificationDelivery) error {
+	r.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (r *notificationRepoStub) ClaimDueDeliveries(_ context.Context, now, leaseUntil time.Time, _ int) ([]models.NotificationDelivery, error) {
+	due := make([]models.NotificationDelivery, 0)
+	for id, d := range r.deliveries {
+		if d.Status == models.DeliveryPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = leaseUntil
+			r.deliveries[id] = d
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+func (r *notificationRepoStub) SaveDelivery(_ context.Context, delivery models.NotificationDelivery) error {
+	r.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+type channelStub struct {
+	err  error
+	sent []string
+}
+
+func (c *channelStub) Send(_ context.Context, target string, _ models.RecentChanges) error {
+	c.sent = append(c.sent, target)
+	return c.err
+}
+
+func newTestDispatcher(repo *notificationRepoStub, channel Channel, maxAttempts int) *dispatcher {
+	log, err := logger.Load(logger.Config{LogLevel: "panic"})
+	if err != nil {
+		panic(err)
+	}
+
+	d := NewDispatcher(config.NotificationsConfig{BatchSize: 10, MaxAttempts: maxAttempts}, repo, nil, log)
+	d.Register(models.SlackChannel, channel)
+	return d
+}
+
+func queueChange(t *testing.T, d *dispatcher, userID string) {
+	change := models.RecentChanges{ID: uuid.New(), Type: models.Assignment, UserID: userID, OwnerID: userID}
+	payload, err := json.Marshal(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.Handle(context.Background(), models.OutboxEvent{ID: uuid.New(), Payload: string(payload)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	var (
+		userID = uuid.New().String()
+		target = "https://hooks.example.com/T000"
+		repo   = &notificationRepoStub{
+			prefs: map[string][]models.NotificationPreference{userID: {
+				{UserID: userID, Channel: models.SlackChannel, Target: target, EventTypes: []string{string(models.Assignment)}},
+				{UserID: userID, Channel: models.SlackChannel, Target: target + "/other", EventTypes: []string{string(models.Mention)}},
+			}},
+			deliveries: make(map[uuid.UUID]models.NotificationDelivery),
+		}
+		channel = &channelStub{}
+		d       = newTestDispatcher(repo, channel, 3)
+	)
+
+	queueChange(t, d, userID)
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("expected delivery only for accepting preference, got %d", len(repo.deliveries))
+	}
+
+	d.Deliver(context.Background())
+
+	if len(channel.sent) != 1 || channel.sent[0] != target {
+		t.Errorf("expected change to be sent to %s, got %v", target, channel.sent)
+	}
+	for _, delivery := range repo.deliveries {
+		if delivery.Status != models.Delivered || delivery.Attempts != 1 {
+			t.Errorf("expected delivered after 1 attempt, got %s after %d", delivery.Status, delivery.Attempts)
+		}
+	}
+}
+
+func TestDispatcherRetriesAndFails(t *testing.T) {
+	var (
+		userID = uuid.New().String()
+		repo   = &notificationRepoStub{
+			prefs: map[string][]models.NotificationPreference{userID: {
+				{UserID: userID, Channel: models.SlackChannel, Target: "https://hooks.example.com/T000", EventTypes: []string{string(models.Assignment)}},
+			}},
+			deliveries: make(map[uuid.UUID]models.NotificationDelivery),
+		}
+		channel = &channelStub{err: &net.OpError{Op: "dial", Err: errors.New("connect: connection refused 10.0.0.7:443")}}
+		d       = newTestDispatcher(repo, channel, 2)
+	)
+
+	queueChange(t, d, userID)
+
+	d.Deliver(context.Background())
+	for _, delivery := range repo.deliveries {
+		if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 {
+			t.Fatalf("expected pending after 1 attempt, got %s after %d", delivery.Status, delivery.Attempts)
+		}
+		if delivery.LastError != "request failed" {
+			t.Errorf("expected connection details to be hidden, got %q", delivery.LastError)
+		}
+	}
+
+	d.Deliver(context.Background())
+	for _, delivery := range repo.deliveries {
+		if delivery.Status != models.Failed || delivery.Attempts != 2 {
+			t.Errorf("expected failed after 2 attempts, got %s after %d", delivery.Status, delivery.Attempts)
+		}
+	}
+}
+
+type ctxKey struct{}
+
+type recentActionRepoStub struct {
+	repository.RecentActionRepository
+	saved map[uuid.UUID]context.Context
+}
+
+func (r *recentActionRepoStub) Create(ctx context.Context, action models.RecentChanges) (bool, error) {
+	if _, ok := r.saved[action.ID]; ok {
+		return false, nil
+	}
+	r.saved[action.ID] = ctx
+	return true, nil
+}
+
+type broadcasterStub struct {
+	broadcasted []context.Context
+}
+
+func (b *broadcasterStub) Broadcast(ctx context.Context, _ models.RecentChanges) {
+	b.broadcasted = append(b.broadcasted, ctx)
+}
+
+func TestRecentChangesHandlerUsesEventContext(t *testing.T) {
+	var (
+		repo        = &recentActionRepoStub{saved: make(map[uuid.UUID]context.Context)}
+		broadcaster = &broadcasterStub{}
+		h           = NewRecentChangesHandler(repo, broadcaster)
+		change      = models.RecentChanges{ID: uuid.New()}
+		ctx         = context.WithValue(context.Background(), ctxKey{}, "tx")
+	)
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := models.OutboxEvent{ID: uuid.New(), Topic: models.RecentChangeTopic, Payload: string(payload)}
+
+	for i := 0; i < 2; i++ {
+		if err := h.Handle(ctx, event); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	if got := repo.saved[change.ID]; got == nil || got.Value(ctxKey{}) != "tx" {
+		t.Errorf("expected change to be saved within transaction of the event")
+	}
+	if len(broadcaster.broadcasted) != 1 {
+		t.Fatalf("expected redelivered change to be broadcasted once, got %d", len(broadcaster.broadcasted))
+	}
+	if broadcaster.broadcasted[0].Value(ctxKey{}) != "tx" {
+		t.Errorf("expected change to be broadcasted within transaction of the event")
+	}
+}
+
+func TestPostJSONHidesURL(t *testing.T) {
+	const secret = "T000-B000-XXXX"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	err = postJSON(context.Background(), &http.Client{}, "http://"+addr+"/services/"+secret, slackPayload{Text: "test"})
+	if err == nil {
+		t.Fatal("expected error for closed port")
+	}
+	if strings.Contains(err.Error(), secret) {
+		t.Fatalf("error contains URL: %s", err)
+	}
+}
@@ -0,0 +1,43 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/pkg/errors"
+)
+
+// NewRecentChangesHandler creates outbox handler which saves recent changes and passes them to broadcasters
+func NewRecentChangesHandler(repo repository.RecentActionRepository, broadcasters ...Broadcaster) *recentChangesHandler {
+	return &recentChangesHandler{
+		repo:         repo,
+		broadcasters: broadcasters,
+	}
+}
+
+type recentChangesHandler struct {
+	repo         repository.RecentActionRepository
+	broadcasters []Broadcaster
+}
+
+// Handle saves recent change from the event, redelivered change is neither saved nor dispatched again
+func (h *recentChangesHandler) Handle(ctx context.Context, event models.OutboxEvent) error {
+	var change models.RecentChanges
+	err := json.Unmarshal([]byte(event.Payload), &change)
+	if err != nil {
+		return errors.Wrapf(err, "unmarshaling recent change from event %s", event.ID)
+	}
+
+	created, err := h.repo.Create(ctx, change)
+	if err != nil || !created {
+		return err
+	}
+
+	for _, b := range h.broadcasters {
+		b.Broadcast(ctx, change)
+	}
+	return nil
+}
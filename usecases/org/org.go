@@ -0,0 +1,298 @@
+package org
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+)
+
+type OrgUsecase interface {
+	// GetChain returns managers of the user starting from direct one up to the top, terminated managers are skipped
+	GetChain(ctx context.Context, userID string) ([]models.User, error)
+	// GetReports returns direct reports of the user or all reports down the hierarchy if indirect is set
+	GetReports(ctx context.Context, userID string, indirect bool) ([]models.User, error)
+	// GetChart returns org chart tree, users without manager are roots
+	GetChart(ctx context.Context) ([]*models.OrgNode, error)
+	// ValidateAssignment checks that the user can report to the manager and belong to the department.
+	// Check and following save are not atomic: two concurrent reassignments (e.g. A to B and B to A) can both pass it
+	// and make a cycle, so readers of reporting lines (GetChain, GetReports, GetChart) stop at users they have already visited.
+	ValidateAssignment(ctx context.Context, userID, managerID, departmentID string) error
+
+	GetDepartments(ctx context.Context) ([]models.Department, error)
+	CreateDepartment(ctx context.Context, req models.DepartmentReq) (*models.Department, error)
+	UpdateDepartment(ctx context.Context, id uuid.UUID, req models.DepartmentReq) (*models.Department, error)
+	DeleteDepartment(ctx context.Context, id uuid.UUID) error
+}
+
+func NewOrgUsecase(userRepo repository.UserRepository, departmentRepo repository.DepartmentRepository) *orgUsecase {
+	return &orgUsecase{
+		userRepo:       userRepo,
+		departmentRepo: departmentRepo,
+	}
+}
+
+type orgUsecase struct {
+	userRepo       repository.UserRepository
+	departmentRepo repository.DepartmentRepository
+}
+
+func (u *orgUsecase) GetChain(ctx context.Context, userID string) ([]models.User, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		chain   = make([]models.User, 0)
+		visited = map[string]bool{userID: true}
+	)
+	// visited guards against cycles made before cycle prevention existed
+	for user.ManagerID != "" && !visited[user.ManagerID] {
+		visited[user.ManagerID] = true
+
+		user, err = u.userRepo.GetUserByID(ctx, user.ManagerID)
+		if err != nil {
+			if models.IsErrNotFound(err) {
+				break
+			}
+			return nil, err
+		}
+
+		// terminated manager is skipped but reporting line continues through them
+		if !user.IsTerminated() {
+			chain = append(chain, user)
+		}
+	}
+	return chain, nil
+}
+
+func (u *orgUsecase) GetReports(ctx context.Context, userID string, indirect bool) ([]models.User, error) {
+	if _, err := u.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if !indirect {
+		return u.userRepo.GetReports(ctx, userID)
+	}
+
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		byManager = groupByManager(users)
+		reports   = make([]models.User, 0)
+		visited   = map[string]bool{userID: true}
+		queue     = []string{userID}
+	)
+	for len(queue) > 0 {
+		managerID := queue[0]
+		queue = queue[1:]
+
+		for _, report := range byManager[managerID] {
+			id := report.ID.String()
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			reports = append(reports, report)
+			queue = append(queue, id)
+		}
+	}
+	return reports, nil
+}
+
+func (u *orgUsecase) GetChart(ctx context.Context) ([]*models.OrgNode, error) {
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]*models.OrgNode, len(users))
+	for _, user := range sortByName(users) {
+		nodes[user.ID.String()] = &models.OrgNode{User: user, Reports: make([]*models.OrgNode, 0)}
+	}
+
+	roots := make([]*models.OrgNode, 0)
+	for _, user := range sortByName(users) {
+		node := nodes[user.ID.String()]
+		manager, ok := nodes[user.ManagerID]
+		if !ok || reportsTo(nodes, user.ManagerID, user.ID.String()) {
+			roots = append(roots, node)
+			continue
+		}
+		manager.Reports = append(manager.Reports, node)
+	}
+	return roots, nil
+}
+
+func (u *orgUsecase) ValidateAssignment(ctx context.Context, userID, managerID, departmentID string) error {
+	if departmentID != "" {
+		if _, err := u.departmentRepo.GetByID(ctx, departmentID); err != nil {
+			if models.IsErrNotFound(err) {
+				return models.NewErrInvalidData("department with id = %s is not found", departmentID)
+			}
+			return err
+		}
+	}
+
+	if managerID == "" {
+		return nil
+	}
+
+	if managerID == userID {
+		return models.NewErrInvalidData("user cannot be own manager")
+	}
+
+	visited := map[string]bool{}
+	for id := managerID; id != "" && !visited[id]; {
+		visited[id] = true
+
+		manager, err := u.userRepo.GetUserByID(ctx, id)
+		if err != nil {
+			if models.IsErrNotFound(err) && id == managerID {
+				return models.NewErrInvalidData("manager with id = %s is not found", managerID)
+			}
+			if models.IsErrNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		if manager.ManagerID == userID {
+			return models.NewErrInvalidData("manager with id = %s reports to the user, reporting line would be cyclic", managerID)
+		}
+		id = manager.ManagerID
+	}
+	return nil
+}
+
+func (u *orgUsecase) GetDepartments(ctx context.Context) ([]models.Department, error) {
+	return u.departmentRepo.GetAll(ctx)
+}
+
+func (u *orgUsecase) CreateDepartment(ctx context.Context, req models.DepartmentReq) (*models.Department, error) {
+	now := time.Now().UTC()
+	department := models.Department{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		ParentID:  req.ParentID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := u.validateParent(ctx, department); err != nil {
+		return nil, err
+	}
+	return &department, u.departmentRepo.Save(ctx, department)
+}
+
+func (u *orgUsecase) UpdateDepartment(ctx context.Context, id uuid.UUID, req models.DepartmentReq) (*models.Department, error) {
+	department, err := u.departmentRepo.GetByID(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	department.Name = req.Name
+	department.ParentID = req.ParentID
+	department.UpdatedAt = time.Now().UTC()
+
+	if err = u.validateParent(ctx, department); err != nil {
+		return nil, err
+	}
+	return &department, u.departmentRepo.Save(ctx, department)
+}
+
+// DeleteDepartment removes department which has neither members nor nested departments
+func (u *orgUsecase) DeleteDepartment(ctx context.Context, id uuid.UUID) error {
+	departments, err := u.departmentRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range departments {
+		if d.ParentID == id.String() {
+			return models.NewErrInvalidData("department with id = %s has nested department %s", id, d.Name)
+		}
+	}
+
+	users, err := u.userRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if user.DepartmentID == id.String() {
+			return models.NewErrInvalidData("department with id = %s has members", id)
+		}
+	}
+
+	return u.departmentRepo.Delete(ctx, id.String())
+}
+
+// validateParent checks that parent department exists and department is not nested into itself
+func (u *orgUsecase) validateParent(ctx context.Context, department models.Department) error {
+	visited := map[string]bool{department.ID.String(): true}
+	for id := department.ParentID; id != ""; {
+		if visited[id] {
+			return models.NewErrInvalidData("department cannot be nested into itself")
+		}
+		visited[id] = true
+
+		parent, err := u.departmentRepo.GetByID(ctx, id)
+		if err != nil {
+			if models.IsErrNotFound(err) {
+				return models.NewErrInvalidData("parent department with id = %s is not found", id)
+			}
+			return err
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+func groupByManager(users []models.User) map[string][]models.User {
+	byManager := make(map[string][]models.User)
+	for _, user := range sortByName(users) {
+		if user.ManagerID != "" {
+			byManager[user.ManagerID] = append(byManager[user.ManagerID], user)
+		}
+	}
+	return byManager
+}
+
+// reportsTo returns true if manager is the user or reports to the user, such users are shown as roots to break cycles
+func reportsTo(nodes map[string]*models.OrgNode, managerID, userID string) bool {
+	visited := map[string]bool{}
+	for id := managerID; id != "" && !visited[id]; {
+		if id == userID {
+			return true
+		}
+		visited[id] = true
+
+		node, ok := nodes[id]
+		if !ok {
+			return false
+		}
+		id = node.User.ManagerID
+	}
+	return false
+}
+
+func sortByName(users []models.User) []models.User {
+	sorted := make([]models.User, len(users))
+	copy(sorted, users)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].LastName != sorted[j].LastName {
+			return sorted[i].LastName < sorted[j].LastName
+		}
+		return sorted[i].FirstName < sorted[j].FirstName
+	})
+	return sorted
+}
@@ -0,0 +1,87 @@
+package org
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+)
+
+type userRepoStub struct {
+	repository.UserRepository
+	users map[string]models.User
+}
+
+func (r *userRepoStub) GetUserByID(_ context.Context, id string) (models.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.NewErrNotFound("user with id=%s is not found", id)
+	}
+	return u, nil
+}
+
+type departmentRepoStub struct {
+	repository.DepartmentRepository
+	departments map[string]models.Department
+}
+
+func (r *departmentRepoStub) GetByID(_ context.Context, id string) (models.Department, error) {
+	d, ok := r.departments[id]
+	if !ok {
+		return models.Department{}, models.NewErrNotFound("department with id = %s is not found", id)
+	}
+	return d, nil
+}
+
+func TestValidateAssignment(t *testing.T) {
+	var (
+		top        = models.User{ID: uuid.New()}
+		lead       = models.User{ID: uuid.New(), ManagerID: top.ID.String()}
+		developer  = models.User{ID: uuid.New(), ManagerID: lead.ID.String()}
+		outsider   = models.User{ID: uuid.New()}
+		department = models.Department{ID: uuid.New()}
+		u          = NewOrgUsecase(
+			&userRepoStub{users: map[string]models.User{
+				top.ID.String():       top,
+				lead.ID.String():      lead,
+				developer.ID.String(): developer,
+				outsider.ID.String():  outsider,
+			}},
+			&departmentRepoStub{departments: map[string]models.Department{department.ID.String(): department}},
+		)
+	)
+
+	tests := []struct {
+		name         string
+		userID       string
+		managerID    string
+		departmentID string
+		invalid      bool
+	}{
+		{name: "no manager", userID: top.ID.String()},
+		{name: "manager from another branch", userID: outsider.ID.String(), managerID: developer.ID.String(), departmentID: department.ID.String()},
+		{name: "self-assignment", userID: lead.ID.String(), managerID: lead.ID.String(), invalid: true},
+		{name: "direct cycle", userID: lead.ID.String(), managerID: developer.ID.String(), invalid: true},
+		{name: "indirect cycle", userID: top.ID.String(), managerID: developer.ID.String(), invalid: true},
+		{name: "missing manager", userID: lead.ID.String(), managerID: uuid.New().String(), invalid: true},
+		{name: "missing department", userID: lead.ID.String(), departmentID: uuid.New().String(), invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := u.ValidateAssignment(context.Background(), tt.userID, tt.managerID, tt.departmentID)
+			if tt.invalid {
+				if !models.IsErrInvalidData(err) {
+					t.Fatalf("expected invalid data, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+}
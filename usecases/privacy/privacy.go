@@ -0,0 +1,300 @@
+package privacy
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+	"github.com/Dimitriy14/staff-manager/util"
+)
+
+type PrivacyUsecase interface {
+	// Apply hides fields of users which current user is not allowed to see, nil users are skipped.
+	// All configurable fields of other users are hidden if access cannot be checked.
+	Apply(ctx context.Context, users ...*models.User)
+	GetSettings(ctx context.Context, userID string) ([]models.FieldPrivacy, error)
+	// UpdateSettings changes visibility of passed fields only, others keep their current visibility
+	UpdateSettings(ctx context.Context, userID string, privacy models.ProfilePrivacy) ([]models.FieldPrivacy, error)
+	GetPolicies(ctx context.Context) ([]models.PrivacyPolicy, error)
+	UpdatePolicy(ctx context.Context, field models.ProfileField, minimum models.Visibility) (*models.PrivacyPolicy, error)
+	// Visibility returns the narrowest of visibility chosen by the user and minimum enforced by admins
+	Visibility(ctx context.Context, user models.User, field models.ProfileField) (models.Visibility, error)
+}
+
+func NewPrivacyUsecase(userRepo repository.UserRepository, policyRepo repository.PrivacyPolicyRepository, org org.OrgUsecase, log logger.Logger) *privacyUsecase {
+	return &privacyUsecase{
+		userRepo:   userRepo,
+		policyRepo: policyRepo,
+		org:        org,
+		log:        log,
+	}
+}
+
+type privacyUsecase struct {
+	userRepo   repository.UserRepository
+	policyRepo repository.PrivacyPolicyRepository
+	org        org.OrgUsecase
+	log        logger.Logger
+}
+
+func (u *privacyUsecase) Apply(ctx context.Context, users ...*models.User) {
+	var (
+		txID = transactionID.FromContext(ctx)
+		v    = &viewer{ua: util.GetUserAccessFromCtx(ctx), privacy: u}
+	)
+
+	minimums, policiesErr := u.minimums(ctx)
+	if policiesErr != nil {
+		u.log.Errorf(txID, "cannot get privacy policies, private fields are hidden: err=%s", policiesErr)
+	}
+
+	for _, user := range users {
+		if user == nil || user.ID.String() == v.ua.UserID {
+			continue
+		}
+		user.Privacy = nil
+
+		// failure to check one user hides fields of that user only
+		var (
+			audience models.Visibility
+			err      = policiesErr
+		)
+		if err == nil {
+			audience, err = v.audience(ctx, *user)
+			if err != nil {
+				u.log.Errorf(txID, "cannot check access to profile of user %s, private fields are hidden: err=%s", user.ID, err)
+			}
+		}
+
+		for field := range models.PrivateFields {
+			if err != nil || !audience.Includes(effective(*user, field, minimums)) {
+				user.Hide(field)
+			}
+		}
+	}
+}
+
+func (u *privacyUsecase) GetSettings(ctx context.Context, userID string) ([]models.FieldPrivacy, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	minimums, err := u.minimums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]models.FieldPrivacy, 0, len(models.PrivateFields))
+	for _, field := range privateFields() {
+		settings = append(settings, models.FieldPrivacy{
+			Field:      field,
+			Visibility: chosen(user, field),
+			Minimum:    minimum(field, minimums),
+			Effective:  effective(user, field, minimums),
+		})
+	}
+	return settings, nil
+}
+
+func (u *privacyUsecase) UpdateSettings(ctx context.Context, userID string, privacy models.ProfilePrivacy) ([]models.FieldPrivacy, error) {
+	user, err := u.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	minimums, err := u.minimums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := make(models.ProfilePrivacy, len(models.PrivateFields))
+	for field, visibility := range user.Privacy {
+		updated[field] = visibility
+	}
+
+	for field, visibility := range privacy {
+		if _, ok := models.PrivateFields[field]; !ok {
+			return nil, models.NewErrInvalidData("visibility of field %s cannot be configured", field)
+		}
+		if !visibility.IsValid() {
+			return nil, models.NewErrInvalidData("unknown visibility %s", visibility)
+		}
+
+		if min := minimum(field, minimums); min.IsNarrowerThan(visibility) {
+			return nil, models.NewErrInvalidData("visibility of field %s cannot be wider than %s enforced by admins", field, min)
+		}
+		updated[field] = visibility
+	}
+
+	user.Privacy = updated
+	err = u.userRepo.Update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return u.GetSettings(ctx, userID)
+}
+
+func (u *privacyUsecase) GetPolicies(ctx context.Context) ([]models.PrivacyPolicy, error) {
+	stored, err := u.policyRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byField := make(map[models.ProfileField]models.PrivacyPolicy, len(stored))
+	for _, policy := range stored {
+		byField[policy.Field] = policy
+	}
+
+	policies := make([]models.PrivacyPolicy, 0, len(models.PrivateFields))
+	for _, field := range privateFields() {
+		policy, ok := byField[field]
+		if !ok {
+			policy = models.PrivacyPolicy{Field: field, Minimum: models.PublicVisibility}
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+func (u *privacyUsecase) UpdatePolicy(ctx context.Context, field models.ProfileField, minimum models.Visibility) (*models.PrivacyPolicy, error) {
+	if _, ok := models.PrivateFields[field]; !ok {
+		return nil, models.NewErrNotFound("visibility of field %s cannot be configured", field)
+	}
+	if !minimum.IsValid() {
+		return nil, models.NewErrInvalidData("unknown visibility %s", minimum)
+	}
+
+	policy := models.PrivacyPolicy{
+		Field:     field,
+		Minimum:   minimum,
+		UpdatedBy: util.GetUserAccessFromCtx(ctx).UserID,
+		UpdatedAt: time.Now().UTC(),
+	}
+
+	err := u.policyRepo.Save(ctx, policy)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (u *privacyUsecase) Visibility(ctx context.Context, user models.User, field models.ProfileField) (models.Visibility, error) {
+	minimums, err := u.minimums(ctx)
+	if err != nil {
+		return "", err
+	}
+	return effective(user, field, minimums), nil
+}
+
+func (u *privacyUsecase) minimums(ctx context.Context) (map[models.ProfileField]models.Visibility, error) {
+	policies, err := u.policyRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	minimums := make(map[models.ProfileField]models.Visibility, len(policies))
+	for _, policy := range policies {
+		minimums[policy.Field] = policy.Minimum
+	}
+	return minimums, nil
+}
+
+// viewer lazily loads data which is required to find out the audience current user belongs to
+type viewer struct {
+	ua      models.UserAccess
+	privacy *privacyUsecase
+	user    *models.User
+	subtree map[string]bool
+}
+
+// audience returns the narrowest visibility current user is allowed to see for the target user
+func (v *viewer) audience(ctx context.Context, target models.User) (models.Visibility, error) {
+	targetID := target.ID.String()
+	if targetID == v.ua.UserID {
+		return models.SelfVisibility, nil
+	}
+
+	scope := v.ua.Role.Scope(models.ViewSensitiveProfile)
+	if scope == models.AllScope {
+		return models.HRVisibility, nil
+	}
+
+	if scope == models.SubtreeScope {
+		if v.subtree == nil {
+			reports, err := v.privacy.org.GetReports(ctx, v.ua.UserID, true)
+			if err != nil {
+				return "", err
+			}
+
+			v.subtree = make(map[string]bool, len(reports))
+			for _, report := range reports {
+				v.subtree[report.ID.String()] = true
+			}
+		}
+
+		if v.subtree[targetID] {
+			return models.ManagerVisibility, nil
+		}
+	}
+
+	if v.user == nil {
+		user, err := v.privacy.userRepo.GetUserByID(ctx, v.ua.UserID)
+		if err != nil {
+			return "", err
+		}
+		v.user = &user
+	}
+
+	if v.user.IsTeammate(target) {
+		return models.TeamVisibility, nil
+	}
+	return models.PublicVisibility, nil
+}
+
+// chosen returns visibility chosen by the user or default one
+func chosen(user models.User, field models.ProfileField) models.Visibility {
+	if visibility, ok := user.Privacy[field]; ok && visibility.IsValid() {
+		return visibility
+	}
+	return models.PrivateFields[field]
+}
+
+func minimum(field models.ProfileField, minimums map[models.ProfileField]models.Visibility) models.Visibility {
+	if visibility, ok := minimums[field]; ok && visibility.IsValid() {
+		return visibility
+	}
+	return models.PublicVisibility
+}
+
+// effective returns the narrowest of visibility chosen by the user and minimum enforced by admins
+func effective(user models.User, field models.ProfileField, minimums map[models.ProfileField]models.Visibility) models.Visibility {
+	visibility, min := chosen(user, field), minimum(field, minimums)
+	if min.IsNarrowerThan(visibility) {
+		return min
+	}
+	return visibility
+}
+
+func privateFields() []models.ProfileField {
+	fields := make([]models.ProfileField, 0, len(models.PrivateFields))
+	for field := range models.PrivateFields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i] < fields[j] })
+	return fields
+}
+
+// Users returns pointers to elements of the slice so privacy can be applied to them in place
+func Users(users []models.User) []*models.User {
+	pointers := make([]*models.User, 0, len(users))
+	for i := range users {
+		pointers = append(pointers, &users[i])
+	}
+	return pointers
+}
@@ -0,0 +1,61 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+)
+
+// flakyUserRepo fails the first lookup of the viewer
+type flakyUserRepo struct {
+	repository.UserRepository
+	viewer models.User
+	calls  int
+}
+
+func (r *flakyUserRepo) GetUserByID(context.Context, string) (models.User, error) {
+	r.calls++
+	if r.calls == 1 {
+		return models.User{}, errors.New("elasticsearch is unavailable")
+	}
+	return r.viewer, nil
+}
+
+type policyRepoStub struct {
+	repository.PrivacyPolicyRepository
+}
+
+func (policyRepoStub) GetAll(context.Context) ([]models.PrivacyPolicy, error) {
+	return nil, nil
+}
+
+func TestApplyHidesOnlyUserWhichCheckFailed(t *testing.T) {
+	log, err := logger.Load(logger.Config{LogLevel: "panic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		viewer = models.User{ID: uuid.New()}
+		first  = &models.User{ID: uuid.New(), HireDate: "2019-03-01"}
+		second = &models.User{ID: uuid.New(), HireDate: "2020-05-01"}
+		u      = NewPrivacyUsecase(&flakyUserRepo{viewer: viewer}, policyRepoStub{}, nil, log)
+		ctx    = context.WithValue(context.Background(), models.AccessKey,
+			&models.UserAccess{UserID: viewer.ID.String(), Role: models.UserRole})
+	)
+
+	u.Apply(ctx, first, second)
+
+	if first.HireDate != "" {
+		t.Errorf("fields of user which check failed should be hidden, got hireDate=%q", first.HireDate)
+	}
+	if second.HireDate != "2020-05-01" {
+		t.Errorf("public field of the next user should stay visible, got hireDate=%q", second.HireDate)
+	}
+}
@@ -0,0 +1,137 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/google/uuid"
+)
+
+// mentionPattern matches @<uuid> and @firstName.lastName which are not a part of email address or word
+var mentionPattern = regexp.MustCompile(
+	`(?:^|[^\p{L}\p{N}_@.])@(?:([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})|(\p{L}[\p{L}'-]*)\.(\p{L}[\p{L}'-]*))`)
+
+// parseMentions returns unique mention tokens found in texts in order of appearance
+func parseMentions(texts ...string) []string {
+	var (
+		tokens []string
+		seen   = make(map[string]bool)
+	)
+
+	for _, text := range texts {
+		for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+			token := m[1]
+			if token == "" {
+				token = m[2] + "." + m[3]
+			}
+
+			key := strings.ToLower(token)
+			if !seen[key] {
+				seen[key] = true
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return tokens
+}
+
+// resolveMentions finds users mentioned in title and description of the task,
+// mentions of unknown and terminated users and ambiguous names are skipped
+func (u *taskUsecase) resolveMentions(ctx context.Context, task models.TaskElastic) ([]models.TaskMention, error) {
+	var (
+		mentions []models.TaskMention
+		seen     = make(map[string]bool)
+	)
+
+	for _, token := range parseMentions(task.Title, task.Description) {
+		user, err := u.mentionedUser(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+
+		if user == nil || seen[user.ID.String()] {
+			continue
+		}
+		seen[user.ID.String()] = true
+
+		mentions = append(mentions, models.TaskMention{
+			Token:    "@" + token,
+			UserID:   user.ID.String(),
+			UserName: fmt.Sprintf("%s %s", user.FirstName, user.LastName),
+		})
+	}
+	return mentions, nil
+}
+
+func (u *taskUsecase) mentionedUser(ctx context.Context, token string) (*models.User, error) {
+	if id, err := uuid.Parse(token); err == nil {
+		user, err := u.userRepo.GetUserByID(ctx, id.String())
+		if err != nil {
+			if models.IsErrNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		if user.IsTerminated() {
+			return nil, nil
+		}
+		return &user, nil
+	}
+
+	names := strings.SplitN(token, ".", 2)
+	users, err := u.userRepo.SearchUsers(ctx, models.UserSearch{ByName: strings.Join(names, " ")})
+	if err != nil {
+		return nil, err
+	}
+
+	var found *models.User
+	for i := range users {
+		if strings.EqualFold(users[i].FirstName, names[0]) && strings.EqualFold(users[i].LastName, names[1]) {
+			if found != nil {
+				return nil, nil
+			}
+			found = &users[i]
+		}
+	}
+	return found, nil
+}
+
+// mentionChanges returns recent changes for users who are mentioned in the task and were not mentioned before,
+// author of the change is not notified about own mentions
+func mentionChanges(task models.Task, previous []models.TaskMention) []models.RecentChanges {
+	var (
+		changes   []models.RecentChanges
+		mentioned = make(map[string]bool)
+	)
+
+	for _, m := range previous {
+		mentioned[m.UserID] = true
+	}
+
+	for _, m := range task.Mentions {
+		if mentioned[m.UserID] || m.UserID == task.UpdatedBy.ID.String() {
+			continue
+		}
+
+		changes = append(changes, models.RecentChanges{
+			ID:            uuid.New(),
+			Title:         fmt.Sprintf("%d %s", task.Number, task.Title),
+			IncidentID:    task.ID,
+			Type:          models.Mention,
+			UserName:      m.UserName,
+			UserID:        m.UserID,
+			OwnerID:       task.CreatedBy.ID.String(),
+			UpdatedByName: fmt.Sprintf("%s %s", task.UpdatedBy.FirstName, task.UpdatedBy.LastName),
+			UpdatedByID:   task.UpdatedBy.ID.String(),
+			ChangeTime:    time.Now().UTC(),
+			Status:        string(task.Status),
+		})
+	}
+	return changes
+}
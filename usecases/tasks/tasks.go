@@ -6,7 +6,11 @@ import (
 	"time"
 
 	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbox"
 	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+	"github.com/Dimitriy14/staff-manager/util"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -26,23 +30,31 @@ type TaskUsecase interface {
 func NewTaskUsecase(
 	taskRepo repository.TaskRepository,
 	userRepo repository.UserRepository,
-	recentChangesRepo repository.RecentActionRepository) *taskUsecase {
+	outboxRepo repository.OutboxRepository,
+	vacationRepo repository.VacationRepository,
+	authorizer access.Authorizer) *taskUsecase {
 	return &taskUsecase{
-		TaskRepository:    taskRepo,
-		userRepo:          userRepo,
-		recentChangesRepo: recentChangesRepo,
+		TaskRepository: taskRepo,
+		userRepo:       userRepo,
+		outboxRepo:     outboxRepo,
+		vacationRepo:   vacationRepo,
+		authorizer:     authorizer,
 	}
 }
 
 const (
 	numOfWorker = 2
+
+	dateLayout = "2006-01-02"
 )
 
 type taskUsecase struct {
 	repository.TaskRepository
 
-	userRepo          repository.UserRepository
-	recentChangesRepo repository.RecentActionRepository
+	userRepo     repository.UserRepository
+	outboxRepo   repository.OutboxRepository
+	vacationRepo repository.VacationRepository
+	authorizer   access.Authorizer
 }
 
 func (u *taskUsecase) SaveTask(ctx context.Context, task models.TaskElastic) (models.Task, error) {
@@ -58,26 +70,68 @@ func (u *taskUsecase) SaveTask(ctx context.Context, task models.TaskElastic) (mo
 	task.Number = uint64(count)
 	task.Status = models.Ready
 
+	var warning string
+	if task.IsAssigned() {
+		task.AssignedID, warning, err = u.resolveAbsentAssignee(ctx, task.AssignedID)
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	task.Mentions, err = u.resolveMentions(ctx, task)
+	if err != nil {
+		return models.Task{}, errors.Wrap(err, "cannot resolve mentions")
+	}
+
 	t := copyToTask(task)
 	t.CreatedBy = &creatorUser
 	t.UpdatedBy = &creatorUser
+	t.Warning = warning
 
+	var changes []models.RecentChanges
 	if task.IsAssigned() {
-		t.Assigned, err = u.assignedUser(ctx, task.AssignedID, t)
+		t.Assigned, err = u.assignedUser(ctx, task.AssignedID)
 		if err != nil {
 			return models.Task{}, err
 		}
+		changes = append(changes, assignmentChange(t, *t.Assigned))
 	}
+	changes = append(changes, mentionChanges(t, nil)...)
 
 	err = u.TaskRepository.SaveTask(ctx, task)
 	if err != nil {
 		return models.Task{}, errors.Wrap(err, "cannot save task")
 	}
 
+	err = u.publish(ctx, changes...)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	audit.SetAction(ctx, "task.create")
+	audit.SetChange(ctx, "task", task.ID.String(), nil, task)
 	return t, nil
 }
 
-func (u *taskUsecase) assignedUser(ctx context.Context, assignedUserID string, task models.Task) (*models.User, error) {
+// publish writes recent changes of the task to outbox, it is called after the task is written to elasticsearch
+func (u *taskUsecase) publish(ctx context.Context, changes ...models.RecentChanges) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	events := make([]models.OutboxEvent, 0, len(changes))
+	for _, change := range changes {
+		event, err := outbox.NewEvent(models.RecentChangeTopic, change)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+
+	return u.outboxRepo.Save(ctx, events...)
+}
+
+func (u *taskUsecase) assignedUser(ctx context.Context, assignedUserID string) (*models.User, error) {
 	assignedUser, err := u.userRepo.GetUserByID(ctx, assignedUserID)
 	if err != nil {
 		if models.IsErrNotFound(err) {
@@ -85,8 +139,11 @@ func (u *taskUsecase) assignedUser(ctx context.Context, assignedUserID string, t
 		}
 		return nil, err
 	}
+	return &assignedUser, nil
+}
 
-	return &assignedUser, u.recentChangesRepo.Save(models.RecentChanges{
+func assignmentChange(task models.Task, assignedUser models.User) models.RecentChanges {
+	return models.RecentChanges{
 		ID:            uuid.New(),
 		Title:         fmt.Sprintf("%d %s", task.Number, task.Title),
 		IncidentID:    task.ID,
@@ -98,27 +155,174 @@ func (u *taskUsecase) assignedUser(ctx context.Context, assignedUserID string, t
 		UpdatedByID:   task.UpdatedBy.ID.String(),
 		ChangeTime:    time.Now().UTC(),
 		Status:        string(task.Status),
-	})
+	}
 }
 
 func (u *taskUsecase) GetUserTasks(ctx context.Context, userID string) ([]models.Task, error) {
+	ua := util.GetUserAccessFromCtx(ctx)
+	canView, err := u.authorizer.Can(ctx, ua, models.ManageTasks, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		return nil, models.NewErrForbidden("user %s cannot view tasks of user %s", ua.UserID, userID)
+	}
+
 	tasks, err := u.TaskRepository.GetUserTasks(ctx, userID)
 	if err != nil {
 		return nil, errors.Wrapf(err, "cannot retrieve tasks for userID=%s", userID)
 	}
 
-	return u.joinTasks(ctx, tasks...)
+	joinedTasks, err := u.joinTasks(ctx, tasks...)
+	if err != nil {
+		return nil, err
+	}
+
+	vacation, err := u.activeVacation(ctx, userID)
+	if err != nil || vacation == nil || vacation.DelegateID == "" {
+		return joinedTasks, err
+	}
+
+	delegate, err := u.activeDelegate(ctx, *vacation)
+	if err != nil || delegate == nil {
+		return joinedTasks, err
+	}
+
+	// only tasks which are still to be done are covered
+	for i := range joinedTasks {
+		if joinedTasks[i].Status != models.Done && !joinedTasks[i].IsDeleted {
+			joinedTasks[i].CoveredBy = delegate
+		}
+	}
+	return joinedTasks, nil
+}
+
+// resolveAbsentAssignee redirects assignment to delegate if assigned user is on vacation now
+func (u *taskUsecase) resolveAbsentAssignee(ctx context.Context, assignedID string) (string, string, error) {
+	vacation, err := u.activeVacation(ctx, assignedID)
+	if err != nil || vacation == nil {
+		return assignedID, "", err
+	}
+
+	if vacation.DelegateID == "" {
+		return assignedID, fmt.Sprintf("%s is on vacation until %s", vacation.UserFullName, vacation.EndDate.Format(dateLayout)), nil
+	}
+
+	delegate, err := u.activeDelegate(ctx, *vacation)
+	if err != nil {
+		return assignedID, "", err
+	}
+	if delegate == nil {
+		return assignedID, fmt.Sprintf("%s is on vacation until %s, delegate %s is no longer available so task stays with %s",
+			vacation.UserFullName, vacation.EndDate.Format(dateLayout), vacation.DelegateFullName, vacation.UserFullName), nil
+	}
+
+	return vacation.DelegateID, fmt.Sprintf("%s is on vacation until %s, task is assigned to delegate %s",
+		vacation.UserFullName, vacation.EndDate.Format(dateLayout), vacation.DelegateFullName), nil
+}
+
+// activeDelegate returns delegate of the vacation or nil if delegate was removed or terminated after vacation was approved
+func (u *taskUsecase) activeDelegate(ctx context.Context, vacation models.VacationDB) (*models.User, error) {
+	delegate, err := u.userRepo.GetUserByID(ctx, vacation.DelegateID)
+	if models.IsErrNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot retrieve delegate user with id=%s", vacation.DelegateID)
+	}
+	if delegate.IsTerminated() {
+		return nil, nil
+	}
+	return &delegate, nil
+}
+
+// activeVacation returns approved vacation of the user which lasts today or nil if user is not absent
+func (u *taskUsecase) activeVacation(ctx context.Context, userID string) (*models.VacationDB, error) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	vacations, err := u.vacationRepo.GetActiveForUser(ctx, userID, today)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot retrieve active vacation for userID=%s", userID)
+	}
+
+	if len(vacations) == 0 {
+		return nil, nil
+	}
+	return &vacations[0], nil
 }
 
+// GetTasks returns page of tasks which current user can manage, page may be shorter than size as tasks are filtered after retrieval
 func (u *taskUsecase) GetTasks(ctx context.Context, from, size int) ([]models.Task, error) {
 	tasks, err := u.TaskRepository.GetTasks(ctx, from, size)
 	if err != nil {
 		return nil, errors.Wrap(err, "cannot retrieve tasks")
 	}
 
+	tasks, err = u.filterManaged(ctx, tasks)
+	if err != nil {
+		return nil, err
+	}
 	return u.joinTasks(ctx, tasks...)
 }
 
+// Search returns tasks matching the search which current user can manage
+func (u *taskUsecase) Search(ctx context.Context, search string) ([]models.TaskElastic, error) {
+	tasks, err := u.TaskRepository.Search(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	return u.filterManaged(ctx, tasks)
+}
+
+// filterManaged keeps tasks which current user can manage
+func (u *taskUsecase) filterManaged(ctx context.Context, tasks []models.TaskElastic) ([]models.TaskElastic, error) {
+	var (
+		ua       = util.GetUserAccessFromCtx(ctx)
+		managed  = make(map[string]bool)
+		filtered = make([]models.TaskElastic, 0, len(tasks))
+	)
+
+	for _, task := range tasks {
+		if task.CreatedByID == ua.UserID || task.AssignedID == ua.UserID {
+			filtered = append(filtered, task)
+			continue
+		}
+
+		owner := taskOwner(task)
+		ok, checked := managed[owner]
+		if !checked {
+			var err error
+			ok, err = u.authorizer.Can(ctx, ua, models.ManageTasks, owner)
+			if err != nil {
+				return nil, err
+			}
+			managed[owner] = ok
+		}
+
+		if ok {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered, nil
+}
+
+// canManage returns true if user created the task, is assigned to it or has ManageTasks permission on its owner
+func (u *taskUsecase) canManage(ctx context.Context, ua models.UserAccess, task models.TaskElastic) (bool, error) {
+	if task.CreatedByID == ua.UserID || task.AssignedID == ua.UserID {
+		return true, nil
+	}
+	return u.authorizer.Can(ctx, ua, models.ManageTasks, taskOwner(task))
+}
+
+// taskOwner returns assigned user of the task or its creator if task is not assigned
+func taskOwner(task models.TaskElastic) string {
+	if task.IsAssigned() {
+		return task.AssignedID
+	}
+	return task.CreatedByID
+}
+
 func (u *taskUsecase) GetTaskByID(ctx context.Context, id uuid.UUID) (models.Task, error) {
 	task, err := u.TaskRepository.GetTaskByID(ctx, id.String())
 	if err != nil {
@@ -133,24 +337,45 @@ func (u *taskUsecase) Update(ctx context.Context, task models.TaskElastic) (mode
 	if err != nil {
 		return models.Task{}, errors.Wrapf(err, "cannot retrieve task by id=%s", task.ID)
 	}
+
+	ua := util.GetUserAccessFromCtx(ctx)
+	canManage, err := u.canManage(ctx, ua, oldTask)
+	if err != nil {
+		return models.Task{}, err
+	}
+	if !canManage {
+		return models.Task{}, models.NewErrForbidden("user %s cannot update task with id=%s", ua.UserID, task.ID)
+	}
 	task.CreatedByID = oldTask.CreatedByID
 	task.CreatedAt = oldTask.CreatedAt
 	task.Number = oldTask.Number
 
+	var warning string
+	if oldTask.AssignedID != task.AssignedID && task.IsAssigned() {
+		task.AssignedID, warning, err = u.resolveAbsentAssignee(ctx, task.AssignedID)
+		if err != nil {
+			return models.Task{}, err
+		}
+	}
+
+	task.Mentions, err = u.resolveMentions(ctx, task)
+	if err != nil {
+		return models.Task{}, errors.Wrap(err, "cannot resolve mentions")
+	}
+
 	t, err := u.joinTaskWithUsers(ctx, task)
 	if err != nil {
 		return models.Task{}, err
 	}
+	t.Warning = warning
 
+	var changes []models.RecentChanges
 	if oldTask.AssignedID != task.AssignedID && task.IsAssigned() {
-		_, err = u.assignedUser(ctx, task.AssignedID, t)
-		if err != nil {
-			return models.Task{}, err
-		}
+		changes = append(changes, assignmentChange(t, *t.Assigned))
 	}
 
 	if oldTask.Status != task.Status && task.IsAssigned() {
-		err = u.recentChangesRepo.Save(models.RecentChanges{
+		changes = append(changes, models.RecentChanges{
 			ID:            uuid.New(),
 			Title:         fmt.Sprintf("%d %s", task.Number, task.Title),
 			IncidentID:    task.ID,
@@ -163,12 +388,22 @@ func (u *taskUsecase) Update(ctx context.Context, task models.TaskElastic) (mode
 			ChangeTime:    t.UpdatedAt,
 			Status:        string(task.Status),
 		})
-		if err != nil {
-			return models.Task{}, err
-		}
+	}
+	changes = append(changes, mentionChanges(t, oldTask.Mentions)...)
+
+	err = u.TaskRepository.UpdateTask(ctx, task)
+	if err != nil {
+		return models.Task{}, errors.Wrap(err, "cannot update task")
+	}
+
+	err = u.publish(ctx, changes...)
+	if err != nil {
+		return models.Task{}, err
 	}
 
-	return t, u.TaskRepository.UpdateTask(ctx, task)
+	audit.SetAction(ctx, "task.update")
+	audit.SetChange(ctx, "task", task.ID.String(), oldTask, task)
+	return t, nil
 }
 
 func (u *taskUsecase) joinTasks(ctx context.Context, tasks ...models.TaskElastic) ([]models.Task, error) {
@@ -251,10 +486,26 @@ func (u *taskUsecase) DeleteTask(ctx context.Context, id uuid.UUID, userID strin
 	if err != nil {
 		return err
 	}
+
+	canManage, err := u.canManage(ctx, util.GetUserAccessFromCtx(ctx), task)
+	if err != nil {
+		return err
+	}
+	if !canManage {
+		return models.NewErrForbidden("user %s cannot delete task with id=%s", userID, id)
+	}
+	before := task
 	task.IsDeleted = true
 	task.UpdatedByID = userID
 
-	return u.TaskRepository.UpdateTask(ctx, task)
+	err = u.TaskRepository.UpdateTask(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	audit.SetAction(ctx, "task.delete")
+	audit.SetChange(ctx, "task", id.String(), before, task)
+	return nil
 }
 
 func copyToTask(te models.TaskElastic) models.Task {
@@ -267,5 +518,7 @@ func copyToTask(te models.TaskElastic) models.Task {
 		CreatedAt:   te.CreatedAt,
 		Status:      te.Status,
 		IsDeleted:   te.IsDeleted,
+		DueDate:     te.DueDate,
+		Mentions:    te.Mentions,
 	}
 }
@@ -0,0 +1,290 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+
+	"github.com/google/uuid"
+)
+
+type taskRepoStub struct {
+	repository.TaskRepository
+	tasks []models.TaskElastic
+}
+
+func (r *taskRepoStub) GetUserTasks(context.Context, string) ([]models.TaskElastic, error) {
+	return r.tasks, nil
+}
+
+type userRepoStub struct {
+	repository.UserRepository
+	users map[string]models.User
+}
+
+func (r *userRepoStub) GetUserByID(_ context.Context, id string) (models.User, error) {
+	u, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.NewErrNotFound("user with id=%s is not found", id)
+	}
+	return u, nil
+}
+
+// orgStub returns direct manager of the user as the whole chain
+type orgStub struct {
+	org.OrgUsecase
+	managers map[string]string
+}
+
+func (o *orgStub) GetChain(_ context.Context, userID string) ([]models.User, error) {
+	if manager, ok := o.managers[userID]; ok {
+		return []models.User{{ID: uuid.MustParse(manager)}}, nil
+	}
+	return nil, nil
+}
+
+func withUser(userID string, role models.Role) context.Context {
+	return context.WithValue(context.Background(), models.AccessKey, &models.UserAccess{UserID: userID, Role: role})
+}
+
+type vacationRepoStub struct {
+	repository.VacationRepository
+	vacations []models.VacationDB
+}
+
+func (r *vacationRepoStub) GetActiveForUser(context.Context, string, time.Time) ([]models.VacationDB, error) {
+	return r.vacations, nil
+}
+
+func TestGetUserTasksCoverage(t *testing.T) {
+	var (
+		userID     = "a8d6c3a0-1f5e-4b8a-9c43-0d2f6a1b7e01"
+		delegateID = "c2e4f6a8-3b5d-4c7e-8f91-2a4c6e8b0d13"
+		users      = map[string]models.User{
+			userID:     {ID: uuid.MustParse(userID)},
+			delegateID: {ID: uuid.MustParse(delegateID)},
+		}
+		tasks = []models.TaskElastic{
+			{ID: uuid.New(), Status: models.InProgress, AssignedID: userID, CreatedByID: userID, UpdatedByID: userID},
+			{ID: uuid.New(), Status: models.Done, AssignedID: userID, CreatedByID: userID, UpdatedByID: userID},
+		}
+		vacation = models.VacationDB{UserID: userID, DelegateID: delegateID}
+	)
+
+	t.Run("open tasks are covered by delegate", func(t *testing.T) {
+		u := &taskUsecase{
+			TaskRepository: &taskRepoStub{tasks: tasks},
+			userRepo:       &userRepoStub{users: users},
+			vacationRepo:   &vacationRepoStub{vacations: []models.VacationDB{vacation}},
+			authorizer:     access.NewAuthorizer(&orgStub{}),
+		}
+
+		got, err := u.GetUserTasks(withUser(userID, models.UserRole), userID)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		for _, task := range got {
+			covered := task.CoveredBy != nil
+			if covered != (task.Status != models.Done) {
+				t.Errorf("task with status %s: expected covered=%t, got %t", task.Status, task.Status != models.Done, covered)
+			}
+		}
+	})
+
+	t.Run("removed delegate covers nothing", func(t *testing.T) {
+		u := &taskUsecase{
+			TaskRepository: &taskRepoStub{tasks: tasks},
+			userRepo:       &userRepoStub{users: map[string]models.User{userID: users[userID]}},
+			vacationRepo:   &vacationRepoStub{vacations: []models.VacationDB{vacation}},
+			authorizer:     access.NewAuthorizer(&orgStub{}),
+		}
+
+		got, err := u.GetUserTasks(withUser(userID, models.UserRole), userID)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(got) != len(tasks) {
+			t.Fatalf("expected %d tasks, got %d", len(tasks), len(got))
+		}
+
+		for _, task := range got {
+			if task.CoveredBy != nil {
+				t.Errorf("task %s is not expected to be covered", task.ID)
+			}
+		}
+	})
+}
+
+type taskWriterStub struct {
+	repository.TaskRepository
+	task    models.TaskElastic
+	updated []models.TaskElastic
+}
+
+func (r *taskWriterStub) GetTaskByID(context.Context, string) (models.TaskElastic, error) {
+	return r.task, nil
+}
+
+func (r *taskWriterStub) UpdateTask(_ context.Context, task models.TaskElastic) error {
+	r.updated = append(r.updated, task)
+	return nil
+}
+
+func TestDeleteTaskWritesIndex(t *testing.T) {
+	var (
+		userID = "a8d6c3a0-1f5e-4b8a-9c43-0d2f6a1b7e01"
+		repo   = &taskWriterStub{task: models.TaskElastic{ID: uuid.New(), Status: models.Ready, CreatedByID: userID}}
+		u      = &taskUsecase{TaskRepository: repo, authorizer: access.NewAuthorizer(&orgStub{})}
+	)
+
+	err := u.DeleteTask(withUser(userID, models.UserRole), repo.task.ID, userID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(repo.updated) != 1 {
+		t.Fatalf("expected task to be written once, got %d writes", len(repo.updated))
+	}
+	if got := repo.updated[0]; !got.IsDeleted || got.UpdatedByID != userID {
+		t.Errorf("expected task deleted by %s, got isDeleted=%t updatedBy=%s", userID, got.IsDeleted, got.UpdatedByID)
+	}
+}
+
+type taskListStub struct {
+	repository.TaskRepository
+	tasks []models.TaskElastic
+}
+
+func (r *taskListStub) Search(context.Context, string) ([]models.TaskElastic, error) {
+	return r.tasks, nil
+}
+
+func (r *taskListStub) GetTaskByID(_ context.Context, id string) (models.TaskElastic, error) {
+	for _, task := range r.tasks {
+		if task.ID.String() == id {
+			return task, nil
+		}
+	}
+	return models.TaskElastic{}, models.NewErrNotFound("task with id=%s is not found", id)
+}
+
+func TestTaskScope(t *testing.T) {
+	var (
+		manager  = uuid.New().String()
+		report   = uuid.New().String()
+		outsider = uuid.New().String()
+		own      = models.TaskElastic{ID: uuid.New(), CreatedByID: manager, AssignedID: outsider}
+		reports  = models.TaskElastic{ID: uuid.New(), CreatedByID: outsider, AssignedID: report}
+		other    = models.TaskElastic{ID: uuid.New(), CreatedByID: outsider, AssignedID: outsider}
+		repo     = &taskListStub{tasks: []models.TaskElastic{own, reports, other}}
+		u        = &taskUsecase{
+			TaskRepository: repo,
+			authorizer:     access.NewAuthorizer(&orgStub{managers: map[string]string{report: manager}}),
+		}
+	)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected []uuid.UUID
+	}{
+		{name: "manager sees own and reports tasks", ctx: withUser(manager, models.ManagerRole), expected: []uuid.UUID{own.ID, reports.ID}},
+		{name: "user sees own tasks", ctx: withUser(report, models.UserRole), expected: []uuid.UUID{reports.ID}},
+		{name: "admin sees all tasks", ctx: withUser(uuid.New().String(), models.AdminRole), expected: []uuid.UUID{own.ID, reports.ID, other.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.Search(tt.ctx, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %d tasks, got %d", len(tt.expected), len(got))
+			}
+			for i := range got {
+				if got[i].ID != tt.expected[i] {
+					t.Errorf("expected task %s at %d, got %s", tt.expected[i], i, got[i].ID)
+				}
+			}
+		})
+	}
+
+	t.Run("user cannot delete task of others", func(t *testing.T) {
+		err := u.DeleteTask(withUser(report, models.UserRole), other.ID, report)
+		if !models.IsErrForbidden(err) {
+			t.Fatalf("expected forbidden error, got %v", err)
+		}
+	})
+
+	t.Run("user cannot view tasks of manager", func(t *testing.T) {
+		_, err := u.GetUserTasks(withUser(report, models.UserRole), manager)
+		if !models.IsErrForbidden(err) {
+			t.Fatalf("expected forbidden error, got %v", err)
+		}
+	})
+}
+
+func TestMentionSkipsTerminatedUser(t *testing.T) {
+	var (
+		active     = models.User{ID: uuid.New(), FirstName: "Ann", LastName: "Lee"}
+		terminated = models.User{ID: uuid.New(), FirstName: "Bob", LastName: "Ray", Status: models.Terminated}
+		u          = &taskUsecase{userRepo: &userRepoStub{users: map[string]models.User{
+			active.ID.String():     active,
+			terminated.ID.String(): terminated,
+		}}}
+		task = models.TaskElastic{Description: "ask @" + active.ID.String() + " and @" + terminated.ID.String()}
+	)
+
+	mentions, err := u.resolveMentions(context.Background(), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mentions) != 1 || mentions[0].UserID != active.ID.String() {
+		t.Fatalf("expected only active user to be mentioned, got %+v", mentions)
+	}
+}
+
+func TestResolveAbsentAssignee(t *testing.T) {
+	var (
+		userID     = uuid.New().String()
+		delegateID = uuid.New().String()
+		vacation   = models.VacationDB{UserID: userID, UserFullName: "Ann Lee", DelegateID: delegateID, DelegateFullName: "Bob Ray",
+			EndDate: time.Now().UTC().AddDate(0, 0, 3)}
+	)
+
+	tests := []struct {
+		name     string
+		delegate models.User
+		expected string
+	}{
+		{name: "active delegate takes the task", delegate: models.User{ID: uuid.MustParse(delegateID), Status: models.Active}, expected: delegateID},
+		{name: "terminated delegate is skipped", delegate: models.User{ID: uuid.MustParse(delegateID), Status: models.Terminated}, expected: userID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &taskUsecase{
+				userRepo:     &userRepoStub{users: map[string]models.User{delegateID: tt.delegate}},
+				vacationRepo: &vacationRepoStub{vacations: []models.VacationDB{vacation}},
+			}
+
+			assignee, note, err := u.resolveAbsentAssignee(context.Background(), userID)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if assignee != tt.expected {
+				t.Errorf("expected task to be assigned to %s, got %s", tt.expected, assignee)
+			}
+			if note == "" {
+				t.Error("expected note about absent assignee")
+			}
+		})
+	}
+}
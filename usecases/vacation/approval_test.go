@@ -0,0 +1,168 @@
+package vacation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildApprovalChain(t *testing.T) {
+	var (
+		approverID = "5b0a3c3e-9e4f-4a8e-8d5c-6a3f0f0c1a11"
+		managerID  = "0c1e2f3a-4b5c-4d6e-8f70-8192a3b4c5d6"
+		chain      = []models.ApprovalRule{
+			{Approver: models.ManagerApprover},
+			{Approver: models.UserApprover, UserID: approverID, LongerThanDays: 10},
+			{Approver: models.AdminApprover, LongerThanDays: 20},
+		}
+	)
+
+	tests := []struct {
+		name      string
+		chain     []models.ApprovalRule
+		days      int
+		managerID string
+		want      []models.VacationApproval
+	}{
+		{
+			name:      "short vacation needs only manager",
+			chain:     chain,
+			days:      5,
+			managerID: managerID,
+			want: []models.VacationApproval{
+				{Step: 1, ApproverType: models.ManagerApprover, ApproverID: managerID},
+			},
+		},
+		{
+			name:      "step is required only when vacation is longer than its days",
+			chain:     chain,
+			days:      11,
+			managerID: managerID,
+			want: []models.VacationApproval{
+				{Step: 1, ApproverType: models.ManagerApprover, ApproverID: managerID},
+				{Step: 2, ApproverType: models.UserApprover, ApproverID: approverID},
+			},
+		},
+		{
+			name:      "requester without manager is approved by admin",
+			chain:     chain,
+			days:      21,
+			managerID: "",
+			want: []models.VacationApproval{
+				{Step: 1, ApproverType: models.AdminApprover},
+				{Step: 2, ApproverType: models.UserApprover, ApproverID: approverID},
+				{Step: 3, ApproverType: models.AdminApprover},
+			},
+		},
+		{
+			name:  "admin approves if no step is required",
+			chain: []models.ApprovalRule{{Approver: models.UserApprover, UserID: approverID, LongerThanDays: 10}},
+			days:  3,
+			want: []models.VacationApproval{
+				{Step: 1, ApproverType: models.AdminApprover},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				u        = &vacationsUsecase{approvalChain: tt.chain}
+				start    = time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+				vacation = models.VacationDB{StartDate: start, EndDate: start.AddDate(0, 0, tt.days-1)}
+			)
+
+			got := u.buildApprovalChain(vacation, tt.managerID)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d steps, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, step := range got {
+				want := tt.want[i]
+				if step.Step != want.Step || step.ApproverType != want.ApproverType || step.ApproverID != want.ApproverID {
+					t.Errorf("step %d: got %+v, want %+v", i+1, step, want)
+				}
+				if step.Decision != models.Pending {
+					t.Errorf("step %d: got decision %s, want %s", i+1, step.Decision, models.Pending)
+				}
+			}
+		})
+	}
+}
+
+func TestApprovalRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule    models.ApprovalRule
+		wantErr bool
+	}{
+		{rule: models.ApprovalRule{Approver: models.AdminApprover}},
+		{rule: models.ApprovalRule{Approver: models.ManagerApprover, LongerThanDays: 3}},
+		{rule: models.ApprovalRule{Approver: models.UserApprover, UserID: "5b0a3c3e-9e4f-4a8e-8d5c-6a3f0f0c1a11"}},
+		{rule: models.ApprovalRule{Approver: models.UserApprover}, wantErr: true},
+		{rule: models.ApprovalRule{Approver: models.UserApprover, UserID: "hr"}, wantErr: true},
+		{rule: models.ApprovalRule{Approver: "team"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := tt.rule.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) err=%v, wantErr=%t", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+// orgStub resolves chain of managers by following direct managers up
+type orgStub struct {
+	org.OrgUsecase
+	managers map[string]string
+}
+
+func (o *orgStub) GetChain(_ context.Context, userID string) ([]models.User, error) {
+	chain := make([]models.User, 0)
+	for manager := o.managers[userID]; manager != ""; manager = o.managers[manager] {
+		chain = append(chain, models.User{ID: uuid.MustParse(manager)})
+	}
+	return chain, nil
+}
+
+func TestCanDecideManagerStep(t *testing.T) {
+	var (
+		head     = uuid.New().String()
+		manager  = uuid.New().String()
+		employee = uuid.New().String()
+		u        = &vacationsUsecase{authorizer: access.NewAuthorizer(&orgStub{managers: map[string]string{
+			employee: manager,
+			manager:  head,
+		}})}
+		vacation = models.VacationDB{UserID: employee}
+		step     = models.VacationApproval{ApproverType: models.ManagerApprover, ApproverID: manager, Decision: models.Pending}
+	)
+
+	tests := []struct {
+		name     string
+		ua       models.UserAccess
+		expected bool
+	}{
+		{name: "direct manager", ua: models.UserAccess{UserID: manager, Role: models.UserRole}, expected: true},
+		{name: "manager of manager", ua: models.UserAccess{UserID: head, Role: models.ManagerRole}, expected: true},
+		{name: "hr", ua: models.UserAccess{UserID: uuid.New().String(), Role: models.HRRole}, expected: true},
+		{name: "manager outside subtree", ua: models.UserAccess{UserID: uuid.New().String(), Role: models.ManagerRole}, expected: false},
+		{name: "requester with manager role", ua: models.UserAccess{UserID: employee, Role: models.ManagerRole}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.canDecide(context.Background(), tt.ua, step, vacation)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("expected %t, got %t", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package vacation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+)
+
+type balanceRepoStub struct {
+	userID string
+	year   int
+	days   int
+	calls  int
+	// legacy is used days of vacations approved before balances were kept
+	legacy int
+	used   *int
+}
+
+func (r *balanceRepoStub) AddUsedDays(_ context.Context, userID string, year, days int) error {
+	r.userID, r.year, r.days = userID, year, days
+	r.calls++
+	if r.used == nil {
+		r.used = new(int)
+	}
+	*r.used += days
+	return nil
+}
+
+func (r *balanceRepoStub) Backfill(_ context.Context, _ string, _ int) error {
+	if r.used == nil {
+		used := r.legacy
+		r.used = &used
+	}
+	return nil
+}
+
+func (r *balanceRepoStub) Get(_ context.Context, userID string, year int) (models.VacationBalance, error) {
+	balance := models.VacationBalance{UserID: userID, Year: year}
+	if r.used != nil {
+		balance.UsedDays = *r.used
+	}
+	return balance, nil
+}
+
+func TestRefund(t *testing.T) {
+	var (
+		start    = time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC)
+		end      = time.Date(2021, 1, 6, 0, 0, 0, 0, time.UTC)
+		vacation = models.VacationDB{UserID: "user", StartDate: start, EndDate: end}
+	)
+
+	tests := []struct {
+		name    string
+		endDate time.Time
+		want    int
+	}{
+		{name: "canceled vacation returns all days", endDate: end, want: -10},
+		{name: "shortened vacation returns days after new end date", endDate: time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC), want: -4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &balanceRepoStub{}
+			u := &vacationsUsecase{balanceRepo: repo}
+
+			if err := u.refund(context.Background(), vacation, tt.endDate); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if repo.days != tt.want {
+				t.Errorf("expected %d days, got %d", tt.want, repo.days)
+			}
+			if repo.userID != vacation.UserID || repo.year != start.Year() {
+				t.Errorf("expected balance of %s in %d, got %s in %d", vacation.UserID, start.Year(), repo.userID, repo.year)
+			}
+		})
+	}
+}
+
+func TestBalanceBackfillsLegacyVacations(t *testing.T) {
+	var (
+		start    = time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC)
+		vacation = models.VacationDB{UserID: "user", StartDate: start, EndDate: start.AddDate(0, 0, 2)}
+		repo     = &balanceRepoStub{legacy: 3}
+		u        = &vacationsUsecase{balanceRepo: repo}
+	)
+
+	if err := u.refund(context.Background(), vacation, vacation.EndDate); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if *repo.used != 0 {
+		t.Fatalf("expected refund of vacation approved before balances to leave 0 used days, got %d", *repo.used)
+	}
+
+	user := "user"
+	repo = &balanceRepoStub{legacy: 5}
+	u = &vacationsUsecase{
+		balanceRepo: repo,
+		authorizer:  access.NewAuthorizer(&orgStub{}),
+		daysPerYear: 24,
+	}
+
+	balance, err := u.GetBalance(withUser(user, models.UserRole), user)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if balance.UsedDays != 5 || balance.LeftDays != 19 {
+		t.Fatalf("expected 5 used and 19 left days, got %d used and %d left", balance.UsedDays, balance.LeftDays)
+	}
+}
@@ -0,0 +1,75 @@
+package vacation
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+)
+
+// maxCalendarDays limits period of calendar which can be requested at once
+const maxCalendarDays = 366
+
+// GetCalendar returns team absences for every day of the period including both from and to dates,
+// absences can be filtered by position of absent user
+func (u *vacationsUsecase) GetCalendar(ctx context.Context, from, to time.Time, position string) ([]models.CalendarDay, error) {
+	txID := transactionID.FromContext(ctx)
+
+	days := vacationDays(from, to)
+	if days < 1 || days > maxCalendarDays {
+		return nil, models.NewErrInvalidData("calendar period should contain from 1 to %d days", maxCalendarDays)
+	}
+
+	vacations, err := u.VacationRepository.GetApprovedBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := make([]models.CalendarDay, 0, days)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(dateLayout)
+		calendar = append(calendar, models.CalendarDay{
+			Date:      date,
+			IsWeekend: day.Weekday() == time.Saturday || day.Weekday() == time.Sunday,
+			Holiday:   u.holidays[date],
+			Absences:  make([]models.Absence, 0),
+		})
+	}
+
+	users := make(map[string]models.User)
+	for _, vacation := range vacations {
+		user, ok := users[vacation.UserID]
+		if !ok {
+			user, err = u.userRepo.GetUserByID(ctx, vacation.UserID)
+			if err != nil {
+				u.log.Warnf(txID, "skipping vacation due to invalid userID=%s", vacation.UserID)
+				continue
+			}
+			users[vacation.UserID] = user
+		}
+
+		if position != "" && !strings.EqualFold(user.Position, position) {
+			continue
+		}
+
+		absence := models.Absence{
+			UserID:       vacation.UserID,
+			UserFullName: vacation.UserFullName,
+			Position:     user.Position,
+			VacationID:   vacation.ID,
+			LeaveType:    models.VacationLeave,
+		}
+
+		for i := range calendar {
+			day := from.AddDate(0, 0, i)
+			if day.Before(vacation.StartDate) || day.After(vacation.EndDate) {
+				continue
+			}
+			calendar[i].Absences = append(calendar[i].Absences, absence)
+		}
+	}
+
+	return calendar, nil
+}
@@ -0,0 +1,118 @@
+package vacation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/util"
+)
+
+const (
+	feedTokenSize = 32
+
+	// feed contains absences which finished not earlier than feedPastDays ago and start in next feedFutureDays
+	feedPastDays   = 90
+	feedFutureDays = 365
+)
+
+// IssueFeedToken creates new feed token for current user, previously issued tokens are revoked
+func (u *vacationsUsecase) IssueFeedToken(ctx context.Context) (*models.FeedToken, error) {
+	userAccess := util.GetUserAccessFromCtx(ctx)
+
+	secret := make([]byte, feedTokenSize)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.feedTokenRepo.DeleteForUser(ctx, userAccess.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	token := models.FeedToken{
+		Token:     hex.EncodeToString(secret),
+		UserID:    userAccess.UserID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	stored := token
+	stored.Token = hashFeedToken(token.Token)
+	return &token, u.feedTokenRepo.Save(ctx, stored)
+}
+
+// RevokeFeedToken revokes all feed tokens of current user
+func (u *vacationsUsecase) RevokeFeedToken(ctx context.Context) error {
+	userAccess := util.GetUserAccessFromCtx(ctx)
+	return u.feedTokenRepo.DeleteForUser(ctx, userAccess.UserID)
+}
+
+// GetFeed returns approved absences and holidays for feed token owner,
+// absences of the whole team are returned if userID is empty
+func (u *vacationsUsecase) GetFeed(ctx context.Context, token, userID string) ([]models.FeedEvent, error) {
+	hash := hashFeedToken(token)
+	stored, err := u.feedTokenRepo.GetByToken(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// tokens are revoked on termination, the check covers tokens of users terminated before that
+	owner, err := u.userRepo.GetUserByID(ctx, stored.UserID)
+	if models.IsErrNotFound(err) || (err == nil && owner.IsTerminated()) {
+		return nil, models.NewErrNotFound("feed token is not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		from = today().AddDate(0, 0, -feedPastDays)
+		to   = today().AddDate(0, 0, feedFutureDays)
+	)
+
+	vacations, err := u.VacationRepository.GetApprovedBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]models.FeedEvent, 0, len(vacations)+len(u.holidays))
+	for _, vacation := range vacations {
+		if userID != "" && vacation.UserID != userID {
+			continue
+		}
+
+		events = append(events, models.FeedEvent{
+			UID:     fmt.Sprintf("vacation-%s", vacation.ID),
+			Summary: fmt.Sprintf("%s: %s", vacation.UserFullName, models.VacationLeave),
+			Start:   vacation.StartDate,
+			End:     vacation.EndDate,
+		})
+	}
+
+	for date, name := range u.holidays {
+		day, err := time.Parse(dateLayout, date)
+		if err != nil || day.Before(from) || day.After(to) {
+			continue
+		}
+
+		events = append(events, models.FeedEvent{
+			UID:     fmt.Sprintf("holiday-%s", date),
+			Summary: name,
+			Start:   day,
+			End:     day,
+		})
+	}
+
+	return events, nil
+}
+
+// hashFeedToken returns hash of the token which is stored instead of the token itself
+func hashFeedToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,85 @@
+package vacation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+)
+
+type feedTokenRepoStub struct {
+	repository.FeedTokenRepository
+	tokens map[string]models.FeedToken
+}
+
+func (r *feedTokenRepoStub) GetByToken(_ context.Context, token string) (models.FeedToken, error) {
+	stored, ok := r.tokens[token]
+	if !ok {
+		return models.FeedToken{}, models.NewErrNotFound("feed token is not found")
+	}
+	return stored, nil
+}
+
+func TestHashFeedToken(t *testing.T) {
+	token := "4f1c2b"
+
+	hash := hashFeedToken(token)
+	if hash == token {
+		t.Fatal("expected token to be hashed")
+	}
+	if len(hash) != 64 {
+		t.Errorf("expected hex encoded SHA-256 of 64 characters, got %d", len(hash))
+	}
+	if hashFeedToken(token) != hash {
+		t.Error("expected hash to be stable")
+	}
+	if hashFeedToken(token+"0") == hash {
+		t.Error("expected different tokens to have different hashes")
+	}
+}
+
+func TestGetFeedRejectsTerminatedOwner(t *testing.T) {
+	var (
+		token    = "4f1c2b"
+		active   = models.User{ID: uuid.New(), Status: models.Active}
+		vacation = models.VacationDB{ID: uuid.New(), UserID: active.ID.String(), Status: models.Approved, StartDate: today(), EndDate: today()}
+	)
+
+	tests := []struct {
+		name     string
+		owner    models.User
+		notFound bool
+	}{
+		{name: "active owner", owner: active},
+		{name: "terminated owner", owner: models.User{ID: uuid.New(), Status: models.Terminated}, notFound: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &vacationsUsecase{
+				VacationRepository: &vacationRepoStub{vacations: map[string]models.VacationDB{vacation.ID.String(): vacation}},
+				feedTokenRepo: &feedTokenRepoStub{tokens: map[string]models.FeedToken{
+					hashFeedToken(token): {Token: hashFeedToken(token), UserID: tt.owner.ID.String()},
+				}},
+				userRepo: &userRepoStub{users: map[string]models.User{tt.owner.ID.String(): tt.owner}},
+			}
+
+			events, err := u.GetFeed(context.Background(), token, "")
+			if tt.notFound {
+				if !models.IsErrNotFound(err) {
+					t.Fatalf("expected not found, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(events) != 1 {
+				t.Fatalf("expected one absence, got %d", len(events))
+			}
+		})
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"github.com/Dimitriy14/staff-manager/logger"
 	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
 	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbox"
 	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
 	"github.com/Dimitriy14/staff-manager/util"
 	"github.com/senseyeio/spaniel"
 
@@ -17,31 +20,87 @@ import (
 
 type VacationsUsecase interface {
 	Save(ctx context.Context, vacation models.VacationDB) (*models.Vacation, error)
-	UpdateVacationStatus(ctx context.Context, vacationID uuid.UUID, status models.VacationStatus) (*models.Vacation, error)
+	Cancel(ctx context.Context, vacationID uuid.UUID) (*models.Vacation, error)
+	CancelUpcoming(ctx context.Context, userID, comment string) ([]models.Vacation, error)
+	// ClearDelegate removes the user from delegates of not finished vacations, it is used when user leaves the company
+	ClearDelegate(ctx context.Context, delegateID string) error
+	DecideCancellation(ctx context.Context, vacationID uuid.UUID, decision models.VacationStatusUpdate) (*models.Vacation, error)
+	WithdrawCancellation(ctx context.Context, vacationID uuid.UUID) (*models.Vacation, error)
+	GetBalance(ctx context.Context, userID string) (models.VacationBalance, error)
+	Shorten(ctx context.Context, vacationID uuid.UUID, endDate time.Time) (*models.Vacation, error)
+	Decide(ctx context.Context, vacationID uuid.UUID, decision models.VacationStatusUpdate) (*models.Vacation, error)
+	AddComment(ctx context.Context, vacationID uuid.UUID, text string) (*models.VacationComment, error)
+	GetComments(ctx context.Context, vacationID uuid.UUID) ([]models.VacationComment, error)
 	GetAll(ctx context.Context) ([]models.Vacation, error)
 	GetPending(ctx context.Context) ([]models.Vacation, error)
 	GetForUser(ctx context.Context, userID string) ([]models.Vacation, error)
+	// GetByID returns vacation visible to requester, approvers and those who manage vacations of the requester
 	GetByID(ctx context.Context, vacationID uuid.UUID) (*models.Vacation, error)
 	SetExpired(ctx context.Context)
+	GetCalendar(ctx context.Context, from, to time.Time, position string) ([]models.CalendarDay, error)
+	IssueFeedToken(ctx context.Context) (*models.FeedToken, error)
+	RevokeFeedToken(ctx context.Context) error
+	GetFeed(ctx context.Context, token, userID string) ([]models.FeedEvent, error)
 }
 
+const dateLayout = "2006-01-02"
+
+// defaultApprovalChain keeps single admin approval when chain is not configured
+var defaultApprovalChain = []models.ApprovalRule{{Approver: models.AdminApprover}}
+
 func NewVacationUseCase(vacationRepo repository.VacationRepository,
+	approvalRepo repository.VacationApprovalRepository,
+	commentRepo repository.VacationCommentRepository,
+	feedTokenRepo repository.FeedTokenRepository,
+	balanceRepo repository.VacationBalanceRepository,
 	userRepo repository.UserRepository,
-	recentChangesRepo repository.RecentActionRepository,
+	tx repository.Transactor,
+	outboxRepo repository.OutboxRepository,
+	authorizer access.Authorizer,
+	approvalChain []models.ApprovalRule,
+	daysPerYear int,
+	holidays []models.Holiday,
 	log logger.Logger) *vacationsUsecase {
+	if len(approvalChain) == 0 {
+		approvalChain = defaultApprovalChain
+	}
+
+	holidayNames := make(map[string]string, len(holidays))
+	for _, h := range holidays {
+		holidayNames[h.Date] = h.Name
+	}
+
 	return &vacationsUsecase{
 		VacationRepository: vacationRepo,
+		approvalRepo:       approvalRepo,
+		commentRepo:        commentRepo,
+		feedTokenRepo:      feedTokenRepo,
+		balanceRepo:        balanceRepo,
 		userRepo:           userRepo,
-		recentChangesRepo:  recentChangesRepo,
+		tx:                 tx,
+		outboxRepo:         outboxRepo,
+		authorizer:         authorizer,
+		approvalChain:      approvalChain,
+		daysPerYear:        daysPerYear,
+		holidays:           holidayNames,
 		log:                log,
 	}
 }
 
 type vacationsUsecase struct {
 	repository.VacationRepository
-	userRepo          repository.UserRepository
-	recentChangesRepo repository.RecentActionRepository
-	log               logger.Logger
+	approvalRepo  repository.VacationApprovalRepository
+	commentRepo   repository.VacationCommentRepository
+	feedTokenRepo repository.FeedTokenRepository
+	balanceRepo   repository.VacationBalanceRepository
+	userRepo      repository.UserRepository
+	tx            repository.Transactor
+	outboxRepo    repository.OutboxRepository
+	authorizer    access.Authorizer
+	approvalChain []models.ApprovalRule
+	daysPerYear   int
+	holidays      map[string]string
+	log           logger.Logger
 }
 
 func (u *vacationsUsecase) Save(ctx context.Context, vacation models.VacationDB) (*models.Vacation, error) {
@@ -63,81 +122,745 @@ func (u *vacationsUsecase) Save(ctx context.Context, vacation models.VacationDB)
 		return nil, err
 	}
 
+	if vacation.DelegateID != "" {
+		if vacation.DelegateID == vacation.UserID {
+			return nil, models.NewErrInvalidData("user cannot be a delegate on own vacation")
+		}
+
+		delegate, err := u.userRepo.GetUserByID(ctx, vacation.DelegateID)
+		if err != nil {
+			if models.IsErrNotFound(err) {
+				return nil, models.NewErrInvalidData("delegate with id = %s is not found", vacation.DelegateID)
+			}
+			return nil, err
+		}
+		vacation.DelegateFullName = fmt.Sprintf("%s %s", delegate.FirstName, delegate.LastName)
+	}
+
 	vacation.ID = uuid.New()
 	vacation.UserFullName = fmt.Sprintf("%s %s", user.FirstName, user.LastName)
 	vacation.UpdateTime = time.Now().UTC()
 	vacation.Status = models.Pending
 
-	createdVacation, err := u.VacationRepository.Save(ctx, vacation)
+	return u.inTransaction(ctx, func(ctx context.Context) (*models.Vacation, error) {
+		createdVacation, err := u.VacationRepository.Save(ctx, vacation)
+		if err != nil {
+			return nil, err
+		}
+
+		approvals := u.buildApprovalChain(*createdVacation, user.ManagerID)
+		err = u.approvalRepo.Save(ctx, approvals...)
+		if err != nil {
+			return nil, err
+		}
+
+		vac := copyToVacation(*createdVacation)
+		vac.User = &user
+		vac.Approvals = approvals
+		audit.SetAction(ctx, "vacation.create")
+		audit.SetChange(ctx, "vacation", vacation.ID.String(), nil, createdVacation)
+
+		err = u.publish(ctx, models.RecentChanges{
+			ID:         uuid.New(),
+			Title:      fmt.Sprintf("%d Vacation", vac.Number),
+			IncidentID: vacation.ID,
+			Type:       models.VacationRequest,
+			UserName:   vacation.UserFullName,
+			UserID:     user.ID.String(),
+			OwnerID:    user.ID.String(),
+			ChangeTime: vacation.UpdateTime,
+			Status:     string(vacation.Status),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &vac, audit.Queue(ctx)
+	})
+}
+
+// Cancel cancels vacation on behalf of requester. Pending vacation is canceled immediately,
+// cancellation of Approved vacation which has not started yet should be signed off by an approver.
+func (u *vacationsUsecase) Cancel(ctx context.Context, vacationID uuid.UUID) (*models.Vacation, error) {
+	return u.inTransaction(ctx, func(ctx context.Context) (*models.Vacation, error) {
+		userAccess := util.GetUserAccessFromCtx(ctx)
+		vacation, err := u.VacationRepository.GetForUpdate(ctx, vacationID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if vacation.UserID != userAccess.UserID {
+			return nil, models.NewErrForbidden("only requester can cancel vacation with id = %s", vacation.ID)
+		}
+
+		switch {
+		case vacation.Status == models.Pending:
+			err := u.skipPendingApprovals(ctx, vacation.ID)
+			if err != nil {
+				return nil, err
+			}
+			return u.changeStatus(ctx, vacation, models.Canceled, "")
+		case vacation.Status == models.Approved && vacation.StartDate.After(today()):
+			return u.changeStatus(ctx, vacation, models.CancelRequested, "")
+		case vacation.Status == models.Approved:
+			return nil, models.NewErrInvalidData("vacation with id = %s has already started, it can be only shortened", vacation.ID)
+		}
+		return nil, models.NewErrInvalidData("vacation with id = %s has status %s and cannot be canceled", vacation.ID, vacation.Status)
+	})
+}
+
+// CancelUpcoming cancels vacations of the user which have not started yet without sign-off of approvers,
+// it is used when user leaves the company
+func (u *vacationsUsecase) CancelUpcoming(ctx context.Context, userID, comment string) ([]models.Vacation, error) {
+	vacations, err := u.VacationRepository.GetForUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	vac := copyToVacation(*createdVacation)
-	vac.User = &user
+	canceled := make([]models.Vacation, 0)
+	for i := range vacations {
+		vacation := &vacations[i]
+		if !vacation.StartDate.After(today()) {
+			continue
+		}
+
+		switch vacation.Status {
+		case models.Pending, models.Approved, models.CancelRequested:
+		default:
+			continue
+		}
+
+		vac, err := u.inTransaction(ctx, func(ctx context.Context) (*models.Vacation, error) {
+			err := u.skipPendingApprovals(ctx, vacation.ID)
+			if err != nil {
+				return nil, err
+			}
 
-	err = u.recentChangesRepo.Save(models.RecentChanges{
-		ID:         uuid.New(),
-		Title:      fmt.Sprintf("%d Vacation", vac.Number),
-		IncidentID: vacation.ID,
-		Type:       models.VacationRequest,
-		UserName:   vacation.UserFullName,
-		UserID:     user.ID.String(),
-		OwnerID:    user.ID.String(),
-		ChangeTime: vacation.UpdateTime,
-		Status:     string(vacation.Status),
+			if vacation.Status != models.Pending {
+				err = u.refund(ctx, *vacation, vacation.EndDate)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			vacation.WasApproved = false
+			return u.changeStatus(ctx, vacation, models.Canceled, comment)
+		})
+		if err != nil {
+			return nil, err
+		}
+		canceled = append(canceled, *vac)
+	}
+	return canceled, nil
+}
+
+// ClearDelegate removes the user from delegates of not finished vacations, tasks of such vacations stay with requesters
+func (u *vacationsUsecase) ClearDelegate(ctx context.Context, delegateID string) error {
+	vacations, err := u.VacationRepository.GetDelegatedTo(ctx, delegateID, today())
+	if err != nil {
+		return err
+	}
+
+	for _, vacation := range vacations {
+		err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+			vacation, err := u.VacationRepository.GetForUpdate(ctx, vacation.ID.String())
+			if err != nil || vacation.DelegateID != delegateID {
+				return err
+			}
+
+			vacation.DelegateID = ""
+			vacation.DelegateFullName = ""
+			vacation.UpdateTime = time.Now().UTC()
+			return u.VacationRepository.Update(ctx, *vacation)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecideCancellation approves or rejects cancellation of Approved vacation, any approver of the vacation can do it.
+// Approved cancellation returns vacation days to requester, rejected one keeps vacation Approved.
+func (u *vacationsUsecase) DecideCancellation(ctx context.Context, vacationID uuid.UUID, decision models.VacationStatusUpdate) (*models.Vacation, error) {
+	return u.inTransaction(ctx, func(ctx context.Context) (*models.Vacation, error) {
+		userAccess := util.GetUserAccessFromCtx(ctx)
+		vacation, err := u.VacationRepository.GetForUpdate(ctx, vacationID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if vacation.Status != models.CancelRequested {
+			return nil, models.NewErrInvalidData("cancellation of vacation with id = %s is not requested", vacation.ID)
+		}
+
+		isApprover, err := u.isApprover(ctx, userAccess, *vacation)
+		if err != nil {
+			return nil, err
+		}
+		if !isApprover {
+			return nil, models.NewErrForbidden("user %s is not an approver of vacation with id = %s", userAccess.UserID, vacation.ID)
+		}
+
+		if decision.Status == models.Rejected {
+			return u.changeStatus(ctx, vacation, models.Approved, decision.Comment)
+		}
+
+		if !vacation.StartDate.After(today()) {
+			return nil, models.NewErrInvalidData("vacation with id = %s has already started, it can be only shortened", vacation.ID)
+		}
+
+		err = u.refund(ctx, *vacation, vacation.EndDate)
+		if err != nil {
+			return nil, err
+		}
+
+		vacation.WasApproved = false
+		return u.changeStatus(ctx, vacation, models.Canceled, decision.Comment)
 	})
+}
+
+// WithdrawCancellation keeps vacation Approved when requester changes their mind before cancellation is decided
+func (u *vacationsUsecase) WithdrawCancellation(ctx context.Context, vacationID uuid.UUID) (*models.Vacation, error) {
+	return u.inTransaction(ctx, func(ctx context.Context) (*models.Vacation, error) {
+		userAccess := util.GetUserAccessFromCtx(ctx)
+		vacation, err := u.VacationRepository.GetForUpdate(ctx, vacationID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if vacation.UserID != userAccess.UserID {
+			return nil, models.NewErrForbidden("only requester can withdraw cancellation of vacation with id = %s", vacation.ID)
+		}
+
+		if vacation.Status != models.CancelRequested {
+			return nil, models.NewErrInvalidData("cancellation of vacation with id = %s is not requested", vacation.ID)
+		}
 
-	return &vac, err
+		return u.changeStatus(ctx, vacation, models.Approved, "cancellation withdrawn")
+	})
 }
 
-func (u *vacationsUsecase) UpdateVacationStatus(ctx context.Context, vacationID uuid.UUID, status models.VacationStatus) (*models.Vacation, error) {
-	userAcces := util.GetUserAccessFromCtx(ctx)
-	oldVacation, err := u.VacationRepository.GetByID(ctx, vacationID.String())
+// GetBalance returns vacation days used and left in the current year
+func (u *vacationsUsecase) GetBalance(ctx context.Context, userID string) (models.VacationBalance, error) {
+	userAccess := util.GetUserAccessFromCtx(ctx)
+	canView, err := u.authorizer.Can(ctx, userAccess, models.ManageVacations, userID)
 	if err != nil {
-		return nil, err
+		return models.VacationBalance{}, err
+	}
+	if !canView {
+		return models.VacationBalance{}, models.NewErrForbidden("user %s cannot view vacation balance of user %s", userAccess.UserID, userID)
+	}
+
+	err = u.balanceRepo.Backfill(ctx, userID, today().Year())
+	if err != nil {
+		return models.VacationBalance{}, err
+	}
+
+	balance, err := u.balanceRepo.Get(ctx, userID, today().Year())
+	if err != nil {
+		return models.VacationBalance{}, err
 	}
 
-	user, err := u.userRepo.GetUserByID(ctx, oldVacation.UserID)
+	balance.LeftDays = u.daysPerYear - balance.UsedDays
+	return balance, nil
+}
+
+// refund returns to the user days of approved vacation which are after end date
+func (u *vacationsUsecase) refund(ctx context.Context, vacation models.VacationDB, endDate time.Time) error {
+	days := vacationDays(vacation.StartDate, vacation.EndDate)
+	if endDate.Before(vacation.EndDate) {
+		days = vacationDays(endDate, vacation.EndDate) - 1
+	}
+
+	err := u.balanceRepo.Backfill(ctx, vacation.UserID, vacation.StartDate.Year())
+	if err != nil {
+		return err
+	}
+	return u.balanceRepo.AddUsedDays(ctx, vacation.UserID, vacation.StartDate.Year(), -days)
+}
+
+// Shorten cuts in-progress vacation short by moving its end date
+func (u *vacationsUsecase) Shorten(ctx context.Context, vacationID uuid.UUID, endDate time.Time) (*models.Vacation, error) {
+	vac, err := u.inTransaction(ctx, func(ctx context.Context) (*models.Vacation, error) {
+		userAccess := util.GetUserAccessFromCtx(ctx)
+		vacation, err := u.VacationRepository.GetForUpdate(ctx, vacationID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		canManage, err := u.authorizer.Can(ctx, userAccess, models.ManageVacations, vacation.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !canManage {
+			return nil, models.NewErrForbidden("user %s cannot shorten vacation with id = %s", userAccess.UserID, vacation.ID)
+		}
+
+		now := today()
+		if vacation.Status != models.Approved || vacation.StartDate.After(now) || vacation.EndDate.Before(now) {
+			return nil, models.NewErrInvalidData("vacation with id = %s is not in progress", vacation.ID)
+		}
+
+		if endDate.Before(now) || !endDate.Before(vacation.EndDate) {
+			return nil, models.NewErrInvalidData("new end date %s should be between today and current end date %s",
+				endDate.Format(dateLayout), vacation.EndDate.Format(dateLayout))
+		}
+
+		changer, err := u.userRepo.GetUserByID(ctx, userAccess.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := u.userRepo.GetUserByID(ctx, vacation.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		before := *vacation
+		oldEndDate := vacation.EndDate
+		vacation.EndDate = endDate
+		vacation.UpdateTime = time.Now().UTC()
+
+		vac := copyToVacation(*vacation)
+		vac.User = &user
+
+		err = u.refund(ctx, before, endDate)
+		if err != nil {
+			return nil, err
+		}
+
+		err = u.VacationRepository.Update(ctx, *vacation)
+		if err != nil {
+			return nil, err
+		}
+
+		audit.SetAction(ctx, "vacation.shorten")
+		audit.SetChange(ctx, "vacation", vacation.ID.String(), before, vacation)
+
+		err = u.publish(ctx, models.RecentChanges{
+			ID:            uuid.New(),
+			Title:         fmt.Sprintf("%d Vacation shortened", vacation.Number),
+			IncidentID:    vacation.ID,
+			Type:          models.VacationShortened,
+			UserName:      vacation.UserFullName,
+			UserID:        vacation.UserID,
+			OwnerID:       vacation.UserID,
+			UpdatedByName: fmt.Sprintf("%s %s", changer.FirstName, changer.LastName),
+			UpdatedByID:   userAccess.UserID,
+			ChangeTime:    vacation.UpdateTime,
+			Status:        string(vacation.Status),
+			Comment:       fmt.Sprintf("end date moved from %s to %s", oldEndDate.Format(dateLayout), endDate.Format(dateLayout)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &vac, audit.Queue(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
+	return vac, nil
+}
 
+// changeStatus saves vacation with new status changed by current user and records it to recent changes
+func (u *vacationsUsecase) changeStatus(ctx context.Context, vacation *models.VacationDB, status models.VacationStatus, comment string) (*models.Vacation, error) {
 	userAccess := util.GetUserAccessFromCtx(ctx)
+	user, err := u.userRepo.GetUserByID(ctx, vacation.UserID)
+	if err != nil {
+		return nil, err
+	}
+
 	statusChanger, err := u.userRepo.GetUserByID(ctx, userAccess.UserID)
 	if err != nil {
 		return nil, err
 	}
 
-	oldVacation.WasApproved = status == models.Approved
-	oldVacation.Status = status
-	oldVacation.StatusChangerFullName = fmt.Sprintf("%s %s", statusChanger.FirstName, statusChanger.LastName)
-	oldVacation.StatusChangerID = userAcces.UserID
-	oldVacation.UpdateTime = time.Now().UTC()
-	vac := copyToVacation(*oldVacation)
+	before := *vacation
+	vacation.Status = status
+	vacation.StatusChangerFullName = fmt.Sprintf("%s %s", statusChanger.FirstName, statusChanger.LastName)
+	vacation.StatusChangerID = userAccess.UserID
+	vacation.StatusComment = comment
+	vacation.UpdateTime = time.Now().UTC()
+
+	vac := copyToVacation(*vacation)
 	vac.User = &user
 	vac.StatusChanger = &statusChanger
 
-	_, err = u.VacationRepository.Save(ctx, *oldVacation)
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		_, err := u.VacationRepository.Save(ctx, *vacation)
+		if err != nil {
+			return err
+		}
+
+		err = u.publish(ctx, models.RecentChanges{
+			ID:            uuid.New(),
+			Title:         fmt.Sprintf("%d Vacation %s", vac.Number, status),
+			IncidentID:    vacation.ID,
+			Type:          models.VacationStatusChange,
+			UserName:      vacation.UserFullName,
+			UserID:        vacation.UserID,
+			OwnerID:       vacation.UserID,
+			UpdatedByName: vacation.StatusChangerFullName,
+			UpdatedByID:   vacation.StatusChangerID,
+			ChangeTime:    vacation.UpdateTime,
+			Status:        string(status),
+			Comment:       comment,
+		})
+		if err != nil {
+			return err
+		}
+
+		audit.SetAction(ctx, "vacation.status_change")
+		audit.SetChange(ctx, "vacation", vacation.ID.String(), before, vacation)
+		return audit.Queue(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	err = u.recentChangesRepo.Save(models.RecentChanges{
+	return &vac, nil
+}
+
+// skipPendingApprovals marks steps which will never be decided as skipped
+func (u *vacationsUsecase) skipPendingApprovals(ctx context.Context, vacationID uuid.UUID) error {
+	approvals, err := u.approvalRepo.GetForVacation(ctx, vacationID.String())
+	if err != nil {
+		return err
+	}
+
+	for _, approval := range approvals {
+		if !approval.IsPending() {
+			continue
+		}
+
+		approval.Decision = models.Skipped
+		err = u.approvalRepo.Update(ctx, approval)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decide records decision of current approver on the first pending step of approval chain.
+// Vacation becomes Approved only when all steps are approved, any rejection rejects whole vacation.
+func (u *vacationsUsecase) Decide(ctx context.Context, vacationID uuid.UUID, decision models.VacationStatusUpdate) (*models.Vacation, error) {
+	return u.inTransaction(ctx, func(ctx context.Context) (*models.Vacation, error) {
+		userAccess := util.GetUserAccessFromCtx(ctx)
+		vacation, err := u.VacationRepository.GetForUpdate(ctx, vacationID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		if vacation.Status != models.Pending {
+			return nil, models.NewErrInvalidData("vacation with id = %s has status %s, only %s vacation can be approved or rejected",
+				vacation.ID, vacation.Status, models.Pending)
+		}
+
+		approvals, err := u.approvalRepo.GetForVacation(ctx, vacation.ID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := u.userRepo.GetUserByID(ctx, vacation.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		// vacations requested before approval chains were introduced have no steps
+		if len(approvals) == 0 {
+			approvals = u.buildApprovalChain(*vacation, user.ManagerID)
+			err = u.approvalRepo.Save(ctx, approvals...)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		current := currentApprovalStep(approvals)
+		if current < 0 {
+			return nil, models.NewErrInvalidData("vacation with id = %s has no pending approval steps", vacation.ID)
+		}
+
+		canDecide, err := u.canDecide(ctx, userAccess, approvals[current], *vacation)
+		if err != nil {
+			return nil, err
+		}
+		if !canDecide {
+			return nil, models.NewErrForbidden("user %s cannot make decision on step %d of vacation with id = %s",
+				userAccess.UserID, approvals[current].Step, vacation.ID)
+		}
+
+		approver, err := u.userRepo.GetUserByID(ctx, userAccess.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now().UTC()
+		approvals[current].ApproverID = userAccess.UserID
+		approvals[current].ApproverFullName = fmt.Sprintf("%s %s", approver.FirstName, approver.LastName)
+		approvals[current].Decision = decision.Status
+		approvals[current].Comment = decision.Comment
+		approvals[current].DecisionTime = &now
+
+		err = u.approvalRepo.Update(ctx, approvals[current])
+		if err != nil {
+			return nil, err
+		}
+
+		changeType := models.VacationStatusChange
+		switch {
+		case decision.Status == models.Rejected:
+			vacation.Status = models.Rejected
+			for i := current + 1; i < len(approvals); i++ {
+				approvals[i].Decision = models.Skipped
+				err = u.approvalRepo.Update(ctx, approvals[i])
+				if err != nil {
+					return nil, err
+				}
+			}
+		case current == len(approvals)-1:
+			// balance is backfilled while vacation is not saved as Approved yet, so it is charged once
+			err = u.balanceRepo.Backfill(ctx, vacation.UserID, vacation.StartDate.Year())
+			if err != nil {
+				return nil, err
+			}
+			vacation.Status = models.Approved
+			vacation.WasApproved = true
+		default:
+			changeType = models.VacationApprovalStep
+		}
+
+		vacation.StatusChangerFullName = approvals[current].ApproverFullName
+		vacation.StatusChangerID = userAccess.UserID
+		vacation.StatusComment = decision.Comment
+		vacation.UpdateTime = now
+
+		_, err = u.VacationRepository.Save(ctx, *vacation)
+		if err != nil {
+			return nil, err
+		}
+
+		if vacation.Status == models.Approved {
+			err = u.balanceRepo.AddUsedDays(ctx, vacation.UserID, vacation.StartDate.Year(), vacationDays(vacation.StartDate, vacation.EndDate))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		vac := copyToVacation(*vacation)
+		vac.User = &user
+		vac.StatusChanger = &approver
+		vac.Approvals = approvals
+
+		changes := []models.RecentChanges{{
+			ID:            uuid.New(),
+			Title:         fmt.Sprintf("%d Vacation %s (step %d of %d)", vac.Number, decision.Status, approvals[current].Step, len(approvals)),
+			IncidentID:    vacation.ID,
+			Type:          changeType,
+			UserName:      vacation.UserFullName,
+			UserID:        user.ID.String(),
+			OwnerID:       user.ID.String(),
+			UpdatedByName: vacation.StatusChangerFullName,
+			UpdatedByID:   vacation.StatusChangerID,
+			ChangeTime:    now,
+			Status:        string(vacation.Status),
+			Comment:       decision.Comment,
+		}}
+
+		if vacation.Status == models.Approved && vacation.DelegateID != "" {
+			changes = append(changes, delegationChange(*vacation, approver))
+		}
+
+		return &vac, u.publish(ctx, changes...)
+	})
+}
+
+// delegationChange tells delegate that tasks of the user should be covered during approved vacation
+func delegationChange(vacation models.VacationDB, approver models.User) models.RecentChanges {
+	return models.RecentChanges{
 		ID:            uuid.New(),
-		Title:         fmt.Sprintf("%d Vacation %s", vac.Number, status),
-		IncidentID:    oldVacation.ID,
-		Type:          models.VacationStatusChange,
-		UserName:      fmt.Sprintf("%s %s", user.FirstName, user.LastName),
-		UserID:        user.ID.String(),
-		OwnerID:       user.ID.String(),
-		UpdatedByName: oldVacation.StatusChangerFullName,
-		UpdatedByID:   oldVacation.StatusChangerID,
-		ChangeTime:    oldVacation.UpdateTime,
-		Status:        string(status),
+		Title:         fmt.Sprintf("%d Vacation coverage %s - %s", vacation.Number, vacation.StartDate.Format(dateLayout), vacation.EndDate.Format(dateLayout)),
+		IncidentID:    vacation.ID,
+		Type:          models.Delegation,
+		UserName:      vacation.DelegateFullName,
+		UserID:        vacation.DelegateID,
+		OwnerID:       vacation.UserID,
+		UpdatedByName: fmt.Sprintf("%s %s", approver.FirstName, approver.LastName),
+		UpdatedByID:   approver.ID.String(),
+		ChangeTime:    vacation.UpdateTime,
+		Status:        string(vacation.Status),
+		Comment:       fmt.Sprintf("%s covers tasks of %s", vacation.DelegateFullName, vacation.UserFullName),
+	}
+}
+
+// publish writes recent changes to outbox, they are saved and delivered to notification channels by relay
+func (u *vacationsUsecase) publish(ctx context.Context, changes ...models.RecentChanges) error {
+	events := make([]models.OutboxEvent, 0, len(changes))
+	for _, change := range changes {
+		event, err := outbox.NewEvent(models.RecentChangeTopic, change)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	return u.outboxRepo.Save(ctx, events...)
+}
+
+// inTransaction runs fn in transaction so vacation changes and their events are written together
+func (u *vacationsUsecase) inTransaction(ctx context.Context, fn func(ctx context.Context) (*models.Vacation, error)) (*models.Vacation, error) {
+	var vac *models.Vacation
+	err := u.tx.InTransaction(ctx, func(ctx context.Context) (err error) {
+		vac, err = fn(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vac, nil
+}
+
+// AddComment adds message to vacation discussion, only requester and approvers can take part in it
+func (u *vacationsUsecase) AddComment(ctx context.Context, vacationID uuid.UUID, text string) (*models.VacationComment, error) {
+	userAccess := util.GetUserAccessFromCtx(ctx)
+	vacation, err := u.VacationRepository.GetByID(ctx, vacationID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.checkDiscussionAccess(ctx, userAccess, *vacation)
+	if err != nil {
+		return nil, err
+	}
+
+	author, err := u.userRepo.GetUserByID(ctx, userAccess.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := models.VacationComment{
+		ID:             uuid.New(),
+		VacationID:     vacation.ID,
+		AuthorID:       userAccess.UserID,
+		AuthorFullName: fmt.Sprintf("%s %s", author.FirstName, author.LastName),
+		Text:           text,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	// requester's reply is addressed to the last approver, approver's comment to the requester
+	addressee := vacation.UserID
+	if userAccess.UserID == vacation.UserID && vacation.StatusChangerID != "" {
+		addressee = vacation.StatusChangerID
+	}
+
+	err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+		err := u.commentRepo.Save(ctx, comment)
+		if err != nil {
+			return err
+		}
+
+		return u.publish(ctx, models.RecentChanges{
+			ID:            uuid.New(),
+			Title:         fmt.Sprintf("%d Vacation comment", vacation.Number),
+			IncidentID:    vacation.ID,
+			Type:          models.VacationCommentAdded,
+			UserName:      vacation.UserFullName,
+			UserID:        addressee,
+			OwnerID:       vacation.UserID,
+			UpdatedByName: comment.AuthorFullName,
+			UpdatedByID:   comment.AuthorID,
+			ChangeTime:    comment.CreatedAt,
+			Status:        string(vacation.Status),
+			Comment:       text,
+		})
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &comment, nil
+}
+
+func (u *vacationsUsecase) GetComments(ctx context.Context, vacationID uuid.UUID) ([]models.VacationComment, error) {
+	userAccess := util.GetUserAccessFromCtx(ctx)
+	vacation, err := u.VacationRepository.GetByID(ctx, vacationID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.checkDiscussionAccess(ctx, userAccess, *vacation)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.commentRepo.GetForVacation(ctx, vacation.ID.String())
+}
+
+func (u *vacationsUsecase) checkDiscussionAccess(ctx context.Context, ua models.UserAccess, vacation models.VacationDB) error {
+	if ua.UserID == vacation.UserID {
+		return nil
+	}
+
+	canApprove, err := u.authorizer.Can(ctx, ua, models.ApproveVacations, vacation.UserID)
+	if err != nil {
+		return err
+	}
+	if canApprove {
+		return nil
+	}
+
+	isApprover, err := u.isApprover(ctx, ua, vacation)
+	if err != nil {
+		return err
+	}
+	if !isApprover {
+		return models.NewErrForbidden("user %s is neither requester nor approver of vacation with id = %s", ua.UserID, vacation.ID)
+	}
+	return nil
+}
+
+// checkViewAccess allows to see vacation to those who manage vacations of the requester besides participants of discussion
+func (u *vacationsUsecase) checkViewAccess(ctx context.Context, ua models.UserAccess, vacation models.VacationDB) error {
+	canManage, err := u.authorizer.Can(ctx, ua, models.ManageVacations, vacation.UserID)
+	if err != nil || canManage {
+		return err
+	}
+	return u.checkDiscussionAccess(ctx, ua, vacation)
+}
 
-	return &vac, err
+// isApprover returns true if user can decide any step of vacation approval chain
+func (u *vacationsUsecase) isApprover(ctx context.Context, ua models.UserAccess, vacation models.VacationDB) (bool, error) {
+	approvals, err := u.approvalRepo.GetForVacation(ctx, vacation.ID.String())
+	if err != nil {
+		return false, err
+	}
+
+	if len(approvals) == 0 {
+		user, err := u.userRepo.GetUserByID(ctx, vacation.UserID)
+		if err != nil {
+			return false, err
+		}
+		approvals = u.buildApprovalChain(vacation, user.ManagerID)
+	}
+
+	for _, approval := range approvals {
+		canDecide, err := u.canDecide(ctx, ua, approval, vacation)
+		if err != nil || canDecide {
+			return canDecide, err
+		}
+	}
+	return false, nil
+}
+
+// canDecide returns true if user can make decision on the step. Besides assigned approver manager step can be decided
+// by anyone who approves vacations of the requester, e.g. manager of the requester's manager or HR.
+func (u *vacationsUsecase) canDecide(ctx context.Context, ua models.UserAccess, approval models.VacationApproval, vacation models.VacationDB) (bool, error) {
+	if approval.CanBeDecidedBy(ua) {
+		return true, nil
+	}
+	if approval.ApproverType != models.ManagerApprover || vacation.UserID == ua.UserID {
+		return false, nil
+	}
+	return u.authorizer.Can(ctx, ua, models.ApproveVacations, vacation.UserID)
 }
 
 func (u *vacationsUsecase) GetByID(ctx context.Context, vacationID uuid.UUID) (*models.Vacation, error) {
@@ -145,6 +868,11 @@ func (u *vacationsUsecase) GetByID(ctx context.Context, vacationID uuid.UUID) (*
 	if err != nil {
 		return nil, err
 	}
+
+	err = u.checkViewAccess(ctx, util.GetUserAccessFromCtx(ctx), *vacDB)
+	if err != nil {
+		return nil, err
+	}
 	vacation := copyToVacation(*vacDB)
 
 	user, err := u.userRepo.GetUserByID(ctx, vacDB.UserID)
@@ -153,7 +881,7 @@ func (u *vacationsUsecase) GetByID(ctx context.Context, vacationID uuid.UUID) (*
 	}
 
 	if vacDB.StatusChangerID != "" {
-		changer, err := u.userRepo.GetUserByID(ctx, vacDB.UserID)
+		changer, err := u.userRepo.GetUserByID(ctx, vacDB.StatusChangerID)
 		if err != nil {
 			return nil, err
 		}
@@ -161,40 +889,215 @@ func (u *vacationsUsecase) GetByID(ctx context.Context, vacationID uuid.UUID) (*
 	}
 	vacation.User = &user
 
+	vacation.Approvals, err = u.approvalRepo.GetForVacation(ctx, vacDB.ID.String())
 	return &vacation, err
 }
 
+// buildApprovalChain creates approval steps which are required for the vacation according to configured chain,
+// manager step is assigned to requester's manager and falls back to admin step when requester has no manager
+func (u *vacationsUsecase) buildApprovalChain(vacation models.VacationDB, managerID string) []models.VacationApproval {
+	var (
+		days      = vacationDays(vacation.StartDate, vacation.EndDate)
+		approvals = make([]models.VacationApproval, 0, len(u.approvalChain))
+	)
+
+	for _, rule := range u.approvalChain {
+		if days <= rule.LongerThanDays {
+			continue
+		}
+
+		approverType, approverID := rule.Approver, rule.UserID
+		if approverType == models.ManagerApprover {
+			approverID = managerID
+			if managerID == "" {
+				approverType = models.AdminApprover
+			}
+		}
+
+		approvals = append(approvals, models.VacationApproval{
+			ID:           uuid.New(),
+			VacationID:   vacation.ID,
+			Step:         len(approvals) + 1,
+			ApproverType: approverType,
+			ApproverID:   approverID,
+			Decision:     models.Pending,
+		})
+	}
+
+	if len(approvals) == 0 {
+		approvals = append(approvals, models.VacationApproval{
+			ID:           uuid.New(),
+			VacationID:   vacation.ID,
+			Step:         1,
+			ApproverType: models.AdminApprover,
+			Decision:     models.Pending,
+		})
+	}
+	return approvals
+}
+
+// currentApprovalStep returns index of first pending step or -1 if there is no such step
+func currentApprovalStep(approvals []models.VacationApproval) int {
+	for i, approval := range approvals {
+		if approval.IsPending() {
+			return i
+		}
+	}
+	return -1
+}
+
+// today returns current date in UTC, vacation dates are stored the same way
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// vacationDays returns amount of days including both start and end dates
+func vacationDays(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+// ExpiryJob is a name of the job which runs SetExpired, scheduled and on demand runs share it to not overlap
+const ExpiryJob = "vacations expiry"
+
+// SetExpired moves Pending vacations which start date has passed to Expired
+// and marks finished Approved vacations as taken
 func (u *vacationsUsecase) SetExpired(ctx context.Context) {
-	txID := transactionID.FromContext(ctx)
-	vacDB, err := u.VacationRepository.GetAll(ctx)
+	var (
+		txID = transactionID.FromContext(ctx)
+		now  = time.Now().UTC()
+		date = today()
+	)
+
+	pending, err := u.VacationRepository.GetPendingStartedBefore(ctx, date)
 	if err != nil {
-		u.log.Errorf(txID, "cannot retrieve all vacations")
+		u.log.Errorf(txID, "cannot retrieve outdated pending vacations: err = %s", err)
 		return
 	}
 
-	now := time.Now()
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	var expired, taken int
+	for _, vacation := range pending {
+		var changed bool
+		err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+			// vacation could be decided or canceled after it was listed, so it is re-read under lock
+			vacation, err := u.VacationRepository.GetForUpdate(ctx, vacation.ID.String())
+			if err != nil {
+				return err
+			}
+			if vacation.Status != models.Pending || !vacation.StartDate.Before(date) {
+				return nil
+			}
+
+			err = u.skipPendingApprovals(ctx, vacation.ID)
+			if err != nil {
+				return err
+			}
 
-	for _, vacation := range vacDB {
-		if vacation.EndDate.After(today) {
 			vacation.Status = models.Expired
-			err = u.VacationRepository.Update(ctx, vacation)
+			vacation.UpdateTime = now
+			err = u.VacationRepository.Update(ctx, *vacation)
 			if err != nil {
-				u.log.Errorf(txID, "cannot set Expired status for vacation id = %s due to err = %s", vacation.ID, err)
+				return err
 			}
+			changed = true
+
+			return u.publish(ctx, models.RecentChanges{
+				ID:         uuid.New(),
+				Title:      fmt.Sprintf("%d Vacation %s", vacation.Number, models.Expired),
+				IncidentID: vacation.ID,
+				Type:       models.VacationStatusChange,
+				UserName:   vacation.UserFullName,
+				UserID:     vacation.UserID,
+				OwnerID:    vacation.UserID,
+				ChangeTime: now,
+				Status:     models.Expired,
+			})
+		})
+		if err != nil {
+			u.log.Errorf(txID, "cannot set Expired status for vacation id = %s due to err = %s", vacation.ID, err)
+			continue
+		}
+		if changed {
+			expired++
 		}
 	}
+
+	finished, err := u.VacationRepository.GetApprovedFinishedBefore(ctx, date)
+	if err != nil {
+		u.log.Errorf(txID, "cannot retrieve finished approved vacations: err = %s", err)
+		return
+	}
+
+	for _, vacation := range finished {
+		var changed bool
+		err = u.tx.InTransaction(ctx, func(ctx context.Context) error {
+			vacation, err := u.VacationRepository.GetForUpdate(ctx, vacation.ID.String())
+			if err != nil {
+				return err
+			}
+			if vacation.Taken || !vacation.EndDate.Before(date) ||
+				(vacation.Status != models.Approved && vacation.Status != models.CancelRequested) {
+				return nil
+			}
+
+			// not reviewed cancellation request is dropped when vacation is over
+			vacation.Status = models.Approved
+			vacation.Taken = true
+			changed = true
+			return u.VacationRepository.Update(ctx, *vacation)
+		})
+		if err != nil {
+			u.log.Errorf(txID, "cannot mark vacation id = %s as taken due to err = %s", vacation.ID, err)
+			continue
+		}
+		if changed {
+			taken++
+		}
+	}
+
+	u.log.Infof(txID, "vacations expiry: %d expired, %d taken", expired, taken)
 }
 
+// GetAll returns actual vacations of users whose vacations current user can approve
 func (u *vacationsUsecase) GetAll(ctx context.Context) ([]models.Vacation, error) {
 	vacationsDB, err := u.VacationRepository.GetActual(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	vacationsDB, err = u.filterPermitted(ctx, models.ApproveVacations, vacationsDB)
+	if err != nil {
+		return nil, err
+	}
 	return u.joinVacationsWithUser(ctx, vacationsDB...)
 }
 
+// filterPermitted keeps vacations of users on whom current user has the permission
+func (u *vacationsUsecase) filterPermitted(ctx context.Context, p models.Permission, vacations []models.VacationDB) ([]models.VacationDB, error) {
+	var (
+		ua        = util.GetUserAccessFromCtx(ctx)
+		permitted = make(map[string]bool)
+		filtered  = make([]models.VacationDB, 0, len(vacations))
+	)
+
+	for _, vacation := range vacations {
+		ok, checked := permitted[vacation.UserID]
+		if !checked {
+			var err error
+			ok, err = u.authorizer.Can(ctx, ua, p, vacation.UserID)
+			if err != nil {
+				return nil, err
+			}
+			permitted[vacation.UserID] = ok
+		}
+
+		if ok {
+			filtered = append(filtered, vacation)
+		}
+	}
+	return filtered, nil
+}
+
 func (u *vacationsUsecase) joinVacationsWithUser(ctx context.Context, vacationsDB ...models.VacationDB) ([]models.Vacation, error) {
 	var (
 		txID      = transactionID.FromContext(ctx)
@@ -236,15 +1139,38 @@ func (u *vacationsUsecase) joinVacationsWithUser(ctx context.Context, vacationsD
 	return vacations, nil
 }
 
+// GetPending returns pending vacations which current user can approve, own vacations are not included
 func (u *vacationsUsecase) GetPending(ctx context.Context) ([]models.Vacation, error) {
+	ua := util.GetUserAccessFromCtx(ctx)
 	vacationsDB, err := u.VacationRepository.GetPending(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return u.joinVacationsWithUser(ctx, vacationsDB...)
+	others := make([]models.VacationDB, 0, len(vacationsDB))
+	for _, vacation := range vacationsDB {
+		if vacation.UserID != ua.UserID {
+			others = append(others, vacation)
+		}
+	}
+
+	others, err = u.filterPermitted(ctx, models.ApproveVacations, others)
+	if err != nil {
+		return nil, err
+	}
+	return u.joinVacationsWithUser(ctx, others...)
 }
+
 func (u *vacationsUsecase) GetForUser(ctx context.Context, userID string) ([]models.Vacation, error) {
+	userAccess := util.GetUserAccessFromCtx(ctx)
+	canView, err := u.authorizer.Can(ctx, userAccess, models.ManageVacations, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		return nil, models.NewErrForbidden("user %s cannot view vacations of user %s", userAccess.UserID, userID)
+	}
+
 	vacationsDB, err := u.VacationRepository.GetForUser(ctx, userID)
 	if err != nil {
 		return nil, err
@@ -255,13 +1181,17 @@ func (u *vacationsUsecase) GetForUser(ctx context.Context, userID string) ([]mod
 
 func copyToVacation(v models.VacationDB) models.Vacation {
 	return models.Vacation{
-		ID:          v.ID,
-		Number:      v.Number,
-		StartDate:   v.StartDate,
-		EndDate:     v.EndDate,
-		Status:      v.Status,
-		UpdateTime:  v.UpdateTime,
-		WasApproved: v.WasApproved,
+		ID:               v.ID,
+		Number:           v.Number,
+		StartDate:        v.StartDate,
+		EndDate:          v.EndDate,
+		Status:           v.Status,
+		UpdateTime:       v.UpdateTime,
+		WasApproved:      v.WasApproved,
+		StatusComment:    v.StatusComment,
+		Taken:            v.Taken,
+		DelegateID:       v.DelegateID,
+		DelegateFullName: v.DelegateFullName,
 	}
 }
 
@@ -0,0 +1,314 @@
+package vacation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+
+	"github.com/google/uuid"
+)
+
+type txStub struct{}
+
+func (txStub) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (txStub) InSavepoint(ctx context.Context, _ string, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+type vacationRepoStub struct {
+	repository.VacationRepository
+	vacations map[string]models.VacationDB
+	listed    []models.VacationDB
+	locked    []string
+}
+
+func (r *vacationRepoStub) GetForUpdate(ctx context.Context, id string) (*models.VacationDB, error) {
+	r.locked = append(r.locked, id)
+	return r.GetByID(ctx, id)
+}
+
+func (r *vacationRepoStub) Update(_ context.Context, vacation models.VacationDB) error {
+	r.vacations[vacation.ID.String()] = vacation
+	return nil
+}
+
+func (r *vacationRepoStub) GetPendingStartedBefore(_ context.Context, _ time.Time) ([]models.VacationDB, error) {
+	return r.listed, nil
+}
+
+func (r *vacationRepoStub) GetApprovedFinishedBefore(_ context.Context, _ time.Time) ([]models.VacationDB, error) {
+	return nil, nil
+}
+
+func (r *vacationRepoStub) GetApprovedBetween(context.Context, time.Time, time.Time) ([]models.VacationDB, error) {
+	vacations := make([]models.VacationDB, 0, len(r.vacations))
+	for _, vacation := range r.vacations {
+		vacations = append(vacations, vacation)
+	}
+	return vacations, nil
+}
+
+func (r *vacationRepoStub) Save(_ context.Context, vacation models.VacationDB) (*models.VacationDB, error) {
+	r.vacations[vacation.ID.String()] = vacation
+	return &vacation, nil
+}
+
+func (r *vacationRepoStub) GetByID(_ context.Context, id string) (*models.VacationDB, error) {
+	vacation, ok := r.vacations[id]
+	if !ok {
+		return nil, models.NewErrNotFound("vacation with id = %s is not found", id)
+	}
+	return &vacation, nil
+}
+
+type approvalRepoStub struct {
+	repository.VacationApprovalRepository
+	approvals map[string][]models.VacationApproval
+}
+
+func (r *approvalRepoStub) GetForVacation(_ context.Context, vacationID string) ([]models.VacationApproval, error) {
+	return append([]models.VacationApproval(nil), r.approvals[vacationID]...), nil
+}
+
+func (r *approvalRepoStub) Update(_ context.Context, approval models.VacationApproval) error {
+	approvals := r.approvals[approval.VacationID.String()]
+	for i := range approvals {
+		if approvals[i].ID == approval.ID {
+			approvals[i] = approval
+		}
+	}
+	return nil
+}
+
+type outboxRepoStub struct {
+	repository.OutboxRepository
+	events []models.OutboxEvent
+}
+
+func (r *outboxRepoStub) Save(_ context.Context, events ...models.OutboxEvent) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+type userRepoStub struct {
+	repository.UserRepository
+	users map[string]models.User
+}
+
+func (r *userRepoStub) GetUserByID(_ context.Context, id string) (models.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, models.NewErrNotFound("user with id=%s is not found", id)
+	}
+	return user, nil
+}
+
+func withUser(userID string, role models.Role) context.Context {
+	return context.WithValue(context.Background(), models.AccessKey, &models.UserAccess{UserID: userID, Role: role})
+}
+
+func TestGetByIDAccess(t *testing.T) {
+	var (
+		manager  = models.User{ID: uuid.New()}
+		employee = models.User{ID: uuid.New(), ManagerID: manager.ID.String()}
+		approver = uuid.New().String()
+		vacation = models.VacationDB{ID: uuid.New(), UserID: employee.ID.String(), Status: models.Pending}
+		u        = &vacationsUsecase{
+			VacationRepository: &vacationRepoStub{vacations: map[string]models.VacationDB{vacation.ID.String(): vacation}},
+			approvalRepo: &approvalRepoStub{approvals: map[string][]models.VacationApproval{vacation.ID.String(): {
+				{ApproverType: models.UserApprover, ApproverID: approver, Decision: models.Pending},
+			}}},
+			userRepo: &userRepoStub{users: map[string]models.User{
+				manager.ID.String():  manager,
+				employee.ID.String(): employee,
+			}},
+			authorizer: access.NewAuthorizer(&orgStub{managers: map[string]string{employee.ID.String(): manager.ID.String()}}),
+		}
+	)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		forbidden bool
+	}{
+		{name: "requester", ctx: withUser(employee.ID.String(), models.UserRole)},
+		{name: "manager of requester", ctx: withUser(manager.ID.String(), models.ManagerRole)},
+		{name: "hr", ctx: withUser(uuid.New().String(), models.HRRole)},
+		{name: "assigned approver", ctx: withUser(approver, models.UserRole)},
+		{name: "colleague", ctx: withUser(uuid.New().String(), models.UserRole), forbidden: true},
+		{name: "manager outside subtree", ctx: withUser(uuid.New().String(), models.ManagerRole), forbidden: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := u.GetByID(tt.ctx, vacation.ID)
+			if tt.forbidden {
+				if !models.IsErrForbidden(err) {
+					t.Fatalf("expected forbidden, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got.ID != vacation.ID || len(got.Approvals) != 1 {
+				t.Fatalf("unexpected vacation: %+v", got)
+			}
+		})
+	}
+
+	if _, err := u.GetByID(withUser(employee.ID.String(), models.UserRole), uuid.New()); !models.IsErrNotFound(err) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestDecideChargesBalanceOnce(t *testing.T) {
+	var (
+		manager  = models.User{ID: uuid.New()}
+		employee = models.User{ID: uuid.New(), ManagerID: manager.ID.String()}
+		start    = today().AddDate(0, 0, 10)
+		vacation = models.VacationDB{ID: uuid.New(), UserID: employee.ID.String(), Status: models.Pending, StartDate: start, EndDate: start.AddDate(0, 0, 4)}
+		vacRepo  = &vacationRepoStub{vacations: map[string]models.VacationDB{vacation.ID.String(): vacation}}
+		balance  = &balanceRepoStub{}
+		u        = &vacationsUsecase{
+			VacationRepository: vacRepo,
+			approvalRepo: &approvalRepoStub{approvals: map[string][]models.VacationApproval{vacation.ID.String(): {
+				{ID: uuid.New(), VacationID: vacation.ID, Step: 1, ApproverType: models.ManagerApprover, ApproverID: manager.ID.String(), Decision: models.Pending},
+			}}},
+			userRepo: &userRepoStub{users: map[string]models.User{
+				manager.ID.String():  manager,
+				employee.ID.String(): employee,
+			}},
+			balanceRepo: balance,
+			outboxRepo:  &outboxRepoStub{},
+			tx:          txStub{},
+		}
+		ctx      = withUser(manager.ID.String(), models.ManagerRole)
+		decision = models.VacationStatusUpdate{Status: models.Approved}
+	)
+
+	got, err := u.Decide(ctx, vacation.ID, decision)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Status != models.Approved || balance.calls != 1 || balance.days != 5 {
+		t.Fatalf("expected approved vacation charged once for 5 days, got %s charged %d times for %d days", got.Status, balance.calls, balance.days)
+	}
+
+	_, err = u.Decide(ctx, vacation.ID, decision)
+	if !models.IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data on repeated decision, got %v", err)
+	}
+	if balance.calls != 1 {
+		t.Fatalf("expected balance to be charged once, got %d", balance.calls)
+	}
+	if len(vacRepo.locked) != 2 {
+		t.Fatalf("expected vacation to be locked on every decision, got %v", vacRepo.locked)
+	}
+}
+
+func TestDecideCancellationRefundsOnce(t *testing.T) {
+	var (
+		manager  = models.User{ID: uuid.New()}
+		employee = models.User{ID: uuid.New(), ManagerID: manager.ID.String()}
+		start    = today().AddDate(0, 0, 10)
+		vacation = models.VacationDB{ID: uuid.New(), UserID: employee.ID.String(), Status: models.CancelRequested, WasApproved: true, StartDate: start, EndDate: start.AddDate(0, 0, 4)}
+		vacRepo  = &vacationRepoStub{vacations: map[string]models.VacationDB{vacation.ID.String(): vacation}}
+		balance  = &balanceRepoStub{}
+		u        = &vacationsUsecase{
+			VacationRepository: vacRepo,
+			approvalRepo: &approvalRepoStub{approvals: map[string][]models.VacationApproval{vacation.ID.String(): {
+				{ID: uuid.New(), VacationID: vacation.ID, Step: 1, ApproverType: models.ManagerApprover, ApproverID: manager.ID.String(), Decision: models.Approved},
+			}}},
+			userRepo: &userRepoStub{users: map[string]models.User{
+				manager.ID.String():  manager,
+				employee.ID.String(): employee,
+			}},
+			balanceRepo: balance,
+			outboxRepo:  &outboxRepoStub{},
+			tx:          txStub{},
+		}
+		ctx      = withUser(manager.ID.String(), models.ManagerRole)
+		decision = models.VacationStatusUpdate{Status: models.Approved}
+	)
+
+	got, err := u.DecideCancellation(ctx, vacation.ID, decision)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Status != models.Canceled || balance.calls != 1 || balance.days != -5 {
+		t.Fatalf("expected canceled vacation refunded once for 5 days, got %s refunded %d times for %d days", got.Status, balance.calls, balance.days)
+	}
+
+	_, err = u.DecideCancellation(ctx, vacation.ID, decision)
+	if !models.IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data on repeated decision, got %v", err)
+	}
+
+	_, err = u.WithdrawCancellation(withUser(employee.ID.String(), models.UserRole), vacation.ID)
+	if !models.IsErrInvalidData(err) {
+		t.Fatalf("expected invalid data on withdrawal of decided cancellation, got %v", err)
+	}
+
+	if balance.calls != 1 {
+		t.Fatalf("expected balance to be refunded once, got %d", balance.calls)
+	}
+	if len(vacRepo.locked) != 3 {
+		t.Fatalf("expected vacation to be locked on every change, got %v", vacRepo.locked)
+	}
+}
+
+func TestSetExpiredRechecksStatus(t *testing.T) {
+	log, err := logger.Load(logger.Config{LogLevel: "panic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		start   = today().AddDate(0, 0, -1)
+		pending = models.VacationDB{ID: uuid.New(), UserID: uuid.New().String(), Status: models.Pending, StartDate: start, EndDate: start}
+		decided = models.VacationDB{ID: uuid.New(), UserID: uuid.New().String(), Status: models.Pending, StartDate: start, EndDate: start}
+		vacRepo = &vacationRepoStub{
+			vacations: map[string]models.VacationDB{pending.ID.String(): pending},
+			listed:    []models.VacationDB{pending, decided},
+		}
+		approvals = &approvalRepoStub{approvals: map[string][]models.VacationApproval{pending.ID.String(): {
+			{ID: uuid.New(), VacationID: pending.ID, Step: 1, ApproverType: models.ManagerApprover, Decision: models.Pending},
+		}}}
+		outboxRepo = &outboxRepoStub{}
+		u          = &vacationsUsecase{
+			VacationRepository: vacRepo,
+			approvalRepo:       approvals,
+			outboxRepo:         outboxRepo,
+			tx:                 txStub{},
+			log:                log,
+		}
+	)
+
+	// vacation was approved after it was listed as pending
+	decided.Status = models.Approved
+	vacRepo.vacations[decided.ID.String()] = decided
+
+	u.SetExpired(context.Background())
+
+	if got := vacRepo.vacations[pending.ID.String()].Status; got != models.Expired {
+		t.Errorf("expected pending vacation to expire, got %s", got)
+	}
+	if got := approvals.approvals[pending.ID.String()][0].Decision; got != models.Skipped {
+		t.Errorf("expected pending approval step to be skipped, got %s", got)
+	}
+	if got := vacRepo.vacations[decided.ID.String()].Status; got != models.Approved {
+		t.Errorf("expected decided vacation to stay %s, got %s", models.Approved, got)
+	}
+	if len(outboxRepo.events) != 1 {
+		t.Errorf("expected one recent change, got %d", len(outboxRepo.events))
+	}
+}
@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/models"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EventHeader     = "X-Staff-Event"
+	EventIDHeader   = "X-Staff-Event-ID"
+	DeliveryHeader  = "X-Staff-Delivery"
+	TimestampHeader = "X-Staff-Timestamp"
+	SignatureHeader = "X-Staff-Signature"
+
+	signaturePrefix = "sha256="
+
+	// DefaultTolerance is a maximum difference between signed timestamp and receiver clock which receivers should accept
+	DefaultTolerance = 5 * time.Minute
+)
+
+// Sign returns signature of webhook body, receivers compute it with their copy of the secret
+// and compare with SignatureHeader. Timestamp is signed too so old requests cannot be replayed by third party.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks signature headers of webhook request against the secret, request signed more than tolerance
+// before or after now is rejected so captured request cannot be replayed later
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) bool {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return false
+	}
+
+	skew := time.Since(time.Unix(timestamp, 0))
+	if skew > tolerance || skew < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign(secret, timestamp, body)))
+}
+
+// send posts signed delivery payload to subscription URL and returns response status code
+func send(ctx context.Context, client *http.Client, sub models.WebhookSubscription, delivery models.WebhookDelivery, now time.Time) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := now.Unix()
+
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(EventIDHeader, delivery.EventID.String())
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, timestamp, body))
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, errors.Wrapf(err, "posting to %s", sub.URL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, errors.Errorf("posting to %s: unexpected status code %d", sub.URL, resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
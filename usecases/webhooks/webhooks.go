@@ -0,0 +1,267 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/config"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/outbound"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/util"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	secretSize    = 32
+	deliveryLimit = 100
+	// deliveryLease is added to time needed to send the batch, delivery is claimed again only if instance died while sending it
+	deliveryLease = time.Minute
+)
+
+type WebhooksUsecase interface {
+	CreateSubscription(ctx context.Context, req models.WebhookSubscriptionReq) (*models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, id uuid.UUID, req models.WebhookSubscriptionReq) (*models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	GetDeliveries(ctx context.Context, status models.WebhookDeliveryStatus) ([]models.WebhookDelivery, error)
+	Replay(ctx context.Context, deliveryID uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+// payload is a body of webhook request
+type payload struct {
+	EventID uuid.UUID            `json:"eventID"`
+	Type    models.ChangesType   `json:"type"`
+	Change  models.RecentChanges `json:"change"`
+}
+
+func NewWebhooksUsecase(cfg config.WebhooksConfig, repo repository.WebhookRepository, client *http.Client, log logger.Logger) *webhooksUsecase {
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	return &webhooksUsecase{
+		cfg:    cfg,
+		repo:   repo,
+		client: client,
+		log:    log,
+		lease:  time.Duration(cfg.BatchSize*cfg.TimeoutInSec)*time.Second + deliveryLease,
+	}
+}
+
+type webhooksUsecase struct {
+	cfg    config.WebhooksConfig
+	repo   repository.WebhookRepository
+	client *http.Client
+	log    logger.Logger
+	lease  time.Duration
+}
+
+func (u *webhooksUsecase) CreateSubscription(ctx context.Context, req models.WebhookSubscriptionReq) (*models.WebhookSubscription, error) {
+	userAccess := util.GetUserAccessFromCtx(ctx)
+
+	err := outbound.ValidateURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		s := make([]byte, secretSize)
+		_, err := rand.Read(s)
+		if err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(s)
+	}
+
+	now := time.Now().UTC()
+	sub := models.WebhookSubscription{
+		ID:          uuid.New(),
+		URL:         req.URL,
+		Secret:      secret,
+		EventTypes:  req.EventTypes,
+		Active:      req.Active == nil || *req.Active,
+		CreatedByID: userAccess.UserID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	return &sub, u.repo.SaveSubscription(ctx, sub)
+}
+
+// UpdateSubscription replaces URL and event types of subscription, secret and active flag are changed only if they are set
+func (u *webhooksUsecase) UpdateSubscription(ctx context.Context, id uuid.UUID, req models.WebhookSubscriptionReq) (*models.WebhookSubscription, error) {
+	err := outbound.ValidateURL(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := u.repo.GetSubscription(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+
+	sub.URL = req.URL
+	sub.EventTypes = req.EventTypes
+	sub.UpdatedAt = time.Now().UTC()
+	if req.Secret != "" {
+		sub.Secret = req.Secret
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+
+	return &sub, u.repo.SaveSubscription(ctx, sub)
+}
+
+func (u *webhooksUsecase) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	return u.repo.DeleteSubscription(ctx, id.String())
+}
+
+func (u *webhooksUsecase) GetSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return u.repo.GetSubscriptions(ctx)
+}
+
+// GetDeliveries returns last deliveries with the status, dead-letter list is requested with WebhookDead status
+func (u *webhooksUsecase) GetDeliveries(ctx context.Context, status models.WebhookDeliveryStatus) ([]models.WebhookDelivery, error) {
+	return u.repo.GetDeliveries(ctx, status, deliveryLimit)
+}
+
+// Replay queues delivery to be sent again with the same payload and event ID
+func (u *webhooksUsecase) Replay(ctx context.Context, deliveryID uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := u.repo.GetDelivery(ctx, deliveryID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if delivery.Status == models.WebhookPending {
+		return nil, models.NewErrInvalidData("webhook delivery with id = %s is already pending", delivery.ID)
+	}
+
+	delivery.Status = models.WebhookPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	return &delivery, u.repo.UpdateDelivery(ctx, delivery)
+}
+
+// Handle queues deliveries of recent change event for active subscriptions which accept its type.
+// It is an outbox handler so deliveries are queued within relay transaction.
+func (u *webhooksUsecase) Handle(ctx context.Context, event models.OutboxEvent) error {
+	var change models.RecentChanges
+	err := json.Unmarshal([]byte(event.Payload), &change)
+	if err != nil {
+		return errors.Wrapf(err, "unmarshaling recent change from event %s", event.ID)
+	}
+
+	subs, err := u.repo.GetActiveSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(payload{EventID: change.ID, Type: change.Type, Change: change})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, sub := range subs {
+		if !sub.Accepts(change.Type) {
+			continue
+		}
+
+		err = u.repo.CreateDelivery(ctx, models.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: sub.ID,
+			EventID:        change.ID,
+			EventType:      change.Type,
+			Payload:        string(body),
+			Status:         models.WebhookPending,
+			CreatedAt:      now,
+			NextAttemptAt:  now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deliver sends due deliveries, it is run by schedule. Deliveries are claimed before they are sent,
+// so several service instances can run it at the same time and no transaction is open while requests are sent.
+func (u *webhooksUsecase) Deliver(ctx context.Context) {
+	var (
+		txID = transactionID.FromContext(ctx)
+		now  = time.Now().UTC()
+	)
+
+	deliveries, err := u.repo.ClaimDueDeliveries(ctx, now, now.Add(u.lease), u.cfg.BatchSize)
+	if err != nil {
+		u.log.Errorf(txID, "cannot claim webhook deliveries: err=%s", err)
+		return
+	}
+
+	subs := make(map[uuid.UUID]models.WebhookSubscription)
+	for i := range deliveries {
+		sub, ok := subs[deliveries[i].SubscriptionID]
+		if !ok {
+			sub, err = u.repo.GetSubscription(ctx, deliveries[i].SubscriptionID.String())
+			if err != nil {
+				u.log.Errorf(txID, "cannot get subscription of webhook delivery %s: err=%s", deliveries[i].ID, err)
+				continue
+			}
+			subs[sub.ID] = sub
+		}
+
+		u.attempt(ctx, sub, &deliveries[i])
+		err = u.repo.UpdateDelivery(ctx, deliveries[i])
+		if err != nil {
+			u.log.Errorf(txID, "cannot save webhook delivery %s: err=%s", deliveries[i].ID, err)
+		}
+	}
+}
+
+// attempt sends delivery and updates its state, delivery is moved to dead-letter list when attempts are over
+func (u *webhooksUsecase) attempt(ctx context.Context, sub models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	var (
+		txID = transactionID.FromContext(ctx)
+		now  = time.Now().UTC()
+		code int
+		err  error
+	)
+
+	if sub.Active {
+		code, err = send(ctx, u.client, sub, *delivery, now)
+	} else {
+		err = errors.New("subscription is inactive")
+	}
+
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	if err == nil {
+		delivery.Status = models.WebhookDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= u.cfg.MaxAttempts || !sub.Active {
+		delivery.Status = models.WebhookDead
+		u.log.Warnf(txID, "webhook delivery %s to %s is dead after %d attempts: err=%s", delivery.ID, sub.URL, delivery.Attempts, err)
+		return
+	}
+
+	backoff := time.Duration(u.cfg.InitialBackoffInSec) * time.Second
+	delivery.NextAttemptAt = now.Add(backoff << uint(delivery.Attempts-1))
+}
@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/config"
+	"github.com/Dimitriy14/staff-manager/logger"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+
+	"github.com/google/uuid"
+)
+
+const testSecret = "0123456789abcdef"
+
+type webhookRepoStub struct {
+	repository.WebhookRepository
+	sub        models.WebhookSubscription
+	deliveries []models.WebhookDelivery
+	updated    []models.WebhookDelivery
+	leaseUntil time.Time
+}
+
+func (r *webhookRepoStub) ClaimDueDeliveries(_ context.Context, _, leaseUntil time.Time, _ int) ([]models.WebhookDelivery, error) {
+	r.leaseUntil = leaseUntil
+	return r.deliveries, nil
+}
+
+func (r *webhookRepoStub) GetSubscription(context.Context, string) (models.WebhookSubscription, error) {
+	return r.sub, nil
+}
+
+func (r *webhookRepoStub) UpdateDelivery(_ context.Context, delivery models.WebhookDelivery) error {
+	r.updated = append(r.updated, delivery)
+	return nil
+}
+
+func TestSignVerify(t *testing.T) {
+	var (
+		body      = []byte(`{"eventID":"1"}`)
+		timestamp = time.Now().Unix()
+		header    = http.Header{}
+	)
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(SignatureHeader, Sign(testSecret, timestamp, body))
+
+	if !Verify(testSecret, header, body, DefaultTolerance) {
+		t.Fatal("valid signature is rejected")
+	}
+	if Verify("another secret value", header, body, DefaultTolerance) {
+		t.Fatal("signature is accepted with wrong secret")
+	}
+	if Verify(testSecret, header, []byte(`{"eventID":"2"}`), DefaultTolerance) {
+		t.Fatal("signature is accepted for modified body")
+	}
+
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp+1, 10))
+	if Verify(testSecret, header, body, DefaultTolerance) {
+		t.Fatal("signature is accepted for modified timestamp")
+	}
+
+	for _, signedAt := range []time.Time{time.Now().Add(-10 * time.Minute), time.Now().Add(10 * time.Minute)} {
+		timestamp = signedAt.Unix()
+		header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		header.Set(SignatureHeader, Sign(testSecret, timestamp, body))
+		if Verify(testSecret, header, body, DefaultTolerance) {
+			t.Fatalf("signature is accepted for timestamp %s out of tolerance", signedAt)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	log, err := logger.Load(logger.Config{LogLevel: "panic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		status      int
+		maxAttempts int
+		expected    models.WebhookDeliveryStatus
+	}{
+		{name: "delivered", status: http.StatusNoContent, maxAttempts: 3, expected: models.WebhookDelivered},
+		{name: "retried", status: http.StatusInternalServerError, maxAttempts: 3, expected: models.WebhookPending},
+		{name: "dead", status: http.StatusInternalServerError, maxAttempts: 1, expected: models.WebhookDead},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				delivery = models.WebhookDelivery{
+					ID:        uuid.New(),
+					EventID:   uuid.New(),
+					EventType: models.Assignment,
+					Payload:   `{"type":"Assignment"}`,
+					Status:    models.WebhookPending,
+				}
+				verified bool
+			)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := ioutil.ReadAll(r.Body)
+				verified = Verify(testSecret, r.Header, body, DefaultTolerance) &&
+					string(body) == delivery.Payload &&
+					r.Header.Get(EventIDHeader) == delivery.EventID.String()
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			var (
+				repo = &webhookRepoStub{
+					sub:        models.WebhookSubscription{ID: uuid.New(), URL: srv.URL, Secret: testSecret, Active: true},
+					deliveries: []models.WebhookDelivery{delivery},
+				}
+				cfg = config.WebhooksConfig{BatchSize: 10, MaxAttempts: tt.maxAttempts, InitialBackoffInSec: 10, TimeoutInSec: 5}
+				u   = NewWebhooksUsecase(cfg, repo, srv.Client(), log)
+				now = time.Now().UTC()
+			)
+
+			u.Deliver(context.Background())
+
+			if !verified {
+				t.Fatal("receiver got request with invalid signature")
+			}
+			if len(repo.updated) != 1 {
+				t.Fatalf("expected 1 updated delivery, got %d", len(repo.updated))
+			}
+			if !repo.leaseUntil.After(now.Add(time.Minute)) {
+				t.Fatalf("deliveries are claimed without lease: %s", repo.leaseUntil)
+			}
+
+			updated := repo.updated[0]
+			if updated.Status != tt.expected || updated.Attempts != 1 || updated.ResponseCode != tt.status {
+				t.Fatalf("unexpected delivery state: %+v", updated)
+			}
+			if tt.expected == models.WebhookPending && !updated.NextAttemptAt.After(now) {
+				t.Fatalf("retry is not postponed: %s", updated.NextAttemptAt)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+)
+
+// AuditMiddleware writes audit entry for every mutating request after it is handled, unless the entry is already
+// written by the usecase together with the mutation and the request succeeded. Status code of such entry is not known yet,
+// so it is left empty. X-Forwarded-For header is used for source IP only if request came from one of trusted proxies.
+func AuditMiddleware(log logger.Logger, auditor audit.AuditUsecase, trustedProxies []*net.IPNet) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var (
+				txID  = transactionID.FromContext(r.Context())
+				sw    = &statusWriter{ResponseWriter: w, status: http.StatusOK}
+				entry = models.AuditEntry{
+					Action:        fmt.Sprintf("%s %s", r.Method, routeTemplate(r)),
+					TargetID:      mux.Vars(r)["id"],
+					TransactionID: txID,
+					SourceIP:      sourceIP(r, trustedProxies),
+				}
+				ctx, rec = audit.WithRecord(r.Context(), func(ctx context.Context, rec *audit.Record) error {
+					return auditor.Queue(ctx, entry, rec)
+				})
+			)
+
+			next.ServeHTTP(sw, r.WithContext(ctx))
+
+			// queued mutation which is rolled back later is recorded as failed request
+			if rec.Queued() && sw.status < http.StatusBadRequest {
+				return
+			}
+			entry.StatusCode = sw.status
+
+			// entry is written even if client has gone after mutation was done
+			bg := context.WithValue(context.Background(), transactionID.Key, txID)
+			if err := auditor.Record(bg, entry, rec); err != nil {
+				log.Errorf(txID, "cannot write audit entry: %s", err)
+			}
+		})
+	}
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// routeTemplate returns path template of matched route without variable patterns, e.g. /staff/task/{id}
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return r.URL.Path
+	}
+
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return r.URL.Path
+	}
+
+	var (
+		b       strings.Builder
+		depth   int
+		pattern bool
+	)
+	for _, c := range tpl {
+		switch c {
+		case '{':
+			depth++
+			if depth == 1 {
+				b.WriteRune(c)
+			}
+			continue
+		case '}':
+			depth--
+			if depth == 0 {
+				pattern = false
+				b.WriteRune(c)
+			}
+			continue
+		case ':':
+			if depth == 1 {
+				pattern = true
+			}
+		}
+		if !pattern {
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// ParseProxies parses addresses of trusted proxies, address is either IP or CIDR
+func ParseProxies(addrs []string) ([]*net.IPNet, error) {
+	proxies := make([]*net.IPNet, 0, len(addrs))
+	for _, addr := range addrs {
+		if !strings.Contains(addr, "/") {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy address %q", addr)
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(addr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy address %q: %s", addr, err)
+		}
+		proxies = append(proxies, n)
+	}
+	return proxies, nil
+}
+
+// sourceIP returns address of the client. Addresses in X-Forwarded-For are checked from the nearest one,
+// the first address which is not a trusted proxy is the client, so the client cannot spoof it by sending the header.
+func sourceIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	addr, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		addr = r.RemoteAddr
+	}
+	if !isTrusted(addr, trustedProxies) {
+		return addr
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		addr = hop
+		if !isTrusted(hop, trustedProxies) {
+			break
+		}
+	}
+	return addr
+}
+
+func isTrusted(addr string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+)
+
+func TestSourceIP(t *testing.T) {
+	proxies, err := ParseProxies([]string{"10.0.0.0/8", "192.168.1.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{name: "direct request", remoteAddr: "203.0.113.5:1234", expected: "203.0.113.5"},
+		{name: "spoofed header from untrusted client", remoteAddr: "203.0.113.5:1234", forwarded: []string{"198.51.100.1"}, expected: "203.0.113.5"},
+		{name: "trusted proxy", remoteAddr: "10.1.2.3:1234", forwarded: []string{"198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "client prepends fake address", remoteAddr: "10.1.2.3:1234", forwarded: []string{"1.1.1.1, 198.51.100.1"}, expected: "198.51.100.1"},
+		{name: "chain of proxies", remoteAddr: "10.1.2.3:1234", forwarded: []string{"198.51.100.1, 192.168.1.1", "10.0.0.7"}, expected: "198.51.100.1"},
+		{name: "only proxies", remoteAddr: "10.1.2.3:1234", forwarded: []string{"10.0.0.7"}, expected: "10.0.0.7"},
+		{name: "trusted proxy without header", remoteAddr: "192.168.1.1:1234", expected: "192.168.1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/staff/task", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			if ip := sourceIP(r, proxies); ip != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, ip)
+			}
+		})
+	}
+}
+
+func TestParseProxiesInvalid(t *testing.T) {
+	if _, err := ParseProxies([]string{"not an address"}); err == nil {
+		t.Fatal("invalid address is accepted")
+	}
+}
+
+type auditorStub struct {
+	audit.AuditUsecase
+	queued   int
+	recorded []models.AuditEntry
+}
+
+func (a *auditorStub) Queue(context.Context, models.AuditEntry, *audit.Record) error {
+	a.queued++
+	return nil
+}
+
+func (a *auditorStub) Record(_ context.Context, entry models.AuditEntry, _ *audit.Record) error {
+	a.recorded = append(a.recorded, entry)
+	return nil
+}
+
+func TestAuditMiddlewareSkipsQueuedEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		queue    bool
+		status   int
+		recorded int
+	}{
+		{name: "queued with mutation", queue: true, status: http.StatusOK},
+		{name: "queued mutation failed later", queue: true, status: http.StatusInternalServerError, recorded: 1},
+		{name: "not queued", status: http.StatusOK, recorded: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditor := &auditorStub{}
+			handler := AuditMiddleware(nil, auditor, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.queue {
+					if err := audit.Queue(r.Context()); err != nil {
+						t.Fatal(err)
+					}
+				}
+				w.WriteHeader(tt.status)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/staff/vacations", nil))
+
+			if len(auditor.recorded) != tt.recorded {
+				t.Fatalf("expected %d entries written by middleware, got %d", tt.recorded, len(auditor.recorded))
+			}
+			if len(auditor.recorded) > 0 && auditor.recorded[0].StatusCode != tt.status {
+				t.Fatalf("unexpected status code %d", auditor.recorded[0].StatusCode)
+			}
+		})
+	}
+}
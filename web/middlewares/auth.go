@@ -9,6 +9,8 @@ import (
 	"github.com/Dimitriy14/staff-manager/logger"
 	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
 	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
 	"github.com/Dimitriy14/staff-manager/usecases/auth"
 	"github.com/Dimitriy14/staff-manager/util"
 	"github.com/Dimitriy14/staff-manager/web/services/rest"
@@ -36,6 +38,7 @@ func AuthMiddleware(log logger.Logger, auth auth.Authentication, service *rest.S
 				return
 			}
 
+			audit.SetActor(ctx, ua.UserID)
 			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, models.AccessKey, ua)))
 		})
 	}
@@ -61,22 +64,33 @@ func getUserUserAccess(ctx context.Context, auth auth.Authentication, aout model
 	return getUserUserAccess(ctx, auth, *newTokens, w)
 }
 
-func AdminRestriction(log logger.Logger, service *rest.Service) mux.MiddlewareFunc {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			var (
-				ctx  = r.Context()
-				txID = transactionID.FromContext(ctx)
-				ua   = util.GetUserAccessFromCtx(ctx)
-			)
+// Permit returns middleware which lets request through only if current user has the permission in any scope,
+// handlers check scope for the particular target
+func Permit(log logger.Logger, authorizer access.Authorizer, service *rest.Service) func(models.Permission) mux.MiddlewareFunc {
+	return func(permission models.Permission) mux.MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var (
+					ctx  = r.Context()
+					txID = transactionID.FromContext(ctx)
+					ua   = util.GetUserAccessFromCtx(ctx)
+				)
 
-			if !ua.Role.IsAdmin() {
-				log.Warnf(txID, "admin access is restricted for user: %s", ua.UserID)
-				service.SendUnauthorized(ctx, w, "admin access is restricted for user: %s", ua.UserID)
-				return
-			}
+				ok, err := authorizer.Can(ctx, ua, permission, "")
+				if err != nil {
+					log.Errorf(txID, "cannot check permission %s for user %s: %s", permission, ua.UserID, err)
+					service.SendInternalServerError(ctx, w, "cannot check permission %s", permission)
+					return
+				}
 
-			next.ServeHTTP(w, r)
-		})
+				if !ok {
+					log.Warnf(txID, "permission %s is not granted to user: %s", permission, ua.UserID)
+					service.SendForbidden(ctx, w, "permission %s is not granted to user: %s", permission, ua.UserID)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+			})
+		}
 	}
 }
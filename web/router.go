@@ -4,7 +4,19 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/web/services/audit"
+	"github.com/Dimitriy14/staff-manager/web/services/celebrations"
+	changerequests "github.com/Dimitriy14/staff-manager/web/services/change-requests"
+	"github.com/Dimitriy14/staff-manager/web/services/directory"
+	"github.com/Dimitriy14/staff-manager/web/services/events"
+	"github.com/Dimitriy14/staff-manager/web/services/lifecycle"
+	"github.com/Dimitriy14/staff-manager/web/services/mood"
+	"github.com/Dimitriy14/staff-manager/web/services/notifications"
+	"github.com/Dimitriy14/staff-manager/web/services/org"
+	"github.com/Dimitriy14/staff-manager/web/services/privacy"
 	"github.com/Dimitriy14/staff-manager/web/services/vacation"
+	"github.com/Dimitriy14/staff-manager/web/services/webhooks"
 
 	recent_changes "github.com/Dimitriy14/staff-manager/web/services/recent-changes"
 
@@ -23,29 +35,50 @@ import (
 const (
 	// UUIDPattern a pattern for UUID matchers
 	UUIDPattern = `(?:[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})`
+	// FeedTokenPattern a pattern for calendar feed tokens
+	FeedTokenPattern = `[0-9a-f]{64}`
+	// ProfileFieldPattern a pattern for profile field names
+	ProfileFieldPattern = `[a-zA-Z]+`
 )
 
 type Services struct {
-	Health         http.HandlerFunc
-	Rest           *rest.Service
-	Auth           auth.Service
-	User           user.Service
-	Task           tasks.Service
-	RecentChanges  recent_changes.Service
-	Vacation       vacation.Service
-	LogMiddleware  mux.MiddlewareFunc
-	TxIDMiddleware mux.MiddlewareFunc
-	AuthMiddleware mux.MiddlewareFunc
-	AdminOnly      mux.MiddlewareFunc
+	Health          http.HandlerFunc
+	Rest            *rest.Service
+	Auth            auth.Service
+	User            user.Service
+	Task            tasks.Service
+	RecentChanges   recent_changes.Service
+	Vacation        vacation.Service
+	Notifications   notifications.Service
+	Events          events.Service
+	Webhooks        webhooks.Service
+	Audit           audit.Service
+	Org             org.Service
+	Privacy         privacy.Service
+	Lifecycle       lifecycle.Service
+	Directory       directory.Service
+	Celebrations    celebrations.Service
+	Mood            mood.Service
+	ChangeRequests  changerequests.Service
+	LogMiddleware   mux.MiddlewareFunc
+	TxIDMiddleware  mux.MiddlewareFunc
+	AuthMiddleware  mux.MiddlewareFunc
+	AuditMiddleware mux.MiddlewareFunc
+	// Permit returns middleware which checks that current user has the permission
+	Permit func(models.Permission) mux.MiddlewareFunc
 }
 
 func NewRouter(pathPrefix string, originHosts []string, s Services) *mux.Router {
 	router := mux.NewRouter().StrictSlash(true).PathPrefix(pathPrefix).Subrouter()
-	router.Use(s.TxIDMiddleware, s.LogMiddleware)
+	router.Use(s.TxIDMiddleware, s.LogMiddleware, s.AuditMiddleware)
 
 	authorisation := router.Name("auth").Subrouter()
 	authorisation.Use(s.AuthMiddleware)
 
+	permit := func(permission models.Permission, handler http.HandlerFunc) http.Handler {
+		return s.Permit(permission)(handler)
+	}
+
 	router.Path("/health").HandlerFunc(s.Health).Methods(http.MethodGet)
 
 	router.Path("/signup").HandlerFunc(s.Auth.SignUp).Methods(http.MethodPost)
@@ -54,6 +87,7 @@ func NewRouter(pathPrefix string, originHosts []string, s Services) *mux.Router
 	authorisation.Path("/signout").HandlerFunc(s.Auth.SignOut).Methods(http.MethodPost)
 
 	authorisation.Path("/user/search").HandlerFunc(s.User.Search).Methods(http.MethodPost)
+	authorisation.Path("/user/search/directory").HandlerFunc(s.User.SearchDirectory).Methods(http.MethodPost)
 	authorisation.Path("/user").HandlerFunc(s.User.GetUser).Methods(http.MethodGet)
 	authorisation.Path("/user").HandlerFunc(s.User.Update).Methods(http.MethodPut)
 	authorisation.Path("/user/photo").HandlerFunc(s.User.UploadImage).Methods(http.MethodPost)
@@ -61,32 +95,97 @@ func NewRouter(pathPrefix string, originHosts []string, s Services) *mux.Router
 
 	authorisation.Path(fmt.Sprintf("/user/{id:%s}", UUIDPattern)).HandlerFunc(s.User.GetCollege).Methods(http.MethodGet)
 
-	adminOnly := authorisation.Name("admin").Subrouter()
-	adminOnly.Use(s.AdminOnly)
-	adminOnly.Path(fmt.Sprintf("/user/{id:%s}", UUIDPattern)).HandlerFunc(s.User.AdminUserUpdate).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/user/{id:%s}", UUIDPattern)).Handler(permit(models.ManageUsers, s.User.AdminUserUpdate)).Methods(http.MethodPut)
+
+	authorisation.Path(fmt.Sprintf("/user/{id:%s}/status", UUIDPattern)).Handler(permit(models.ManageUsers, s.Lifecycle.UpdateStatus)).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/user/{id:%s}/termination", UUIDPattern)).Handler(permit(models.ManageUsers, s.Lifecycle.Terminate)).Methods(http.MethodPost)
+	authorisation.Path(fmt.Sprintf("/user/{id:%s}/onboarding", UUIDPattern)).Handler(permit(models.ManageUsers, s.Lifecycle.Onboard)).Methods(http.MethodPost)
+
+	authorisation.Path("/user/import").Handler(permit(models.ManageUsers, s.Directory.Import)).Methods(http.MethodPost)
+	authorisation.Path("/user/export").Handler(permit(models.ManageUsers, s.Directory.Export)).Methods(http.MethodGet)
+
+	authorisation.Path("/user/change-requests").HandlerFunc(s.ChangeRequests.GetOwn).Methods(http.MethodGet)
+	authorisation.Path("/user/change-requests").HandlerFunc(s.ChangeRequests.Submit).Methods(http.MethodPost)
+	authorisation.Path(fmt.Sprintf("/user/change-requests/{id:%s}", UUIDPattern)).HandlerFunc(s.ChangeRequests.Cancel).Methods(http.MethodDelete)
+	authorisation.Path("/change-requests").Handler(permit(models.ManageUsers, s.ChangeRequests.Search)).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/change-requests/{id:%s}", UUIDPattern)).Handler(permit(models.ManageUsers, s.ChangeRequests.Review)).Methods(http.MethodPut)
+
+	authorisation.Path(fmt.Sprintf("/user/{id:%s}/managers", UUIDPattern)).HandlerFunc(s.Org.GetChain).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/user/{id:%s}/reports", UUIDPattern)).HandlerFunc(s.Org.GetReports).Methods(http.MethodGet)
+	authorisation.Path("/org/chart").HandlerFunc(s.Org.GetChart).Methods(http.MethodGet)
+	authorisation.Path("/user/privacy").HandlerFunc(s.Privacy.GetSettings).Methods(http.MethodGet)
+	authorisation.Path("/user/privacy").HandlerFunc(s.Privacy.UpdateSettings).Methods(http.MethodPut)
+	authorisation.Path("/privacy/policies").Handler(permit(models.ManagePrivacy, s.Privacy.GetPolicies)).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/privacy/policies/{field:%s}", ProfileFieldPattern)).Handler(permit(models.ManagePrivacy, s.Privacy.UpdatePolicy)).Methods(http.MethodPut)
+
+	authorisation.Path("/celebrations").HandlerFunc(s.Celebrations.GetUpcoming).Methods(http.MethodGet)
+
+	authorisation.Path("/user/mood").HandlerFunc(s.Mood.GetHistory).Methods(http.MethodGet)
+	authorisation.Path("/user/mood").HandlerFunc(s.Mood.CheckIn).Methods(http.MethodPost)
+	authorisation.Path("/mood/pulse").Handler(permit(models.ViewTeamPulse, s.Mood.GetTeamPulse)).Methods(http.MethodGet)
+
+	authorisation.Path("/departments").HandlerFunc(s.Org.GetDepartments).Methods(http.MethodGet)
+	authorisation.Path("/departments").Handler(permit(models.ManageDepartments, s.Org.CreateDepartment)).Methods(http.MethodPost)
+	authorisation.Path(fmt.Sprintf("/departments/{id:%s}", UUIDPattern)).Handler(permit(models.ManageDepartments, s.Org.UpdateDepartment)).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/departments/{id:%s}", UUIDPattern)).Handler(permit(models.ManageDepartments, s.Org.DeleteDepartment)).Methods(http.MethodDelete)
 
 	authorisation.Path("/task").HandlerFunc(s.Task.GetMyTasks).Methods(http.MethodGet)
 	authorisation.Path("/task").HandlerFunc(s.Task.SaveTask).Methods(http.MethodPost)
 	authorisation.Path("/task/search").HandlerFunc(s.Task.SearchForUser).Methods(http.MethodPost)
-	authorisation.Path("/task/search/all").HandlerFunc(s.Task.Search).Methods(http.MethodPost)
-	authorisation.Path(fmt.Sprintf("/task/{id:%s}", UUIDPattern)).HandlerFunc(s.Task.Update).Methods(http.MethodPut)
-	authorisation.Path("/task/list").HandlerFunc(s.Task.GetTasks).Methods(http.MethodGet)
+	authorisation.Path("/task/search/all").Handler(permit(models.ManageTasks, s.Task.Search)).Methods(http.MethodPost)
+	authorisation.Path(fmt.Sprintf("/task/{id:%s}", UUIDPattern)).Handler(permit(models.ManageTasks, s.Task.Update)).Methods(http.MethodPut)
+	authorisation.Path("/task/list").Handler(permit(models.ManageTasks, s.Task.GetTasks)).Methods(http.MethodGet)
 	authorisation.Path(fmt.Sprintf("/task/{id:%s}", UUIDPattern)).HandlerFunc(s.Task.GetTaskByID).Methods(http.MethodGet)
-	authorisation.Path(fmt.Sprintf("/task/{id:%s}", UUIDPattern)).HandlerFunc(s.Task.DeleteTask).Methods(http.MethodDelete)
-	authorisation.Path(fmt.Sprintf("/task/user/{id:%s}", UUIDPattern)).HandlerFunc(s.Task.GetUserTasks).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/task/{id:%s}", UUIDPattern)).Handler(permit(models.ManageTasks, s.Task.DeleteTask)).Methods(http.MethodDelete)
+	authorisation.Path(fmt.Sprintf("/task/user/{id:%s}", UUIDPattern)).Handler(permit(models.ManageTasks, s.Task.GetUserTasks)).Methods(http.MethodGet)
 
 	authorisation.Path("/recent").HandlerFunc(s.RecentChanges.GetRecentChanges).Methods(http.MethodGet)
-	authorisation.Path(fmt.Sprintf("/recent/user/{id:%s}", UUIDPattern)).HandlerFunc(s.RecentChanges.GetRecentChangesForUser).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/recent/user/{id:%s}", UUIDPattern)).Handler(permit(models.ViewActivity, s.RecentChanges.GetRecentChangesForUser)).Methods(http.MethodGet)
+	authorisation.Path("/recent/unread/count").HandlerFunc(s.RecentChanges.CountUnread).Methods(http.MethodGet)
+	authorisation.Path("/recent/read").HandlerFunc(s.RecentChanges.MarkAllRead).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/recent/{id:%s}/read", UUIDPattern)).HandlerFunc(s.RecentChanges.MarkRead).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/recent/{id:%s}/read", UUIDPattern)).HandlerFunc(s.RecentChanges.MarkUnread).Methods(http.MethodDelete)
+	authorisation.Path(fmt.Sprintf("/recent/{id:%s}", UUIDPattern)).HandlerFunc(s.RecentChanges.Dismiss).Methods(http.MethodDelete)
 
 	authorisation.Path("/vacations").HandlerFunc(s.Vacation.GetMyVacation).Methods(http.MethodGet)
 	authorisation.Path("/vacations").HandlerFunc(s.Vacation.CreateNew).Methods(http.MethodPost)
 	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}", UUIDPattern)).HandlerFunc(s.Vacation.GetByID).Methods(http.MethodGet)
 	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}", UUIDPattern)).HandlerFunc(s.Vacation.Cancel).Methods(http.MethodDelete)
-	adminOnly.Path(fmt.Sprintf("/vacations/{id:%s}", UUIDPattern)).HandlerFunc(s.Vacation.UpdateStatus).Methods(http.MethodPut)
-	authorisation.Path(fmt.Sprintf("/vacations/user/{id:%s}", UUIDPattern)).HandlerFunc(s.Vacation.GetForUser).Methods(http.MethodGet)
-	authorisation.Path("/vacations/pending").HandlerFunc(s.Vacation.GetPending).Methods(http.MethodGet)
-	authorisation.Path("/vacations/all").HandlerFunc(s.Vacation.GetAll).Methods(http.MethodGet)
-	router.Path("/vacations/expired").HandlerFunc(s.Vacation.UpdateExpired).Methods(http.MethodPost)
+	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}", UUIDPattern)).HandlerFunc(s.Vacation.UpdateStatus).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}/cancellation", UUIDPattern)).HandlerFunc(s.Vacation.DecideCancellation).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}/cancellation", UUIDPattern)).HandlerFunc(s.Vacation.WithdrawCancellation).Methods(http.MethodDelete)
+	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}/end", UUIDPattern)).HandlerFunc(s.Vacation.Shorten).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}/comments", UUIDPattern)).HandlerFunc(s.Vacation.GetComments).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/vacations/{id:%s}/comments", UUIDPattern)).HandlerFunc(s.Vacation.AddComment).Methods(http.MethodPost)
+	authorisation.Path(fmt.Sprintf("/vacations/user/{id:%s}", UUIDPattern)).Handler(permit(models.ManageVacations, s.Vacation.GetForUser)).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/vacations/user/{id:%s}/balance", UUIDPattern)).HandlerFunc(s.Vacation.GetBalance).Methods(http.MethodGet)
+	authorisation.Path("/vacations/pending").Handler(permit(models.ApproveVacations, s.Vacation.GetPending)).Methods(http.MethodGet)
+	authorisation.Path("/vacations/all").Handler(permit(models.ApproveVacations, s.Vacation.GetAll)).Methods(http.MethodGet)
+	authorisation.Path("/vacations/calendar").HandlerFunc(s.Vacation.GetCalendar).Methods(http.MethodGet)
+	authorisation.Path("/vacations/feed").HandlerFunc(s.Vacation.IssueFeedToken).Methods(http.MethodPost)
+	authorisation.Path("/vacations/feed").HandlerFunc(s.Vacation.RevokeFeedToken).Methods(http.MethodDelete)
+	router.Path(fmt.Sprintf("/vacations/feed/{token:%s}.ics", FeedTokenPattern)).HandlerFunc(s.Vacation.GetFeed).Methods(http.MethodGet)
+	authorisation.Path("/vacations/expired").Handler(permit(models.RunJobs, s.Vacation.UpdateExpired)).Methods(http.MethodPost)
+
+	authorisation.Path("/events").HandlerFunc(s.Events.Stream).Methods(http.MethodGet)
+
+	authorisation.Path("/notifications/preferences").HandlerFunc(s.Notifications.GetPreferences).Methods(http.MethodGet)
+	authorisation.Path("/notifications/preferences").HandlerFunc(s.Notifications.UpdatePreferences).Methods(http.MethodPut)
+	authorisation.Path("/notifications/deliveries").HandlerFunc(s.Notifications.GetDeliveries).Methods(http.MethodGet)
+	authorisation.Path("/notifications/digest").HandlerFunc(s.Notifications.GetDigest).Methods(http.MethodGet)
+	authorisation.Path("/notifications/digest").HandlerFunc(s.Notifications.UpdateDigest).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/notifications/digest/preview/{id:%s}", UUIDPattern)).Handler(permit(models.ManageNotifications, s.Notifications.PreviewDigest)).Methods(http.MethodGet)
+
+	authorisation.Path("/webhooks").Handler(permit(models.ManageWebhooks, s.Webhooks.GetSubscriptions)).Methods(http.MethodGet)
+	authorisation.Path("/webhooks").Handler(permit(models.ManageWebhooks, s.Webhooks.CreateSubscription)).Methods(http.MethodPost)
+	authorisation.Path(fmt.Sprintf("/webhooks/{id:%s}", UUIDPattern)).Handler(permit(models.ManageWebhooks, s.Webhooks.UpdateSubscription)).Methods(http.MethodPut)
+	authorisation.Path(fmt.Sprintf("/webhooks/{id:%s}", UUIDPattern)).Handler(permit(models.ManageWebhooks, s.Webhooks.DeleteSubscription)).Methods(http.MethodDelete)
+	authorisation.Path("/webhooks/deliveries").Handler(permit(models.ManageWebhooks, s.Webhooks.GetDeliveries)).Methods(http.MethodGet)
+	authorisation.Path(fmt.Sprintf("/webhooks/deliveries/{id:%s}/replay", UUIDPattern)).Handler(permit(models.ManageWebhooks, s.Webhooks.Replay)).Methods(http.MethodPost)
+
+	authorisation.Path("/audit").Handler(permit(models.ViewAudit, s.Audit.Search)).Methods(http.MethodGet)
+	authorisation.Path("/audit/export").Handler(permit(models.ViewAudit, s.Audit.Export)).Methods(http.MethodGet)
+	authorisation.Path("/audit/verify").Handler(permit(models.ViewAudit, s.Audit.Verify)).Methods(http.MethodGet)
 
 	var corsRouter = mux.NewRouter()
 	{
@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+)
+
+const (
+	csvContentType = "text/csv; charset=utf-8"
+	exportPageSize = 1000
+
+	actor      = "actor"
+	action     = "action"
+	targetType = "targetType"
+	targetID   = "targetID"
+	from       = "from"
+	to         = "to"
+	limit      = "limit"
+	offset     = "offset"
+	beforeSeq  = "beforeSeq"
+)
+
+var csvHeader = []string{
+	"seq", "id", "time", "actorID", "action", "targetType", "targetID",
+	"statusCode", "transactionID", "sourceIP", "diff", "before", "after", "prevHash", "hash",
+}
+
+type Service interface {
+	Search(w http.ResponseWriter, r *http.Request)
+	Export(w http.ResponseWriter, r *http.Request)
+	Verify(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, audit audit.AuditUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:     r,
+		audit: audit,
+		log:   log,
+	}
+}
+
+type serviceImpl struct {
+	r     *rest.Service
+	audit audit.AuditUsecase
+	log   logger.Logger
+}
+
+func (s *serviceImpl) Search(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		s.log.Warnf(txID, "invalid audit filter: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid audit filter: err=%s", err)
+		return
+	}
+
+	entries, err := s.audit.Search(ctx, filter)
+	if err != nil {
+		s.log.Warnf(txID, "Search(ctx, filter=%+v) err=%s", filter, err)
+		s.r.SendInternalServerError(ctx, w, "audit log retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, entries)
+}
+
+// Export renders all entries matching filter as CSV, limit and offset are ignored.
+// Pages are read by sequence number cursor, so entries appended during export do not shift pages.
+func (s *serviceImpl) Export(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		buf  bytes.Buffer
+	)
+
+	filter, err := parseFilter(r)
+	if err != nil {
+		s.log.Warnf(txID, "invalid audit filter: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid audit filter: err=%s", err)
+		return
+	}
+	filter.Limit = exportPageSize
+	filter.Offset = 0
+
+	cw := csv.NewWriter(&buf)
+	_ = cw.Write(csvHeader)
+	for {
+		entries, err := s.audit.Search(ctx, filter)
+		if err != nil {
+			s.log.Warnf(txID, "Search(ctx, filter=%+v) err=%s", filter, err)
+			s.r.SendInternalServerError(ctx, w, "audit log export failed")
+			return
+		}
+
+		for _, e := range entries {
+			_ = cw.Write([]string{
+				strconv.FormatInt(e.Seq, 10), e.ID.String(), e.Time.Format(time.RFC3339Nano), e.ActorID, e.Action,
+				e.TargetType, e.TargetID, strconv.Itoa(e.StatusCode), e.TransactionID, e.SourceIP,
+				e.Diff, e.Before, e.After, e.PrevHash, e.Hash,
+			})
+		}
+
+		if len(entries) < exportPageSize {
+			break
+		}
+		filter.BeforeSeq = entries[len(entries)-1].Seq
+	}
+	cw.Flush()
+
+	if err := cw.Error(); err != nil {
+		s.log.Warnf(txID, "cannot write audit export: err=%s", err)
+		s.r.SendInternalServerError(ctx, w, "audit log export failed")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+	s.r.RenderContent(ctx, w, csvContentType, buf.Bytes())
+}
+
+func (s *serviceImpl) Verify(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	result, err := s.audit.Verify(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "Verify(ctx) err=%s", err)
+		s.r.SendInternalServerError(ctx, w, "audit log verification failed")
+		return
+	}
+
+	if !result.Valid {
+		s.log.Errorf(txID, "audit log chain is broken at seq=%d", result.BrokenAt)
+	}
+
+	s.r.RenderJSON(ctx, w, result)
+}
+
+func parseFilter(r *http.Request) (models.AuditFilter, error) {
+	var (
+		query  = r.URL.Query()
+		filter = models.AuditFilter{
+			ActorID:    query.Get(actor),
+			Action:     query.Get(action),
+			TargetType: query.Get(targetType),
+			TargetID:   query.Get(targetID),
+		}
+		err error
+	)
+
+	if v := query.Get(from); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, err
+		}
+		filter.From = &t
+	}
+
+	if v := query.Get(to); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, err
+		}
+		filter.To = &t
+	}
+
+	if v := query.Get(limit); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, err
+		}
+	}
+
+	if v := query.Get(offset); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, err
+		}
+	}
+
+	if v := query.Get(beforeSeq); v != "" {
+		if filter.BeforeSeq, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/Dimitriy14/staff-manager/logger"
 	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
 	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
 	"github.com/Dimitriy14/staff-manager/usecases/auth"
 	"github.com/Dimitriy14/staff-manager/web/services/rest"
 
@@ -70,6 +71,8 @@ func (a *authService) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	u.ID = uuid.New()
+	u.Status = models.Invited
+	audit.SetAction(ctx, "auth.sign_up")
 
 	err = a.authentication.SignUp(ctx, u)
 	if err != nil {
@@ -85,6 +88,7 @@ func (a *authService) SignUp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.SetChange(ctx, "user", u.ID.String(), nil, u)
 	a.r.RenderJSON(ctx, w, u)
 }
 
@@ -108,6 +112,8 @@ func (a *authService) SignIn(w http.ResponseWriter, r *http.Request) {
 		a.r.SendBadRequest(ctx, w, "invalid credentials payload: %s", err)
 		return
 	}
+	audit.SetAction(ctx, "auth.sign_in")
+	audit.SetChange(ctx, "user", cred.Email, nil, nil)
 
 	aout, err := a.authentication.SignIn(ctx, cred.Email, cred.Password)
 	if err != nil {
@@ -147,6 +153,7 @@ func (a *authService) successfullyAuthorised(ctx context.Context, w http.Respons
 		return
 	}
 
+	audit.SetActor(ctx, ua.UserID)
 	util.SetSecureTokens(*aout, w)
 	a.r.RenderJSON(ctx, w, ua)
 }
@@ -171,6 +178,8 @@ func (a *authService) RequiredPassword(w http.ResponseWriter, r *http.Request) {
 		a.r.SendBadRequest(ctx, w, "invalid credentials payload: %s", err)
 		return
 	}
+	audit.SetAction(ctx, "auth.password_change")
+	audit.SetChange(ctx, "user", cred.Email, nil, nil)
 
 	c, err := r.Cookie(sessionCookie)
 	if err != nil {
@@ -186,14 +195,43 @@ func (a *authService) RequiredPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	a.activate(ctx, *token)
 	a.successfullyAuthorised(ctx, w, token)
 }
 
+// activate moves invited user to active when the user sets a password, sign-in is not affected if it fails
+func (a *authService) activate(ctx context.Context, token models.AuthOutput) {
+	txID := transactionID.FromContext(ctx)
+
+	ua, _, err := a.authentication.GetUserAccess(ctx, token.AccessToken)
+	if err != nil || ua == nil {
+		a.log.Warnf(txID, "cannot get user access to activate user: err=%v", err)
+		return
+	}
+
+	user, err := a.user.GetUserByID(ctx, ua.UserID)
+	if err != nil {
+		a.log.Warnf(txID, "cannot get user(%s) to activate: err=%s", ua.UserID, err)
+		return
+	}
+
+	if user.Status != models.Invited {
+		return
+	}
+
+	user.Status = models.Active
+	err = a.user.Update(ctx, user)
+	if err != nil {
+		a.log.Warnf(txID, "cannot activate user(%s): err=%s", ua.UserID, err)
+	}
+}
+
 func (a *authService) SignOut(w http.ResponseWriter, r *http.Request) {
 	var (
 		ctx = r.Context()
 	)
 
+	audit.SetAction(ctx, "auth.sign_out")
 	util.SetSecureTokens(models.AuthOutput{}, w)
 
 	a.r.RenderJSON(ctx, w, struct{}{})
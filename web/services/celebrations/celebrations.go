@@ -0,0 +1,63 @@
+package celebrations
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/usecases/celebrations"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+)
+
+const (
+	daysParam = "days"
+
+	defaultDays = 30
+	maxDays     = 366
+)
+
+type Service interface {
+	GetUpcoming(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, celebrations celebrations.CelebrationsUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:            r,
+		celebrations: celebrations,
+		log:          log,
+	}
+}
+
+type serviceImpl struct {
+	r            *rest.Service
+	celebrations celebrations.CelebrationsUsecase
+	log          logger.Logger
+}
+
+func (s *serviceImpl) GetUpcoming(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		days = defaultDays
+		err  error
+	)
+
+	if v := r.URL.Query().Get(daysParam); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 1 || days > maxDays {
+			s.log.Warnf(txID, "invalid days parameter %s", v)
+			s.r.SendBadRequest(ctx, w, "days parameter should be a number from 1 to %d", maxDays)
+			return
+		}
+	}
+
+	upcoming, err := s.celebrations.Upcoming(ctx, days)
+	if err != nil {
+		s.log.Warnf(txID, "Upcoming(ctx, days=%d) err=%s", days, err)
+		s.r.SendInternalServerError(ctx, w, "celebrations retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, upcoming)
+}
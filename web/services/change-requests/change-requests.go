@@ -0,0 +1,194 @@
+package changerequests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	changerequests "github.com/Dimitriy14/staff-manager/usecases/change-requests"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	statusParam = "status"
+	userIDParam = "userID"
+)
+
+var statuses = map[models.ChangeRequestStatus]bool{
+	models.ChangeRequestPending:  true,
+	models.ChangeRequestApproved: true,
+	models.ChangeRequestRejected: true,
+	models.ChangeRequestCanceled: true,
+}
+
+type Service interface {
+	Submit(w http.ResponseWriter, r *http.Request)
+	GetOwn(w http.ResponseWriter, r *http.Request)
+	Cancel(w http.ResponseWriter, r *http.Request)
+	Search(w http.ResponseWriter, r *http.Request)
+	Review(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, requests changerequests.ChangeRequestsUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:        r,
+		requests: requests,
+		log:      log,
+	}
+}
+
+type serviceImpl struct {
+	r        *rest.Service
+	requests changerequests.ChangeRequestsUsecase
+	log      logger.Logger
+}
+
+func (s *serviceImpl) Submit(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+		req  models.ProfileChangeRequestReq
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.ProfileChangeRequest, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	request, err := s.requests.Submit(ctx, ua.UserID, req)
+	if err != nil {
+		s.log.Warnf(txID, "Submit(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.sendChangeRequestError(ctx, w, err, "change request submitting failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, request)
+}
+
+func (s *serviceImpl) GetOwn(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	requests, err := s.requests.Search(ctx, models.ChangeRequestFilter{UserID: ua.UserID})
+	if err != nil {
+		s.log.Warnf(txID, "Search(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.sendChangeRequestError(ctx, w, err, "change requests retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, requests)
+}
+
+func (s *serviceImpl) Cancel(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	request, err := s.requests.Cancel(ctx, ua.UserID, id)
+	if err != nil {
+		s.log.Warnf(txID, "Cancel(ctx, userID=%s, id=%s) err=%s", ua.UserID, id, err)
+		s.sendChangeRequestError(ctx, w, err, "change request canceling failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, request)
+}
+
+// Search returns change requests of all users, they can be filtered by status and user
+func (s *serviceImpl) Search(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		txID   = transactionID.FromContext(ctx)
+		query  = r.URL.Query()
+		filter = models.ChangeRequestFilter{
+			UserID: query.Get(userIDParam),
+			Status: models.ChangeRequestStatus(query.Get(statusParam)),
+		}
+	)
+
+	if filter.Status != "" && !statuses[filter.Status] {
+		s.log.Warnf(txID, "invalid status parameter %s", filter.Status)
+		s.r.SendBadRequest(ctx, w, "status parameter should be one of Pending, Approved, Rejected, Canceled")
+		return
+	}
+
+	requests, err := s.requests.Search(ctx, filter)
+	if err != nil {
+		s.log.Warnf(txID, "Search(ctx, filter=%+v) err=%s", filter, err)
+		s.sendChangeRequestError(ctx, w, err, "change requests retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, requests)
+}
+
+func (s *serviceImpl) Review(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		txID   = transactionID.FromContext(ctx)
+		ua     = util.GetUserAccessFromCtx(ctx)
+		id     = mux.Vars(r)["id"]
+		review models.ChangeRequestReview
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.ChangeRequestReview, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	err = json.Unmarshal(body, &review)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	request, err := s.requests.Review(ctx, ua.UserID, id, review)
+	if err != nil {
+		s.log.Warnf(txID, "Review(ctx, id=%s, status=%s) err=%s", id, review.Status, err)
+		s.sendChangeRequestError(ctx, w, err, "change request reviewing failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, request)
+}
+
+func (s *serviceImpl) sendChangeRequestError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrNotFound(err):
+		s.r.SendNotFound(ctx, w, "%s: %s", message, err)
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	case models.IsErrConflict(err):
+		s.r.SendConflict(ctx, w, "%s: %s", message, err)
+	case models.IsErrForbidden(err):
+		s.r.SendForbidden(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}
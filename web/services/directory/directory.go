@@ -0,0 +1,147 @@
+package directory
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+	"github.com/Dimitriy14/staff-manager/usecases/directory"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+)
+
+const (
+	csvContentType  = "text/csv"
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// maxImportSize limits size of imported file
+	maxImportSize = 10 << 20
+
+	dryRunParam  = "dryRun"
+	columnsParam = "columns"
+)
+
+type Service interface {
+	Import(w http.ResponseWriter, r *http.Request)
+	Export(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, directory directory.DirectoryUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:         r,
+		directory: directory,
+		log:       log,
+	}
+}
+
+type serviceImpl struct {
+	r         *rest.Service
+	directory directory.DirectoryUsecase
+	log       logger.Logger
+}
+
+// Import creates users from CSV or XLSX file passed as request body, format is chosen by content type
+func (s *serviceImpl) Import(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		txID   = transactionID.FromContext(ctx)
+		dryRun bool
+		err    error
+	)
+
+	if v := r.URL.Query().Get(dryRunParam); v != "" {
+		dryRun, err = strconv.ParseBool(v)
+		if err != nil {
+			s.log.Warnf(txID, "invalid dryRun parameter: err=%s", err)
+			s.r.SendBadRequest(ctx, w, "invalid dryRun parameter: err=%s", err)
+			return
+		}
+	}
+
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		s.log.Warnf(txID, "invalid content type: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid content type: err=%s", err)
+		return
+	}
+
+	content, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	defer util.CloseReqBody(s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "cannot read imported file: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "cannot read imported file: err=%s", err)
+		return
+	}
+
+	var rows []models.ImportRow
+	switch contentType {
+	case csvContentType:
+		rows, err = directory.ReadCSV(bytes.NewReader(content))
+	case xlsxContentType:
+		rows, err = directory.ReadXLSX(content)
+	default:
+		s.log.Warnf(txID, "unsupported content type %s", contentType)
+		s.r.SendBadRequest(ctx, w, "unsupported content type %s, use %s or %s", contentType, csvContentType, xlsxContentType)
+		return
+	}
+	if err != nil {
+		s.log.Warnf(txID, "cannot parse imported file: err=%s", err)
+		s.sendDirectoryError(ctx, w, err, "imported file parsing failed")
+		return
+	}
+
+	report, err := s.directory.Import(ctx, rows, dryRun)
+	if err != nil {
+		s.log.Warnf(txID, "Import(ctx, dryRun=%t) err=%s", dryRun, err)
+		s.sendDirectoryError(ctx, w, err, "users import failed")
+		return
+	}
+
+	if !dryRun {
+		audit.SetAction(ctx, "user.import")
+		audit.SetChange(ctx, "user", "", nil, report)
+	}
+	s.r.RenderJSON(ctx, w, report)
+}
+
+// Export renders all users as CSV, columns are passed as comma separated list
+func (s *serviceImpl) Export(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx     = r.Context()
+		txID    = transactionID.FromContext(ctx)
+		columns []string
+		buf     bytes.Buffer
+	)
+
+	if c := r.URL.Query().Get(columnsParam); c != "" {
+		columns = strings.Split(c, ",")
+	}
+
+	err := s.directory.Export(ctx, &buf, columns)
+	if err != nil {
+		s.log.Warnf(txID, "Export(ctx, columns=%v) err=%s", columns, err)
+		s.sendDirectoryError(ctx, w, err, "users export failed")
+		return
+	}
+
+	audit.SetAction(ctx, "user.export")
+	w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+	s.r.RenderContent(ctx, w, csvContentType+"; charset=utf-8", buf.Bytes())
+}
+
+func (s *serviceImpl) sendDirectoryError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}
@@ -0,0 +1,94 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+)
+
+// heartbeatInterval keeps idle connection alive behind proxies and load balancers
+const heartbeatInterval = 30 * time.Second
+
+type Subscriber interface {
+	Subscribe(userID string) (<-chan models.RecentChanges, func())
+}
+
+type Service interface {
+	Stream(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, sub Subscriber, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:   r,
+		sub: sub,
+		log: log,
+	}
+}
+
+type serviceImpl struct {
+	r   *rest.Service
+	sub Subscriber
+	log logger.Logger
+}
+
+// Stream sends recent changes of current user as Server-Sent Events
+func (s *serviceImpl) Stream(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.log.Errorf(txID, "response writer does not support streaming")
+		s.r.SendInternalServerError(ctx, w, "streaming is not supported")
+		return
+	}
+
+	changes, unsubscribe := s.sub.Subscribe(ua.UserID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case change := <-changes:
+			data, err := json.Marshal(change)
+			if err != nil {
+				s.log.Errorf(txID, "cannot marshal recent change %s: err=%s", change.ID, err)
+				continue
+			}
+
+			_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", change.ID, change.Type, data)
+			if err != nil {
+				s.log.Warnf(txID, "event stream of user %s is closed: err=%s", ua.UserID, err)
+				return
+			}
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				s.log.Warnf(txID, "event stream of user %s is closed: err=%s", ua.UserID, err)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,156 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+	"github.com/Dimitriy14/staff-manager/usecases/lifecycle"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+
+	"github.com/gorilla/mux"
+)
+
+type Service interface {
+	UpdateStatus(w http.ResponseWriter, r *http.Request)
+	Terminate(w http.ResponseWriter, r *http.Request)
+	Onboard(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, lifecycle lifecycle.LifecycleUsecase, user repository.UserRepository, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:         r,
+		lifecycle: lifecycle,
+		user:      user,
+		log:       log,
+	}
+}
+
+type serviceImpl struct {
+	r         *rest.Service
+	lifecycle lifecycle.LifecycleUsecase
+	user      repository.UserRepository
+	log       logger.Logger
+}
+
+func (s *serviceImpl) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		txID   = transactionID.FromContext(ctx)
+		id     = mux.Vars(r)["id"]
+		update models.EmploymentStatusUpdate
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.EmploymentStatusUpdate, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	err = json.Unmarshal(body, &update)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	before, err := s.user.GetUserByID(ctx, id)
+	if err != nil {
+		s.log.Warnf(txID, "GetUserByID(ctx, id=%s) err=%s", id, err)
+		s.sendLifecycleError(ctx, w, err, "user retrieving failed")
+		return
+	}
+
+	user, err := s.lifecycle.UpdateStatus(ctx, id, update.Status)
+	if err != nil {
+		s.log.Warnf(txID, "UpdateStatus(ctx, userID=%s, status=%s) err=%s", id, update.Status, err)
+		s.sendLifecycleError(ctx, w, err, "employment status updating failed")
+		return
+	}
+
+	audit.SetAction(ctx, "user.status_change")
+	audit.SetChange(ctx, "user", id, before, user)
+	s.r.RenderJSON(ctx, w, user)
+}
+
+func (s *serviceImpl) Terminate(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+		req  models.TerminationReq
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.Termination, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	before, err := s.user.GetUserByID(ctx, id)
+	if err != nil {
+		s.log.Warnf(txID, "GetUserByID(ctx, id=%s) err=%s", id, err)
+		s.sendLifecycleError(ctx, w, err, "user retrieving failed")
+		return
+	}
+
+	result, err := s.lifecycle.Terminate(ctx, id, req)
+	if err != nil {
+		s.log.Warnf(txID, "Terminate(ctx, userID=%s) err=%s", id, err)
+		s.sendLifecycleError(ctx, w, err, "user termination failed")
+		return
+	}
+
+	// reassigned tasks and canceled vacations are recorded under termination action
+	audit.SetAction(ctx, "user.terminate")
+	audit.SetChange(ctx, "user", id, before, result.User)
+	s.r.RenderJSON(ctx, w, result)
+}
+
+func (s *serviceImpl) Onboard(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	tasks, err := s.lifecycle.Onboard(ctx, id)
+	if err != nil {
+		s.log.Warnf(txID, "Onboard(ctx, userID=%s) err=%s", id, err)
+		s.sendLifecycleError(ctx, w, err, "user onboarding failed")
+		return
+	}
+
+	audit.SetAction(ctx, "user.onboard")
+	audit.SetChange(ctx, "user", id, nil, tasks)
+	s.r.RenderJSON(ctx, w, tasks)
+}
+
+func (s *serviceImpl) sendLifecycleError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrNotFound(err):
+		s.r.SendNotFound(ctx, w, "%s: %s", message, err)
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	case models.IsErrConflict(err):
+		s.r.SendConflict(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}
@@ -0,0 +1,170 @@
+package mood
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+	"github.com/Dimitriy14/staff-manager/usecases/mood"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+)
+
+const (
+	from         = "from"
+	to           = "to"
+	managerID    = "managerID"
+	departmentID = "departmentID"
+	weeks        = "weeks"
+
+	defaultHistoryDays = 90
+	defaultWeeks       = 8
+	maxWeeks           = 52
+)
+
+type Service interface {
+	CheckIn(w http.ResponseWriter, r *http.Request)
+	GetHistory(w http.ResponseWriter, r *http.Request)
+	GetTeamPulse(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, mood mood.MoodUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:    r,
+		mood: mood,
+		log:  log,
+	}
+}
+
+type serviceImpl struct {
+	r    *rest.Service
+	mood mood.MoodUsecase
+	log  logger.Logger
+}
+
+func (s *serviceImpl) CheckIn(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+		req  models.MoodCheckInReq
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.MoodCheckIn, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	checkIn, err := s.mood.CheckIn(ctx, ua.UserID, req)
+	if err != nil {
+		s.log.Warnf(txID, "CheckIn(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.sendMoodError(ctx, w, err, "mood check-in failed")
+		return
+	}
+
+	// check-in is private, so its score and note are not written to audit log
+	audit.SetAction(ctx, "user.mood_checkin")
+	s.r.RenderJSON(ctx, w, checkIn)
+}
+
+// GetHistory returns check-ins of current user, the last 90 days are returned by default
+func (s *serviceImpl) GetHistory(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		txID  = transactionID.FromContext(ctx)
+		ua    = util.GetUserAccessFromCtx(ctx)
+		query = r.URL.Query()
+		end   = time.Now().UTC()
+		start = end.AddDate(0, 0, -defaultHistoryDays)
+		err   error
+	)
+
+	if v := query.Get(from); v != "" {
+		start, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.log.Warnf(txID, "invalid from parameter %s: err=%s", v, err)
+			s.r.SendBadRequest(ctx, w, "from parameter should be in RFC3339 format")
+			return
+		}
+	}
+
+	if v := query.Get(to); v != "" {
+		end, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.log.Warnf(txID, "invalid to parameter %s: err=%s", v, err)
+			s.r.SendBadRequest(ctx, w, "to parameter should be in RFC3339 format")
+			return
+		}
+	}
+
+	history, err := s.mood.History(ctx, ua.UserID, start, end)
+	if err != nil {
+		s.log.Warnf(txID, "History(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.sendMoodError(ctx, w, err, "mood history retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, history)
+}
+
+func (s *serviceImpl) GetTeamPulse(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		txID   = transactionID.FromContext(ctx)
+		ua     = util.GetUserAccessFromCtx(ctx)
+		query  = r.URL.Query()
+		filter = models.PulseFilter{
+			ManagerID:    query.Get(managerID),
+			DepartmentID: query.Get(departmentID),
+			Weeks:        defaultWeeks,
+		}
+		err error
+	)
+
+	if v := query.Get(weeks); v != "" {
+		filter.Weeks, err = strconv.Atoi(v)
+		if err != nil || filter.Weeks < 1 || filter.Weeks > maxWeeks {
+			s.log.Warnf(txID, "invalid weeks parameter %s", v)
+			s.r.SendBadRequest(ctx, w, "weeks parameter should be a number from 1 to %d", maxWeeks)
+			return
+		}
+	}
+
+	pulse, err := s.mood.TeamPulse(ctx, ua, filter)
+	if err != nil {
+		s.log.Warnf(txID, "TeamPulse(ctx, filter=%+v) err=%s", filter, err)
+		s.sendMoodError(ctx, w, err, "team pulse retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, pulse)
+}
+
+func (s *serviceImpl) sendMoodError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrNotFound(err):
+		s.r.SendNotFound(ctx, w, "%s: %s", message, err)
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	case models.IsErrForbidden(err):
+		s.r.SendForbidden(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}
@@ -0,0 +1,204 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/notifications"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	frequencyParam  = "frequency"
+	formatParam     = "format"
+	htmlFormat      = "html"
+	htmlContentType = "text/html; charset=utf-8"
+)
+
+type Service interface {
+	GetPreferences(w http.ResponseWriter, r *http.Request)
+	UpdatePreferences(w http.ResponseWriter, r *http.Request)
+	GetDeliveries(w http.ResponseWriter, r *http.Request)
+	GetDigest(w http.ResponseWriter, r *http.Request)
+	UpdateDigest(w http.ResponseWriter, r *http.Request)
+	PreviewDigest(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, n notifications.NotificationsUsecase, digest notifications.DigestUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:      r,
+		n:      n,
+		digest: digest,
+		log:    log,
+	}
+}
+
+type serviceImpl struct {
+	r      *rest.Service
+	n      notifications.NotificationsUsecase
+	digest notifications.DigestUsecase
+	log    logger.Logger
+}
+
+func (s *serviceImpl) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	prefs, err := s.n.GetPreferences(ctx, ua.UserID)
+	if err != nil {
+		s.log.Warnf(txID, "GetPreferences(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.r.SendInternalServerError(ctx, w, "notification preferences retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, prefs)
+}
+
+func (s *serviceImpl) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.NotificationPreferences, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	var prefs []models.NotificationPreference
+	err = json.Unmarshal(body, &prefs)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	prefs, err = s.n.UpdatePreferences(ctx, ua.UserID, prefs)
+	if err != nil {
+		s.log.Warnf(txID, "UpdatePreferences(ctx, userID=%s) err=%s", ua.UserID, err)
+		if models.IsErrInvalidData(err) {
+			s.r.SendBadRequest(ctx, w, "invalid notification preferences: %s", err)
+			return
+		}
+		s.r.SendInternalServerError(ctx, w, "notification preferences updating failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, prefs)
+}
+
+func (s *serviceImpl) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	deliveries, err := s.n.GetDeliveries(ctx, ua.UserID)
+	if err != nil {
+		s.log.Warnf(txID, "GetDeliveries(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.r.SendInternalServerError(ctx, w, "notification deliveries retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, deliveries)
+}
+
+func (s *serviceImpl) GetDigest(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	sub, err := s.digest.GetSubscription(ctx, ua.UserID)
+	if err != nil {
+		s.log.Warnf(txID, "GetSubscription(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.r.SendInternalServerError(ctx, w, "digest subscription retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, sub)
+}
+
+func (s *serviceImpl) UpdateDigest(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.DigestSubscription, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	var req models.DigestSubscription
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	sub, err := s.digest.UpdateSubscription(ctx, ua.UserID, req.Frequency)
+	if err != nil {
+		s.log.Warnf(txID, "UpdateSubscription(ctx, userID=%s, frequency=%s) err=%s", ua.UserID, req.Frequency, err)
+		s.r.SendInternalServerError(ctx, w, "digest subscription updating failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, sub)
+}
+
+// PreviewDigest renders digest of the user as JSON, or as HTML page if format=html is specified
+func (s *serviceImpl) PreviewDigest(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx       = r.Context()
+		txID      = transactionID.FromContext(ctx)
+		id        = mux.Vars(r)["id"]
+		query     = r.URL.Query()
+		frequency = models.DigestFrequency(query.Get(frequencyParam))
+	)
+
+	switch frequency {
+	case "", models.DailyDigest, models.WeeklyDigest:
+	default:
+		s.log.Warnf(txID, "invalid digest frequency: %s", frequency)
+		s.r.SendBadRequest(ctx, w, "invalid digest frequency: %s", frequency)
+		return
+	}
+
+	preview, err := s.digest.Preview(ctx, id, frequency)
+	if err != nil {
+		s.log.Warnf(txID, "Preview(ctx, userID=%s, frequency=%s) err=%s", id, frequency, err)
+		if models.IsErrNotFound(err) {
+			s.r.SendNotFound(ctx, w, "user with id=%s is not found", id)
+			return
+		}
+		s.r.SendInternalServerError(ctx, w, "digest preview rendering failed")
+		return
+	}
+
+	if query.Get(formatParam) == htmlFormat {
+		s.r.RenderContent(ctx, w, htmlContentType, []byte(preview.HTML))
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, preview)
+}
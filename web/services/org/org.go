@@ -0,0 +1,246 @@
+package org
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const indirectParam = "indirect"
+
+type Service interface {
+	GetChain(w http.ResponseWriter, r *http.Request)
+	GetReports(w http.ResponseWriter, r *http.Request)
+	GetChart(w http.ResponseWriter, r *http.Request)
+
+	GetDepartments(w http.ResponseWriter, r *http.Request)
+	CreateDepartment(w http.ResponseWriter, r *http.Request)
+	UpdateDepartment(w http.ResponseWriter, r *http.Request)
+	DeleteDepartment(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, org org.OrgUsecase, privacy privacy.PrivacyUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:       r,
+		org:     org,
+		privacy: privacy,
+		log:     log,
+	}
+}
+
+type serviceImpl struct {
+	r       *rest.Service
+	org     org.OrgUsecase
+	privacy privacy.PrivacyUsecase
+	log     logger.Logger
+}
+
+func (s *serviceImpl) GetChain(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	chain, err := s.org.GetChain(ctx, id)
+	if err != nil {
+		s.log.Warnf(txID, "GetChain(ctx, userID=%s) err=%s", id, err)
+		s.sendOrgError(ctx, w, err, "chain of command retrieving failed")
+		return
+	}
+
+	s.privacy.Apply(ctx, privacy.Users(chain)...)
+	s.r.RenderJSON(ctx, w, chain)
+}
+
+func (s *serviceImpl) GetReports(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx      = r.Context()
+		txID     = transactionID.FromContext(ctx)
+		id       = mux.Vars(r)["id"]
+		indirect bool
+		err      error
+	)
+
+	if v := r.URL.Query().Get(indirectParam); v != "" {
+		indirect, err = strconv.ParseBool(v)
+		if err != nil {
+			s.log.Warnf(txID, "invalid indirect parameter: err=%s", err)
+			s.r.SendBadRequest(ctx, w, "invalid indirect parameter: err=%s", err)
+			return
+		}
+	}
+
+	reports, err := s.org.GetReports(ctx, id, indirect)
+	if err != nil {
+		s.log.Warnf(txID, "GetReports(ctx, userID=%s, indirect=%t) err=%s", id, indirect, err)
+		s.sendOrgError(ctx, w, err, "reports retrieving failed")
+		return
+	}
+
+	s.privacy.Apply(ctx, privacy.Users(reports)...)
+	s.r.RenderJSON(ctx, w, reports)
+}
+
+func (s *serviceImpl) GetChart(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	chart, err := s.org.GetChart(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "GetChart(ctx) err=%s", err)
+		s.r.SendInternalServerError(ctx, w, "org chart retrieving failed")
+		return
+	}
+
+	s.privacy.Apply(ctx, chartUsers(chart)...)
+	s.r.RenderJSON(ctx, w, chart)
+}
+
+func chartUsers(nodes []*models.OrgNode) []*models.User {
+	users := make([]*models.User, 0, len(nodes))
+	for _, node := range nodes {
+		users = append(users, &node.User)
+		users = append(users, chartUsers(node.Reports)...)
+	}
+	return users
+}
+
+func (s *serviceImpl) GetDepartments(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	departments, err := s.org.GetDepartments(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "GetDepartments(ctx) err=%s", err)
+		s.r.SendInternalServerError(ctx, w, "departments retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, departments)
+}
+
+func (s *serviceImpl) CreateDepartment(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	req, ok := s.retrieveDepartmentReq(w, r)
+	if !ok {
+		return
+	}
+
+	department, err := s.org.CreateDepartment(ctx, req)
+	if err != nil {
+		s.log.Warnf(txID, "CreateDepartment(ctx, name=%s) err=%s", req.Name, err)
+		s.sendOrgError(ctx, w, err, "department creating failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, department)
+}
+
+func (s *serviceImpl) UpdateDepartment(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	departmentID, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid department id(%s): err=%s", id, err)
+		s.r.SendBadRequest(ctx, w, "invalid department id(%s): err=%s", id, err)
+		return
+	}
+
+	req, ok := s.retrieveDepartmentReq(w, r)
+	if !ok {
+		return
+	}
+
+	department, err := s.org.UpdateDepartment(ctx, departmentID, req)
+	if err != nil {
+		s.log.Warnf(txID, "UpdateDepartment(ctx, id=%s) err=%s", id, err)
+		s.sendOrgError(ctx, w, err, "department updating failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, department)
+}
+
+func (s *serviceImpl) DeleteDepartment(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	departmentID, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid department id(%s): err=%s", id, err)
+		s.r.SendBadRequest(ctx, w, "invalid department id(%s): err=%s", id, err)
+		return
+	}
+
+	err = s.org.DeleteDepartment(ctx, departmentID)
+	if err != nil {
+		s.log.Warnf(txID, "DeleteDepartment(ctx, id=%s) err=%s", id, err)
+		s.sendOrgError(ctx, w, err, "department deleting failed")
+		return
+	}
+
+	s.r.SendNoContent(w)
+}
+
+func (s *serviceImpl) retrieveDepartmentReq(w http.ResponseWriter, r *http.Request) (models.DepartmentReq, bool) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		req  models.DepartmentReq
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.Department, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return req, false
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return req, false
+	}
+	return req, true
+}
+
+func (s *serviceImpl) sendOrgError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrNotFound(err):
+		s.r.SendNotFound(ctx, w, "%s: %s", message, err)
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}
@@ -0,0 +1,174 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+
+	"github.com/gorilla/mux"
+)
+
+type Service interface {
+	GetSettings(w http.ResponseWriter, r *http.Request)
+	UpdateSettings(w http.ResponseWriter, r *http.Request)
+	GetPolicies(w http.ResponseWriter, r *http.Request)
+	UpdatePolicy(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, privacy privacy.PrivacyUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:       r,
+		privacy: privacy,
+		log:     log,
+	}
+}
+
+type serviceImpl struct {
+	r       *rest.Service
+	privacy privacy.PrivacyUsecase
+	log     logger.Logger
+}
+
+func (s *serviceImpl) GetSettings(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	settings, err := s.privacy.GetSettings(ctx, ua.UserID)
+	if err != nil {
+		s.log.Warnf(txID, "GetSettings(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.sendPrivacyError(ctx, w, err, "privacy settings retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, settings)
+}
+
+func (s *serviceImpl) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx      = r.Context()
+		txID     = transactionID.FromContext(ctx)
+		ua       = util.GetUserAccessFromCtx(ctx)
+		settings models.ProfilePrivacy
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.PrivacySettings, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	err = json.Unmarshal(body, &settings)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	before, err := s.privacy.GetSettings(ctx, ua.UserID)
+	if err != nil {
+		s.log.Warnf(txID, "GetSettings(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.sendPrivacyError(ctx, w, err, "privacy settings retrieving failed")
+		return
+	}
+
+	after, err := s.privacy.UpdateSettings(ctx, ua.UserID, settings)
+	if err != nil {
+		s.log.Warnf(txID, "UpdateSettings(ctx, userID=%s) err=%s", ua.UserID, err)
+		s.sendPrivacyError(ctx, w, err, "privacy settings updating failed")
+		return
+	}
+
+	audit.SetAction(ctx, "user.privacy_update")
+	audit.SetChange(ctx, "user", ua.UserID, before, after)
+	s.r.RenderJSON(ctx, w, after)
+}
+
+func (s *serviceImpl) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	policies, err := s.privacy.GetPolicies(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "GetPolicies(ctx) err=%s", err)
+		s.sendPrivacyError(ctx, w, err, "privacy policies retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, policies)
+}
+
+func (s *serviceImpl) UpdatePolicy(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		txID  = transactionID.FromContext(ctx)
+		field = models.ProfileField(mux.Vars(r)["field"])
+		req   models.PrivacyPolicyReq
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.PrivacyPolicy, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	policies, err := s.privacy.GetPolicies(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "GetPolicies(ctx) err=%s", err)
+		s.sendPrivacyError(ctx, w, err, "privacy policies retrieving failed")
+		return
+	}
+
+	var before *models.PrivacyPolicy
+	for i := range policies {
+		if policies[i].Field == field {
+			before = &policies[i]
+		}
+	}
+
+	policy, err := s.privacy.UpdatePolicy(ctx, field, req.Minimum)
+	if err != nil {
+		s.log.Warnf(txID, "UpdatePolicy(ctx, field=%s) err=%s", field, err)
+		s.sendPrivacyError(ctx, w, err, "privacy policy updating failed")
+		return
+	}
+
+	audit.SetAction(ctx, "privacy.policy_update")
+	audit.SetChange(ctx, "privacy_policy", string(field), before, policy)
+	s.r.RenderJSON(ctx, w, policy)
+}
+
+func (s *serviceImpl) sendPrivacyError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrNotFound(err):
+		s.r.SendNotFound(ctx, w, "%s: %s", message, err)
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	case models.IsErrConflict(err):
+		s.r.SendConflict(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}
@@ -1,7 +1,10 @@
 package recent_changes
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -9,28 +12,42 @@ import (
 
 	"github.com/Dimitriy14/staff-manager/logger"
 	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
 	"github.com/Dimitriy14/staff-manager/repository"
+	"github.com/Dimitriy14/staff-manager/usecases/access"
 	"github.com/Dimitriy14/staff-manager/util"
 	"github.com/Dimitriy14/staff-manager/web/services/rest"
 )
 
+const (
+	unread     = "unread"
+	untilParam = "until"
+)
+
 type Service interface {
 	GetRecentChanges(w http.ResponseWriter, r *http.Request)
 	GetRecentChangesForUser(w http.ResponseWriter, r *http.Request)
+	CountUnread(w http.ResponseWriter, r *http.Request)
+	MarkAllRead(w http.ResponseWriter, r *http.Request)
+	MarkRead(w http.ResponseWriter, r *http.Request)
+	MarkUnread(w http.ResponseWriter, r *http.Request)
+	Dismiss(w http.ResponseWriter, r *http.Request)
 }
 
-func NewService(repo repository.RecentActionRepository, r *rest.Service, log logger.Logger) *serviceImpl {
+func NewService(repo repository.RecentActionRepository, authorizer access.Authorizer, r *rest.Service, log logger.Logger) *serviceImpl {
 	return &serviceImpl{
-		repo: repo,
-		r:    r,
-		log:  log,
+		repo:       repo,
+		authorizer: authorizer,
+		r:          r,
+		log:        log,
 	}
 }
 
 type serviceImpl struct {
-	repo repository.RecentActionRepository
-	r    *rest.Service
-	log  logger.Logger
+	repo       repository.RecentActionRepository
+	authorizer access.Authorizer
+	r          *rest.Service
+	log        logger.Logger
 }
 
 func (s *serviceImpl) GetRecentChanges(w http.ResponseWriter, r *http.Request) {
@@ -40,9 +57,20 @@ func (s *serviceImpl) GetRecentChanges(w http.ResponseWriter, r *http.Request) {
 		ua   = util.GetUserAccessFromCtx(ctx)
 	)
 
-	rc, err := s.repo.GetUserChanges(ua.UserID)
+	var unreadOnly bool
+	if v := r.URL.Query().Get(unread); v != "" {
+		var err error
+		unreadOnly, err = strconv.ParseBool(v)
+		if err != nil {
+			s.log.Warnf(txID, "cannot parse unread parameter: %s", err)
+			s.r.SendBadRequest(ctx, w, "cannot parse unread parameter: %s", err)
+			return
+		}
+	}
+
+	rc, err := s.repo.GetInbox(ua.UserID, unreadOnly)
 	if err != nil {
-		s.log.Warnf(txID, "GetUserChanges userID=%s failed due to err=%s", ua.UserID, err)
+		s.log.Warnf(txID, "GetInbox userID=%s failed due to err=%s", ua.UserID, err)
 		s.r.SendInternalServerError(ctx, w, "retrieving user changes failed")
 		return
 	}
@@ -54,6 +82,7 @@ func (s *serviceImpl) GetRecentChangesForUser(w http.ResponseWriter, r *http.Req
 	var (
 		ctx  = r.Context()
 		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
 		id   = mux.Vars(r)["id"]
 	)
 
@@ -64,6 +93,18 @@ func (s *serviceImpl) GetRecentChangesForUser(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	ok, err := s.authorizer.Can(ctx, ua, models.ViewActivity, uid.String())
+	if err != nil {
+		s.log.Errorf(txID, "cannot check permission %s for user %s: %s", models.ViewActivity, ua.UserID, err)
+		s.r.SendInternalServerError(ctx, w, "retrieving user changes failed")
+		return
+	}
+	if !ok {
+		s.log.Warnf(txID, "user %s cannot view changes of user %s", ua.UserID, uid)
+		s.r.SendForbidden(ctx, w, "changes of user with id=%s cannot be viewed", uid)
+		return
+	}
+
 	rc, err := s.repo.GetUserChanges(uid.String())
 	if err != nil {
 		s.log.Warnf(txID, "GetRecentChangesForUser userID=%s failed due to err=%s", uid.String(), err)
@@ -73,3 +114,105 @@ func (s *serviceImpl) GetRecentChangesForUser(w http.ResponseWriter, r *http.Req
 
 	s.r.RenderJSON(ctx, w, rc)
 }
+
+func (s *serviceImpl) CountUnread(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	count, err := s.repo.CountUnread(ua.UserID)
+	if err != nil {
+		s.log.Warnf(txID, "CountUnread userID=%s failed due to err=%s", ua.UserID, err)
+		s.r.SendInternalServerError(ctx, w, "counting unread changes failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, models.UnreadCount{Count: count})
+}
+
+func (s *serviceImpl) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+	)
+
+	until, err := time.Parse(time.RFC3339, r.URL.Query().Get(untilParam))
+	if err != nil {
+		s.r.SendBadRequest(ctx, w, "until parameter should be in RFC3339 format")
+		return
+	}
+
+	err = s.repo.MarkAllRead(ua.UserID, until)
+	if err != nil {
+		s.log.Warnf(txID, "MarkAllRead userID=%s until=%s failed due to err=%s", ua.UserID, until, err)
+		s.r.SendInternalServerError(ctx, w, "marking changes as read failed")
+		return
+	}
+
+	s.r.SendNoContent(w)
+}
+
+func (s *serviceImpl) MarkRead(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	err := s.repo.MarkRead(ua.UserID, id)
+	if err != nil {
+		s.log.Warnf(txID, "MarkRead userID=%s changeID=%s failed due to err=%s", ua.UserID, id, err)
+		s.sendMarkError(ctx, w, err)
+		return
+	}
+
+	s.r.SendNoContent(w)
+}
+
+func (s *serviceImpl) MarkUnread(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	err := s.repo.MarkUnread(ua.UserID, id)
+	if err != nil {
+		s.log.Warnf(txID, "MarkUnread userID=%s changeID=%s failed due to err=%s", ua.UserID, id, err)
+		s.sendMarkError(ctx, w, err)
+		return
+	}
+
+	s.r.SendNoContent(w)
+}
+
+func (s *serviceImpl) Dismiss(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		ua   = util.GetUserAccessFromCtx(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	err := s.repo.Dismiss(ua.UserID, id)
+	if err != nil {
+		s.log.Warnf(txID, "Dismiss userID=%s changeID=%s failed due to err=%s", ua.UserID, id, err)
+		s.sendMarkError(ctx, w, err)
+		return
+	}
+
+	s.r.SendNoContent(w)
+}
+
+func (s *serviceImpl) sendMarkError(ctx context.Context, w http.ResponseWriter, err error) {
+	if models.IsErrNotFound(err) {
+		s.r.SendNotFound(ctx, w, "%s", err)
+		return
+	}
+	s.r.SendInternalServerError(ctx, w, "updating recent change failed")
+}
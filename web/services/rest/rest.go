@@ -38,6 +38,16 @@ func (r *Service) RenderJSON(ctx context.Context, w http.ResponseWriter, respons
 	r.render(ctx, w, http.StatusOK, data)
 }
 
+// RenderContent is used for rendering non JSON response body with specified content type
+func (r *Service) RenderContent(ctx context.Context, w http.ResponseWriter, contentType string, data []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(data)
+	if err != nil {
+		r.log.Warnf(transactionID.FromContext(ctx), "Write request failed, error:%v", err)
+	}
+}
+
 func (r *Service) render(ctx context.Context, w http.ResponseWriter, code int, response []byte) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
@@ -65,11 +75,21 @@ func (r *Service) SendUnauthorized(ctx context.Context, w http.ResponseWriter, m
 	r.sendMessage(ctx, w, http.StatusUnauthorized, message, v...)
 }
 
+// SendForbidden sends Forbidden Status and logs an error if it exists
+func (r *Service) SendForbidden(ctx context.Context, w http.ResponseWriter, message string, v ...interface{}) {
+	r.sendMessage(ctx, w, http.StatusForbidden, message, v...)
+}
+
 // SendNotFound sends Not Fount Status and logs an error if it exists
 func (r *Service) SendNotFound(ctx context.Context, w http.ResponseWriter, message string, v ...interface{}) {
 	r.sendMessage(ctx, w, http.StatusNotFound, message, v...)
 }
 
+// SendConflict sends Conflict Status and logs an error if it exists
+func (r *Service) SendConflict(ctx context.Context, w http.ResponseWriter, message string, v ...interface{}) {
+	r.sendMessage(ctx, w, http.StatusConflict, message, v...)
+}
+
 // SendInternalServerError sends Internal Server Error Status and logs an error if it exists
 func (r *Service) SendInternalServerError(ctx context.Context, w http.ResponseWriter, message string, v ...interface{}) {
 	r.sendMessage(ctx, w, http.StatusInternalServerError, message, v...)
@@ -1,6 +1,7 @@
 package tasks
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 	"github.com/Dimitriy14/staff-manager/logger"
 	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
 	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
 	"github.com/Dimitriy14/staff-manager/usecases/tasks"
 	"github.com/Dimitriy14/staff-manager/util"
 	"github.com/Dimitriy14/staff-manager/web/services/rest"
@@ -23,17 +25,19 @@ const (
 	size = "size"
 )
 
-func NewTaskService(taskuc tasks.TaskUsecase, r *rest.Service, log logger.Logger) *taskService {
+func NewTaskService(taskuc tasks.TaskUsecase, privacy privacy.PrivacyUsecase, r *rest.Service, log logger.Logger) *taskService {
 	return &taskService{
-		taskuc: taskuc,
-		r:      r,
-		log:    log,
+		taskuc:  taskuc,
+		privacy: privacy,
+		r:       r,
+		log:     log,
 	}
 }
 
 type taskService struct {
-	taskuc tasks.TaskUsecase
-	r      *rest.Service
+	taskuc  tasks.TaskUsecase
+	privacy privacy.PrivacyUsecase
+	r       *rest.Service
 
 	log logger.Logger
 }
@@ -67,10 +71,15 @@ func (ts *taskService) GetUserTasks(w http.ResponseWriter, r *http.Request) {
 	t, err := ts.taskuc.GetUserTasks(ctx, uid.String())
 	if err != nil {
 		ts.log.Warnf(txID, "GetUserTasks userID=%s failed due to err=%s", id, err)
+		if models.IsErrForbidden(err) {
+			ts.r.SendForbidden(ctx, w, "tasks of user with id=%s cannot be viewed", id)
+			return
+		}
 		ts.r.SendInternalServerError(ctx, w, "user tasks retrieving failed")
 		return
 	}
 
+	ts.applyPrivacy(ctx, t)
 	ts.r.RenderJSON(ctx, w, t)
 }
 
@@ -88,6 +97,7 @@ func (ts *taskService) GetMyTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ts.applyPrivacy(ctx, t)
 	ts.r.RenderJSON(ctx, w, t)
 }
 
@@ -129,6 +139,7 @@ func (ts *taskService) SaveTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ts.privacy.Apply(ctx, t.CreatedBy, t.UpdatedBy, t.Assigned, t.CoveredBy)
 	ts.r.RenderJSON(ctx, w, t)
 }
 
@@ -168,6 +179,7 @@ func (ts *taskService) GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ts.applyPrivacy(ctx, t)
 	ts.r.RenderJSON(ctx, w, t)
 }
 
@@ -192,6 +204,7 @@ func (ts *taskService) GetTaskByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ts.privacy.Apply(ctx, task.CreatedBy, task.UpdatedBy, task.Assigned, task.CoveredBy)
 	ts.r.RenderJSON(ctx, w, task)
 }
 
@@ -294,10 +307,15 @@ func (ts *taskService) Update(w http.ResponseWriter, r *http.Request) {
 	t, err := ts.taskuc.Update(ctx, task)
 	if err != nil {
 		ts.log.Warnf(txID, "SaveTask userID=%s failed due to err=%s", ua.UserID, err)
+		if models.IsErrForbidden(err) {
+			ts.r.SendForbidden(ctx, w, "task with id=%s cannot be updated", id)
+			return
+		}
 		ts.r.SendInternalServerError(ctx, w, "tasks updating failed")
 		return
 	}
 
+	ts.privacy.Apply(ctx, t.CreatedBy, t.UpdatedBy, t.Assigned, t.CoveredBy)
 	ts.r.RenderJSON(ctx, w, t)
 }
 
@@ -320,7 +338,11 @@ func (ts *taskService) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		ts.log.Warnf(txID, "DeleteTask taskID=%s failed due to err=%s", uid.String(), err)
 		if models.IsErrNotFound(err) {
-			ts.r.SendNotFound(ctx, w, "task with id=%s is not found")
+			ts.r.SendNotFound(ctx, w, "task with id=%s is not found", uid.String())
+			return
+		}
+		if models.IsErrForbidden(err) {
+			ts.r.SendForbidden(ctx, w, "task with id=%s cannot be deleted", uid.String())
 			return
 		}
 		ts.r.SendInternalServerError(ctx, w, "tasks saving failed")
@@ -329,3 +351,12 @@ func (ts *taskService) DeleteTask(w http.ResponseWriter, r *http.Request) {
 
 	ts.r.SendNoContent(w)
 }
+
+// applyPrivacy hides profile fields of task users which current user is not allowed to see
+func (ts *taskService) applyPrivacy(ctx context.Context, list []models.Task) {
+	users := make([]*models.User, 0, 4*len(list))
+	for i := range list {
+		users = append(users, list[i].CreatedBy, list[i].UpdatedBy, list[i].Assigned, list[i].CoveredBy)
+	}
+	ts.privacy.Apply(ctx, users...)
+}
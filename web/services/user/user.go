@@ -8,9 +8,10 @@ import (
 
 	"github.com/Dimitriy14/staff-manager/usecases/photos"
 
+	"github.com/Dimitriy14/staff-manager/usecases/audit"
 	"github.com/Dimitriy14/staff-manager/usecases/auth"
-
-	"github.com/google/uuid"
+	"github.com/Dimitriy14/staff-manager/usecases/org"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
 
 	"github.com/gorilla/mux"
 
@@ -40,6 +41,7 @@ var (
 
 type Service interface {
 	Search(w http.ResponseWriter, r *http.Request)
+	SearchDirectory(w http.ResponseWriter, r *http.Request)
 
 	GetCollege(w http.ResponseWriter, r *http.Request)
 	GetUser(w http.ResponseWriter, r *http.Request)
@@ -51,22 +53,26 @@ type Service interface {
 	UploadImage(w http.ResponseWriter, r *http.Request)
 }
 
-func NewUserService(r *rest.Service, log logger.Logger, user repository.UserRepository, a auth.Authentication, photo photos.Uploader) *userService {
+func NewUserService(r *rest.Service, log logger.Logger, user repository.UserRepository, a auth.Authentication, photo photos.Uploader, org org.OrgUsecase, privacy privacy.PrivacyUsecase) *userService {
 	return &userService{
-		r:     r,
-		log:   log,
-		user:  user,
-		a:     a,
-		photo: photo,
+		r:       r,
+		log:     log,
+		user:    user,
+		a:       a,
+		photo:   photo,
+		org:     org,
+		privacy: privacy,
 	}
 }
 
 type userService struct {
-	r     *rest.Service
-	log   logger.Logger
-	user  repository.UserRepository
-	a     auth.Authentication
-	photo photos.Uploader
+	r       *rest.Service
+	log     logger.Logger
+	user    repository.UserRepository
+	a       auth.Authentication
+	photo   photos.Uploader
+	org     org.OrgUsecase
+	privacy privacy.PrivacyUsecase
 }
 
 func (u *userService) Search(w http.ResponseWriter, r *http.Request) {
@@ -90,9 +96,45 @@ func (u *userService) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	u.privacy.Apply(ctx, privacy.Users(users)...)
 	u.r.RenderJSON(ctx, w, users)
 }
 
+func (u *userService) SearchDirectory(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		us   models.UserSearch
+	)
+
+	err := json.NewDecoder(r.Body).Decode(&us)
+	if err != nil {
+		u.log.Warnf(txID, "cannot decode user search: err=%s", err)
+		u.r.SendBadRequest(ctx, w, "invalid user search payload: %s", err)
+		return
+	}
+
+	if us.Offset < 0 || us.Limit < 0 {
+		u.log.Warnf(txID, "invalid user search limit(%d) or offset(%d)", us.Limit, us.Offset)
+		u.r.SendBadRequest(ctx, w, "invalid user search payload: limit and offset cannot be negative")
+		return
+	}
+
+	result, err := u.user.SearchDirectory(ctx, us)
+	if err != nil {
+		u.log.Warnf(txID, "cannot search directory by query(%s): err=%s", us.Query, err)
+		u.r.SendInternalServerError(ctx, w, "cannot search directory by query(%s): err=%s", us.Query, err)
+		return
+	}
+
+	users := make([]*models.User, 0, len(result.Users))
+	for i := range result.Users {
+		users = append(users, &result.Users[i].User)
+	}
+	u.privacy.Apply(ctx, users...)
+	u.r.RenderJSON(ctx, w, result)
+}
+
 func (u *userService) GetUser(w http.ResponseWriter, r *http.Request) {
 	var (
 		ctx  = r.Context()
@@ -124,6 +166,13 @@ func (u *userService) GetCollege(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// terminated user is visible only to those who can reinstate them
+	if ua := util.GetUserAccessFromCtx(ctx); user.IsTerminated() && ua.Role.Scope(models.ManageUsers) == models.NoScope {
+		u.r.SendNotFound(ctx, w, "user with id(%s) is not found", id)
+		return
+	}
+
+	u.privacy.Apply(ctx, &user)
 	u.r.RenderJSON(ctx, w, user)
 }
 
@@ -140,6 +189,7 @@ func (u *userService) GetAdmins(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	u.privacy.Apply(ctx, privacy.Users(user)...)
 	u.r.RenderJSON(ctx, w, user)
 }
 
@@ -172,16 +222,37 @@ func (u *userService) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = user.Profile.Validate()
+	if err != nil {
+		u.log.Warnf(txID, "invalid user profile: err=%s", err)
+		u.r.SendBadRequest(ctx, w, "invalid user update payload: err=%s", err)
+		return
+	}
+
+	user.DateOfBirth, err = models.NormalizeDate(user.DateOfBirth)
+	if err != nil {
+		u.log.Warnf(txID, "invalid date of birth: err=%s", err)
+		u.r.SendBadRequest(ctx, w, "invalid user update payload: err=%s", err)
+		return
+	}
+
+	before := oldUser
 	oldUser.MobilePhone = user.MobilePhone
 	oldUser.DateOfBirth = user.DateOfBirth
-	oldUser.Mood = user.Mood
+	oldUser.Profile = user.Profile
 	err = u.user.Update(ctx, oldUser)
 	if err != nil {
 		u.log.Warnf(txID, "cannot Update user by id(%s): err=%s", ua.UserID, err)
+		if models.IsErrConflict(err) {
+			u.r.SendConflict(ctx, w, "cannot Update user by id(%s): %s", ua.UserID, err)
+			return
+		}
 		u.r.SendInternalServerError(ctx, w, "cannot Update user by id(%s): err=%s", ua.UserID, err)
 		return
 	}
 
+	audit.SetAction(ctx, "user.update")
+	audit.SetChange(ctx, "user", ua.UserID, before, oldUser)
 	u.r.RenderJSON(ctx, w, oldUser)
 }
 
@@ -193,18 +264,11 @@ func (u *userService) AdminUserUpdate(w http.ResponseWriter, r *http.Request) {
 
 func (u *userService) adminUpdate(w http.ResponseWriter, r *http.Request, id string) {
 	var (
-		ctx     = r.Context()
-		txID    = transactionID.FromContext(ctx)
-		newUser models.User
+		ctx   = r.Context()
+		txID  = transactionID.FromContext(ctx)
+		patch models.UserPatch
 	)
 
-	userID, err := uuid.Parse(id)
-	if err != nil {
-		u.log.Warnf(txID, "invalid user id(%s): err=%s", id, err)
-		u.r.SendBadRequest(ctx, w, "invalid user id(%s): err=%s", id, err)
-		return
-	}
-
 	oldUser, err := u.user.GetUserByID(ctx, id)
 	if err != nil {
 		u.log.Warnf(txID, "GetUserByID id(%s): err=%s", id, err)
@@ -219,30 +283,69 @@ func (u *userService) adminUpdate(w http.ResponseWriter, r *http.Request, id str
 		return
 	}
 
-	err = json.Unmarshal(body, &newUser)
+	err = json.Unmarshal(body, &patch)
 	if err != nil {
 		u.log.Warnf(txID, "invalid user update payload, cannot unmarshal: err=%s", err)
 		u.r.SendBadRequest(ctx, w, "invalid user update payload, cannot unmarshal: err=%s", err)
 		return
 	}
-	newUser.ID = userID
-	newUser.Email = oldUser.Email
-	newUser.ImageURL = oldUser.ImageURL
+
+	if patch.DateOfBirth != nil {
+		*patch.DateOfBirth, err = models.NormalizeDate(*patch.DateOfBirth)
+		if err != nil {
+			u.log.Warnf(txID, "invalid date of birth: err=%s", err)
+			u.r.SendBadRequest(ctx, w, "invalid user update payload: err=%s", err)
+			return
+		}
+	}
+
+	if patch.HireDate != nil {
+		*patch.HireDate, err = models.NormalizeDate(*patch.HireDate)
+		if err != nil {
+			u.log.Warnf(txID, "invalid hire date: err=%s", err)
+			u.r.SendBadRequest(ctx, w, "invalid user update payload: err=%s", err)
+			return
+		}
+	}
+
+	newUser := patch.Apply(oldUser)
+
+	err = u.org.ValidateAssignment(ctx, id, newUser.ManagerID, newUser.DepartmentID)
+	if err != nil {
+		u.log.Warnf(txID, "invalid manager or department for user id(%s): err=%s", id, err)
+		if models.IsErrInvalidData(err) {
+			u.r.SendBadRequest(ctx, w, "invalid manager or department: %s", err)
+			return
+		}
+		u.r.SendInternalServerError(ctx, w, "cannot validate manager or department for id(%s): err=%s", id, err)
+		return
+	}
 
 	err = u.a.UpdateUserRole(ctx, newUser.Email, newUser.Role)
 	if err != nil {
-		u.log.Warnf(txID, "cannot update user role: err=%s", id, err)
-		u.r.SendInternalServerError(ctx, w, "cannot update user role: err=%s", id, err)
+		u.log.Warnf(txID, "cannot update user role for id(%s): err=%s", id, err)
+		u.r.SendInternalServerError(ctx, w, "cannot update user role for id(%s): err=%s", id, err)
 		return
 	}
 
-	err = u.user.Update(ctx, newUser)
+	// the whole document is replaced so that fields cleared by the patch are removed as well
+	err = u.user.Save(ctx, newUser)
 	if err != nil {
-		u.log.Warnf(txID, "cannot search user by id(%s): err=%s", id, err)
-		u.r.SendInternalServerError(ctx, w, "cannot search user by id(%s): err=%s", id, err)
+		u.log.Warnf(txID, "cannot save user by id(%s): err=%s", id, err)
+		if models.IsErrConflict(err) {
+			u.r.SendConflict(ctx, w, "cannot save user by id(%s): %s", id, err)
+			return
+		}
+		u.r.SendInternalServerError(ctx, w, "cannot save user by id(%s): err=%s", id, err)
 		return
 	}
 
+	action := "user.admin_update"
+	if oldUser.Role != newUser.Role {
+		action = "user.role_change"
+	}
+	audit.SetAction(ctx, action)
+	audit.SetChange(ctx, "user", id, oldUser, newUser)
 	u.r.RenderJSON(ctx, w, newUser)
 }
 
@@ -294,13 +397,20 @@ func (u *userService) UploadImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before := user
 	user.ImageURL = link
 	err = u.user.Update(ctx, user)
 	if err != nil {
 		u.log.Warnf(txID, "cannot update user id(%s): err=%s", ua.UserID, err)
+		if models.IsErrConflict(err) {
+			u.r.SendConflict(ctx, w, "cannot update user id(%s): %s", ua.UserID, err)
+			return
+		}
 		u.r.SendInternalServerError(ctx, w, "cannot update user id(%s): err=%s", ua.UserID, err)
 		return
 	}
 
+	audit.SetAction(ctx, "user.photo_upload")
+	audit.SetChange(ctx, "user", ua.UserID, before, user)
 	u.r.RenderJSON(ctx, w, user)
 }
@@ -0,0 +1,82 @@
+package vacation
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Dimitriy14/staff-manager/models"
+)
+
+const (
+	calendarContentType = "text/calendar; charset=utf-8"
+	icalDateLayout      = "20060102"
+	icalTimeLayout      = "20060102T150405Z"
+
+	// icalLineLimit is a maximum length of content line in octets, longer lines are folded
+	icalLineLimit = 75
+)
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// renderICal renders events as all-day events of iCalendar (RFC 5545) document
+func renderICal(events []models.FeedEvent) []byte {
+	var (
+		buf   bytes.Buffer
+		stamp = time.Now().UTC().Format(icalTimeLayout)
+	)
+
+	writeLine := func(format string, v ...interface{}) {
+		buf.WriteString(foldICalLine(fmt.Sprintf(format, v...)))
+		buf.WriteString("\r\n")
+	}
+
+	writeLine("BEGIN:VCALENDAR")
+	writeLine("VERSION:2.0")
+	writeLine("PRODID:-//staff-manager//absences//EN")
+	writeLine("CALSCALE:GREGORIAN")
+	writeLine("X-WR-CALNAME:Team absences")
+	for _, e := range events {
+		writeLine("BEGIN:VEVENT")
+		writeLine("UID:%s@staff-manager", e.UID)
+		writeLine("DTSTAMP:%s", stamp)
+		writeLine("DTSTART;VALUE=DATE:%s", e.Start.Format(icalDateLayout))
+		// DTEND of all-day event is exclusive
+		writeLine("DTEND;VALUE=DATE:%s", e.End.AddDate(0, 0, 1).Format(icalDateLayout))
+		writeLine("SUMMARY:%s", icalEscaper.Replace(e.Summary))
+		writeLine("TRANSP:TRANSPARENT")
+		writeLine("END:VEVENT")
+	}
+	writeLine("END:VCALENDAR")
+
+	return buf.Bytes()
+}
+
+// foldICalLine splits line longer than 75 octets into several lines, each next line starts with a space.
+// Line is split between characters so multi-byte UTF-8 characters are kept whole.
+func foldICalLine(line string) string {
+	if len(line) <= icalLineLimit {
+		return line
+	}
+
+	var (
+		folded strings.Builder
+		limit  = icalLineLimit
+	)
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		// leading space of continuation line is counted in the limit
+		limit = icalLineLimit - 1
+	}
+	folded.WriteString(line)
+	return folded.String()
+}
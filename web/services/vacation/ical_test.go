@@ -0,0 +1,41 @@
+package vacation
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFoldICalLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "short line", line: "SUMMARY:Ann Lee: vacation"},
+		{name: "ascii line", line: "SUMMARY:" + strings.Repeat("a", 200)},
+		{name: "multi-byte line", line: "SUMMARY:" + strings.Repeat("Відпустка ", 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := foldICalLine(tt.line)
+
+			lines := strings.Split(folded, "\r\n")
+			for i, line := range lines {
+				if len(line) > icalLineLimit {
+					t.Errorf("line %d is %d octets long", i, len(line))
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d does not start with a space", i)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits UTF-8 character", i)
+				}
+			}
+
+			if unfolded := strings.Replace(folded, "\r\n ", "", -1); unfolded != tt.line {
+				t.Errorf("unfolded line differs from original: %q", unfolded)
+			}
+		})
+	}
+}
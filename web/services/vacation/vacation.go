@@ -1,6 +1,7 @@
 package vacation
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +11,8 @@ import (
 	"github.com/Dimitriy14/staff-manager/logger"
 	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
 	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/scheduler"
+	"github.com/Dimitriy14/staff-manager/usecases/privacy"
 	"github.com/Dimitriy14/staff-manager/usecases/vacation"
 	"github.com/Dimitriy14/staff-manager/util"
 	"github.com/Dimitriy14/staff-manager/web/services/rest"
@@ -18,13 +21,22 @@ import (
 	"github.com/gorilla/mux"
 )
 
-const layout = "2006-01-02"
+const (
+	layout = "2006-01-02"
 
-func NewService(r *rest.Service, vac vacation.VacationsUsecase, log logger.Logger) *serviceImpl {
+	from     = "from"
+	to       = "to"
+	position = "position"
+	user     = "user"
+)
+
+func NewService(r *rest.Service, vac vacation.VacationsUsecase, privacy privacy.PrivacyUsecase, jobs *scheduler.Scheduler, log logger.Logger) *serviceImpl {
 	return &serviceImpl{
-		r:   r,
-		vac: vac,
-		log: log,
+		r:       r,
+		vac:     vac,
+		privacy: privacy,
+		jobs:    jobs,
+		log:     log,
 	}
 }
 
@@ -37,13 +49,25 @@ type Service interface {
 	CreateNew(w http.ResponseWriter, r *http.Request)
 	UpdateStatus(w http.ResponseWriter, r *http.Request)
 	Cancel(w http.ResponseWriter, r *http.Request)
+	DecideCancellation(w http.ResponseWriter, r *http.Request)
+	WithdrawCancellation(w http.ResponseWriter, r *http.Request)
+	GetBalance(w http.ResponseWriter, r *http.Request)
+	Shorten(w http.ResponseWriter, r *http.Request)
 	UpdateExpired(w http.ResponseWriter, r *http.Request)
+	GetComments(w http.ResponseWriter, r *http.Request)
+	GetCalendar(w http.ResponseWriter, r *http.Request)
+	IssueFeedToken(w http.ResponseWriter, r *http.Request)
+	RevokeFeedToken(w http.ResponseWriter, r *http.Request)
+	GetFeed(w http.ResponseWriter, r *http.Request)
+	AddComment(w http.ResponseWriter, r *http.Request)
 }
 
 type serviceImpl struct {
-	r   *rest.Service
-	vac vacation.VacationsUsecase
-	log logger.Logger
+	r       *rest.Service
+	vac     vacation.VacationsUsecase
+	privacy privacy.PrivacyUsecase
+	jobs    *scheduler.Scheduler
+	log     logger.Logger
 }
 
 func (s *serviceImpl) GetAll(w http.ResponseWriter, r *http.Request) {
@@ -59,6 +83,7 @@ func (s *serviceImpl) GetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.applyPrivacy(ctx, vacations)
 	s.r.RenderJSON(ctx, w, vacations)
 }
 
@@ -78,11 +103,12 @@ func (s *serviceImpl) GetForUser(w http.ResponseWriter, r *http.Request) {
 
 	vacations, err := s.vac.GetForUser(ctx, uid.String())
 	if err != nil {
-		s.log.Warnf(txID, "GetByID(ctx, id=%s) err=%s", uid, err)
-		s.r.SendInternalServerError(ctx, w, "vacation retrieving failed")
+		s.log.Warnf(txID, "GetForUser(ctx, id=%s) err=%s", uid, err)
+		s.sendVacationError(ctx, w, err, "vacation retrieving failed")
 		return
 	}
 
+	s.applyPrivacy(ctx, vacations)
 	s.r.RenderJSON(ctx, w, vacations)
 }
 
@@ -100,6 +126,7 @@ func (s *serviceImpl) GetMyVacation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.applyPrivacy(ctx, vacations)
 	s.r.RenderJSON(ctx, w, vacations)
 }
 
@@ -116,6 +143,7 @@ func (s *serviceImpl) GetPending(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.applyPrivacy(ctx, vacations)
 	s.r.RenderJSON(ctx, w, vacations)
 }
 
@@ -154,6 +182,7 @@ func (s *serviceImpl) CreateNew(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	v.UserID = ua.UserID
+	v.DelegateID = vacationReq.DelegateID
 
 	if v.StartDate.After(v.EndDate) {
 		s.log.Warnf(txID, "start date %v cannot be after end date %v", v.StartDate, v.EndDate)
@@ -172,6 +201,7 @@ func (s *serviceImpl) CreateNew(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.privacy.Apply(ctx, vac.User, vac.StatusChanger)
 	s.r.RenderJSON(ctx, w, vac)
 }
 
@@ -204,13 +234,14 @@ func (s *serviceImpl) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vac, err := s.vac.UpdateVacationStatus(ctx, uid, v.Status)
+	vac, err := s.vac.Decide(ctx, uid, v)
 	if err != nil {
-		s.log.Warnf(txID, "UpdateVacationStatus(ctx, id=%s, status=%s) err=%s", uid.String(), v.Status, err)
-		s.r.SendInternalServerError(ctx, w, "vacation retrieving failed")
+		s.log.Warnf(txID, "Decide(ctx, id=%s, status=%s) err=%s", uid.String(), v.Status, err)
+		s.sendVacationError(ctx, w, err, "cannot update vacation status")
 		return
 	}
 
+	s.privacy.Apply(ctx, vac.User, vac.StatusChanger)
 	s.r.RenderJSON(ctx, w, vac)
 }
 
@@ -228,16 +259,159 @@ func (s *serviceImpl) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vac, err := s.vac.UpdateVacationStatus(ctx, uid, models.Canceled)
+	vac, err := s.vac.Cancel(ctx, uid)
 	if err != nil {
-		s.log.Warnf(txID, "UpdateVacationStatus(ctx, id=%s, status=%s) err=%s", uid.String(), models.Canceled, err)
-		s.r.SendInternalServerError(ctx, w, "vacation retrieving failed")
+		s.log.Warnf(txID, "Cancel(ctx, id=%s) err=%s", uid.String(), err)
+		s.sendVacationError(ctx, w, err, "cannot cancel vacation")
+		return
+	}
+
+	s.privacy.Apply(ctx, vac.User, vac.StatusChanger)
+	s.r.RenderJSON(ctx, w, vac)
+}
+
+func (s *serviceImpl) DecideCancellation(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid vacation id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid vacation id: err=%s", err)
+		return
+	}
+
+	body, err := util.RetrieveAndValidate(schemas.VacationStatusUpdate, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	var v models.VacationStatusUpdate
+	err = json.Unmarshal(body, &v)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	vac, err := s.vac.DecideCancellation(ctx, uid, v)
+	if err != nil {
+		s.log.Warnf(txID, "DecideCancellation(ctx, id=%s, status=%s) err=%s", uid.String(), v.Status, err)
+		s.sendVacationError(ctx, w, err, "cannot decide vacation cancellation")
+		return
+	}
+
+	s.privacy.Apply(ctx, vac.User, vac.StatusChanger)
+	s.r.RenderJSON(ctx, w, vac)
+}
+
+func (s *serviceImpl) WithdrawCancellation(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid vacation id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid vacation id: err=%s", err)
+		return
+	}
+
+	vac, err := s.vac.WithdrawCancellation(ctx, uid)
+	if err != nil {
+		s.log.Warnf(txID, "WithdrawCancellation(ctx, id=%s) err=%s", uid.String(), err)
+		s.sendVacationError(ctx, w, err, "cannot withdraw vacation cancellation")
+		return
+	}
+
+	s.privacy.Apply(ctx, vac.User, vac.StatusChanger)
+	s.r.RenderJSON(ctx, w, vac)
+}
+
+func (s *serviceImpl) GetBalance(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	balance, err := s.vac.GetBalance(ctx, id)
+	if err != nil {
+		s.log.Warnf(txID, "GetBalance(ctx, userID=%s) err=%s", id, err)
+		s.sendVacationError(ctx, w, err, "cannot get vacation balance")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, balance)
+}
+
+func (s *serviceImpl) Shorten(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid vacation id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid vacation id: err=%s", err)
+		return
+	}
+
+	body, err := util.RetrieveAndValidate(schemas.VacationEndDateUpdate, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
 		return
 	}
 
+	var v models.VacationEndDateUpdate
+	err = json.Unmarshal(body, &v)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	endDate, err := time.Parse(layout, v.EndDate)
+	if err != nil {
+		s.log.Warnf(txID, "EndDate parsing failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "EndDate parsing failed: err=%s", err)
+		return
+	}
+
+	vac, err := s.vac.Shorten(ctx, uid, endDate)
+	if err != nil {
+		s.log.Warnf(txID, "Shorten(ctx, id=%s, endDate=%s) err=%s", uid.String(), v.EndDate, err)
+		s.sendVacationError(ctx, w, err, "cannot shorten vacation")
+		return
+	}
+
+	s.privacy.Apply(ctx, vac.User, vac.StatusChanger)
 	s.r.RenderJSON(ctx, w, vac)
 }
 
+func (s *serviceImpl) sendVacationError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrForbidden(err):
+		s.r.SendForbidden(ctx, w, "%s: %s", message, err)
+	case models.IsErrNotFound(err):
+		s.r.SendNotFound(ctx, w, "%s: %s", message, err)
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}
+
 func (s *serviceImpl) GetByID(w http.ResponseWriter, r *http.Request) {
 	var (
 		ctx  = r.Context()
@@ -254,11 +428,12 @@ func (s *serviceImpl) GetByID(w http.ResponseWriter, r *http.Request) {
 
 	vac, err := s.vac.GetByID(ctx, uid)
 	if err != nil {
-		s.log.Warnf(txID, "GetVacationByID(ctx, id=%s, status=%s) err=%s", uid.String(), models.Canceled, err)
-		s.r.SendInternalServerError(ctx, w, "vacation retrieving failed")
+		s.log.Warnf(txID, "GetVacationByID(ctx, id=%s) err=%s", uid.String(), err)
+		s.sendVacationError(ctx, w, err, "vacation retrieving failed")
 		return
 	}
 
+	s.privacy.Apply(ctx, vac.User, vac.StatusChanger)
 	s.r.RenderJSON(ctx, w, vac)
 }
 
@@ -268,7 +443,195 @@ func (s *serviceImpl) UpdateExpired(w http.ResponseWriter, r *http.Request) {
 		txID = transactionID.FromContext(ctx)
 	)
 
-	go s.vac.SetExpired(ctx)
+	// request context is canceled when response is sent, so job runs with detached one,
+	// it is skipped if scheduled run is in progress on any instance
+	go func() {
+		_, err := s.jobs.Run(transactionID.AddIDContext(context.Background(), txID), vacation.ExpiryJob, s.vac.SetExpired)
+		if err != nil {
+			s.log.Errorf(txID, "vacations expiry lock failed: err=%s", err)
+		}
+	}()
 
 	s.r.RenderJSON(ctx, w, rest.Message{Message: fmt.Sprintf("vacation status update started with txID = %s", txID)})
 }
+
+func (s *serviceImpl) GetComments(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid vacation id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid vacation id: err=%s", err)
+		return
+	}
+
+	comments, err := s.vac.GetComments(ctx, uid)
+	if err != nil {
+		s.log.Warnf(txID, "GetComments(ctx, id=%s) err=%s", uid.String(), err)
+		if models.IsErrForbidden(err) {
+			s.r.SendForbidden(ctx, w, "cannot retrieve vacation comments: %s", err)
+			return
+		}
+		if models.IsErrNotFound(err) {
+			s.r.SendNotFound(ctx, w, "cannot retrieve vacation comments: %s", err)
+			return
+		}
+		s.r.SendInternalServerError(ctx, w, "vacation comments retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, comments)
+}
+
+func (s *serviceImpl) AddComment(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid vacation id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid vacation id: err=%s", err)
+		return
+	}
+
+	body, err := util.RetrieveAndValidate(schemas.VacationComment, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return
+	}
+
+	var c models.VacationCommentReq
+	err = json.Unmarshal(body, &c)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return
+	}
+
+	comment, err := s.vac.AddComment(ctx, uid, c.Text)
+	if err != nil {
+		s.log.Warnf(txID, "AddComment(ctx, id=%s) err=%s", uid.String(), err)
+		if models.IsErrForbidden(err) {
+			s.r.SendForbidden(ctx, w, "cannot comment vacation: %s", err)
+			return
+		}
+		if models.IsErrNotFound(err) {
+			s.r.SendNotFound(ctx, w, "cannot comment vacation: %s", err)
+			return
+		}
+		s.r.SendInternalServerError(ctx, w, "vacation comment saving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, comment)
+}
+
+func (s *serviceImpl) GetCalendar(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx   = r.Context()
+		txID  = transactionID.FromContext(ctx)
+		query = r.URL.Query()
+	)
+
+	start, err := time.Parse(layout, query.Get(from))
+	if err != nil {
+		s.log.Warnf(txID, "cannot parse \"from\" date: %s", err)
+		s.r.SendBadRequest(ctx, w, "cannot parse \"from\" date: %s", err)
+		return
+	}
+
+	end, err := time.Parse(layout, query.Get(to))
+	if err != nil {
+		s.log.Warnf(txID, "cannot parse \"to\" date: %s", err)
+		s.r.SendBadRequest(ctx, w, "cannot parse \"to\" date: %s", err)
+		return
+	}
+
+	calendar, err := s.vac.GetCalendar(ctx, start, end, query.Get(position))
+	if err != nil {
+		s.log.Warnf(txID, "GetCalendar(ctx, from=%s, to=%s) err=%s", start, end, err)
+		s.sendVacationError(ctx, w, err, "cannot build absence calendar")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, calendar)
+}
+
+func (s *serviceImpl) IssueFeedToken(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	token, err := s.vac.IssueFeedToken(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "IssueFeedToken(ctx) err=%s", err)
+		s.r.SendInternalServerError(ctx, w, "feed token issuing failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, token)
+}
+
+func (s *serviceImpl) RevokeFeedToken(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	err := s.vac.RevokeFeedToken(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "RevokeFeedToken(ctx) err=%s", err)
+		s.r.SendInternalServerError(ctx, w, "feed token revoking failed")
+		return
+	}
+
+	s.r.SendNoContent(w)
+}
+
+func (s *serviceImpl) GetFeed(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		txID   = transactionID.FromContext(ctx)
+		token  = mux.Vars(r)["token"]
+		userID = r.URL.Query().Get(user)
+	)
+
+	if userID != "" {
+		if _, err := uuid.Parse(userID); err != nil {
+			s.log.Warnf(txID, "invalid user id: err=%s", err)
+			s.r.SendBadRequest(ctx, w, "invalid user id: err=%s", err)
+			return
+		}
+	}
+
+	events, err := s.vac.GetFeed(ctx, token, userID)
+	if err != nil {
+		s.log.Warnf(txID, "GetFeed(ctx, user=%s) err=%s", userID, err)
+		if models.IsErrNotFound(err) {
+			s.r.SendNotFound(ctx, w, "feed is not found")
+			return
+		}
+		s.r.SendInternalServerError(ctx, w, "feed retrieving failed")
+		return
+	}
+
+	s.r.RenderContent(ctx, w, calendarContentType, renderICal(events))
+}
+
+// applyPrivacy hides profile fields of vacation users which current user is not allowed to see
+func (s *serviceImpl) applyPrivacy(ctx context.Context, vacations []models.Vacation) {
+	users := make([]*models.User, 0, 2*len(vacations))
+	for i := range vacations {
+		users = append(users, vacations[i].User, vacations[i].StatusChanger)
+	}
+	s.privacy.Apply(ctx, users...)
+}
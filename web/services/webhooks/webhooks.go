@@ -0,0 +1,216 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Dimitriy14/staff-manager/json-validator/schemas"
+	"github.com/Dimitriy14/staff-manager/logger"
+	transactionID "github.com/Dimitriy14/staff-manager/logger/transaction-id"
+	"github.com/Dimitriy14/staff-manager/models"
+	"github.com/Dimitriy14/staff-manager/usecases/webhooks"
+	"github.com/Dimitriy14/staff-manager/util"
+	"github.com/Dimitriy14/staff-manager/web/services/rest"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const statusParam = "status"
+
+type Service interface {
+	GetSubscriptions(w http.ResponseWriter, r *http.Request)
+	CreateSubscription(w http.ResponseWriter, r *http.Request)
+	UpdateSubscription(w http.ResponseWriter, r *http.Request)
+	DeleteSubscription(w http.ResponseWriter, r *http.Request)
+	GetDeliveries(w http.ResponseWriter, r *http.Request)
+	Replay(w http.ResponseWriter, r *http.Request)
+}
+
+func NewService(r *rest.Service, wh webhooks.WebhooksUsecase, log logger.Logger) *serviceImpl {
+	return &serviceImpl{
+		r:   r,
+		wh:  wh,
+		log: log,
+	}
+}
+
+type serviceImpl struct {
+	r   *rest.Service
+	wh  webhooks.WebhooksUsecase
+	log logger.Logger
+}
+
+func (s *serviceImpl) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	subs, err := s.wh.GetSubscriptions(ctx)
+	if err != nil {
+		s.log.Warnf(txID, "GetSubscriptions(ctx) err=%s", err)
+		s.r.SendInternalServerError(ctx, w, "webhook subscriptions retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, subs)
+}
+
+func (s *serviceImpl) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+	)
+
+	req, ok := s.retrieveSubscriptionReq(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := s.wh.CreateSubscription(ctx, req)
+	if err != nil {
+		s.log.Warnf(txID, "CreateSubscription(ctx, url=%s) err=%s", req.URL, err)
+		s.sendWebhookError(ctx, w, err, "webhook subscription creating failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, models.CreatedWebhookSubscription{WebhookSubscription: *sub, Secret: sub.Secret})
+}
+
+func (s *serviceImpl) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid subscription id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid subscription id: err=%s", err)
+		return
+	}
+
+	req, ok := s.retrieveSubscriptionReq(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := s.wh.UpdateSubscription(ctx, uid, req)
+	if err != nil {
+		s.log.Warnf(txID, "UpdateSubscription(ctx, id=%s) err=%s", uid, err)
+		s.sendWebhookError(ctx, w, err, "webhook subscription updating failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, sub)
+}
+
+func (s *serviceImpl) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid subscription id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid subscription id: err=%s", err)
+		return
+	}
+
+	err = s.wh.DeleteSubscription(ctx, uid)
+	if err != nil {
+		s.log.Warnf(txID, "DeleteSubscription(ctx, id=%s) err=%s", uid, err)
+		s.sendWebhookError(ctx, w, err, "webhook subscription deleting failed")
+		return
+	}
+
+	s.r.SendNoContent(w)
+}
+
+func (s *serviceImpl) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx    = r.Context()
+		txID   = transactionID.FromContext(ctx)
+		status = models.WebhookDeliveryStatus(r.URL.Query().Get(statusParam))
+	)
+
+	switch status {
+	case "", models.WebhookPending, models.WebhookDelivered, models.WebhookDead:
+	default:
+		s.log.Warnf(txID, "invalid delivery status: %s", status)
+		s.r.SendBadRequest(ctx, w, "invalid delivery status: %s", status)
+		return
+	}
+
+	deliveries, err := s.wh.GetDeliveries(ctx, status)
+	if err != nil {
+		s.log.Warnf(txID, "GetDeliveries(ctx, status=%s) err=%s", status, err)
+		s.r.SendInternalServerError(ctx, w, "webhook deliveries retrieving failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, deliveries)
+}
+
+func (s *serviceImpl) Replay(w http.ResponseWriter, r *http.Request) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		id   = mux.Vars(r)["id"]
+	)
+
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		s.log.Warnf(txID, "invalid delivery id: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "invalid delivery id: err=%s", err)
+		return
+	}
+
+	delivery, err := s.wh.Replay(ctx, uid)
+	if err != nil {
+		s.log.Warnf(txID, "Replay(ctx, id=%s) err=%s", uid, err)
+		s.sendWebhookError(ctx, w, err, "webhook delivery replay failed")
+		return
+	}
+
+	s.r.RenderJSON(ctx, w, delivery)
+}
+
+func (s *serviceImpl) retrieveSubscriptionReq(w http.ResponseWriter, r *http.Request) (models.WebhookSubscriptionReq, bool) {
+	var (
+		ctx  = r.Context()
+		txID = transactionID.FromContext(ctx)
+		req  models.WebhookSubscriptionReq
+	)
+
+	body, err := util.RetrieveAndValidate(schemas.WebhookSubscription, s.log, r)
+	if err != nil {
+		s.log.Warnf(txID, "validation failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "validation failed: err=%s", err)
+		return req, false
+	}
+
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		s.log.Warnf(txID, "unmarshaling failed: err=%s", err)
+		s.r.SendBadRequest(ctx, w, "unmarshaling failed: err=%s", err)
+		return req, false
+	}
+	return req, true
+}
+
+func (s *serviceImpl) sendWebhookError(ctx context.Context, w http.ResponseWriter, err error, message string) {
+	switch {
+	case models.IsErrNotFound(err):
+		s.r.SendNotFound(ctx, w, "%s: %s", message, err)
+	case models.IsErrInvalidData(err):
+		s.r.SendBadRequest(ctx, w, "%s: %s", message, err)
+	default:
+		s.r.SendInternalServerError(ctx, w, "%s", message)
+	}
+}